@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/store"
+)
+
+const apikeysUsage = "usage: linkwatch apikeys create -name <name> -scopes <s1,s2> [-project name] | list [-project name] | revoke <id>"
+
+func runAPIKeys(args []string) error {
+	if len(args) == 0 {
+		return errors.New(apikeysUsage)
+	}
+	sub, args := args[0], args[1:]
+	fs := flag.NewFlagSet("apikeys "+sub, flag.ExitOnError)
+	out := outputFlag(fs)
+	name := fs.String("name", "", "key name (create)")
+	scopes := fs.String("scopes", "", "comma separated: "+strings.Join(auth.AllScopes, ", ")+" (create)")
+	projectName := fs.String("project", "", "project name (create: default project; list: all projects)")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	st, err := openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	project, err := lookupProject(ctx, st, *projectName)
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "create":
+		var sc []string
+		for _, s := range strings.Split(*scopes, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				sc = append(sc, s)
+			}
+		}
+		if project == "" {
+			project = store.DefaultProjectID
+		}
+		key, k, err := auth.NewKey(project, *name, sc)
+		if err != nil {
+			return err
+		}
+		if err := st.CreateAPIKey(ctx, k); err != nil {
+			return err
+		}
+		if err := renderAPIKeys(*out, []store.APIKey{k}); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "\nkey (shown once, store it now):\n")
+		fmt.Println(key)
+		return nil
+	case "list":
+		items, err := st.ListAPIKeys(ctx, project)
+		if err != nil {
+			return err
+		}
+		return renderAPIKeys(*out, items)
+	case "revoke":
+		if len(pos) != 1 {
+			return errors.New("usage: linkwatch apikeys revoke <id>")
+		}
+		if err := st.RevokeAPIKey(ctx, pos[0]); err != nil {
+			return err
+		}
+		fmt.Println("revoked", pos[0])
+		return nil
+	default:
+		return errors.New(apikeysUsage)
+	}
+}
+
+func renderAPIKeys(format string, items []store.APIKey) error {
+	rows := make([][]string, 0, len(items))
+	for _, k := range items {
+		last, revoked := "-", "-"
+		if k.LastUsedAt != nil {
+			last = k.LastUsedAt.Format(time.RFC3339)
+		}
+		if k.RevokedAt != nil {
+			revoked = k.RevokedAt.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{k.ID, k.ProjectID, k.Name, k.Prefix, strings.Join(k.Scopes, ","), last, revoked})
+	}
+	return render(format, map[string]any{"items": items}, []string{"ID", "PROJECT", "NAME", "PREFIX", "SCOPES", "LAST_USED", "REVOKED"}, rows)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/nurzh/linkwatch/internal/core"
+)
+
+// linkwatch check <url>: no DB, prints status and latency breakdown
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	out := outputFlag(fs)
+	timeout := fs.Duration("timeout", 5*time.Second, "request timeout")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return errors.New("usage: linkwatch check <url> [-timeout 5s]")
+	}
+	canon, _, err := core.Canonicalize(pos[0])
+	if err != nil {
+		return fmt.Errorf("bad url: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout+time.Second)
+	defer cancel()
+	res := checker.CheckOnce(ctx, canon, *timeout)
+
+	ms := func(d time.Duration) string { return fmt.Sprintf("%.1f", float64(d.Microseconds())/1000) }
+	tm := res.Timing
+	rows := [][]string{{res.URL, orDash(res.StatusCode), ms(tm.DNS), ms(tm.Connect), ms(tm.TLS), ms(tm.TTFB), ms(tm.Total), orDash(res.Error)}}
+	if err := render(*out, res, []string{"URL", "STATUS", "DNS_MS", "CONNECT_MS", "TLS_MS", "TTFB_MS", "TOTAL_MS", "ERROR"}, rows); err != nil {
+		return err
+	}
+	if res.Error != nil {
+		return errors.New("check failed")
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/store"
+)
+
+const migrateUsage = "usage: linkwatch migrate up | down [steps] | status"
+
+// linkwatch migrate up|down|status
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	st, err := openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	m, err := store.Migrator(st)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := m.Up(ctx)
+		for _, x := range ran {
+			fmt.Printf("applied %03d_%s\n", x.Version, x.Name)
+		}
+		if err == nil && len(ran) == 0 {
+			fmt.Println("nothing to apply")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("bad steps %q", args[1])
+			}
+		}
+		ran, err := m.Down(ctx, steps)
+		for _, x := range ran {
+			fmt.Printf("reverted %03d_%s\n", x.Version, x.Name)
+		}
+		return err
+	case "status":
+		rows, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			applied := "pending"
+			if r.AppliedAt != nil {
+				applied = r.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%03d_%-20s %s\n", r.Version, r.Name, applied)
+		}
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+// used by serve when -migrate is on
+func migrateUp(ctx context.Context, st store.Store) error {
+	m, err := store.Migrator(st)
+	if err != nil {
+		return err
+	}
+	ran, err := m.Up(ctx)
+	for _, x := range ran {
+		log.Printf("migration applied: %03d_%s", x.Version, x.Name)
+	}
+	return err
+}
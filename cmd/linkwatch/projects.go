@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/store"
+)
+
+const projectsUsage = "usage: linkwatch projects create <name> | list | set <name> [-max-targets n] [-min-interval d]"
+
+func runProjects(args []string) error {
+	if len(args) == 0 {
+		return errors.New(projectsUsage)
+	}
+	sub, args := args[0], args[1:]
+	fs := flag.NewFlagSet("projects "+sub, flag.ExitOnError)
+	out := outputFlag(fs)
+	maxTargets := fs.Int("max-targets", -1, "target quota, negative = unlimited (create, set)")
+	minInterval := fs.Duration("min-interval", 0, "minimum check interval, 0 = none (create, set)")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	st, err := openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	//only flags given on the command line change the quota
+	apply := func(p *store.Project) error {
+		if set["max-targets"] {
+			p.MaxTargets = nil
+			if *maxTargets >= 0 {
+				p.MaxTargets = maxTargets
+			}
+		}
+		if set["min-interval"] {
+			p.MinIntervalSeconds = nil
+			if *minInterval > 0 {
+				if *minInterval%time.Second != 0 {
+					return fmt.Errorf("min-interval must be whole seconds, got %s", *minInterval)
+				}
+				secs := int(*minInterval / time.Second)
+				p.MinIntervalSeconds = &secs
+			}
+		}
+		return p.Validate()
+	}
+
+	switch sub {
+	case "create":
+		if len(pos) != 1 {
+			return errors.New("usage: linkwatch projects create <name> [-max-targets n] [-min-interval d]")
+		}
+		p := store.Project{ID: core.NewID("p"), Name: pos[0], CreatedAt: time.Now().UTC()}
+		if err := apply(&p); err != nil {
+			return err
+		}
+		if err := st.CreateProject(ctx, p); err != nil {
+			return err
+		}
+		return renderProjects(*out, []store.Project{p})
+	case "list":
+		items, err := st.ListProjects(ctx)
+		if err != nil {
+			return err
+		}
+		return renderProjects(*out, items)
+	case "set":
+		if len(pos) != 1 {
+			return errors.New("usage: linkwatch projects set <name> [-max-targets n] [-min-interval d]")
+		}
+		p, err := st.GetProjectByName(ctx, pos[0])
+		if err != nil {
+			return fmt.Errorf("project %q: %w", pos[0], err)
+		}
+		if err := apply(&p); err != nil {
+			return err
+		}
+		if err := st.UpdateProject(ctx, p); err != nil {
+			return err
+		}
+		return renderProjects(*out, []store.Project{p})
+	default:
+		return errors.New(projectsUsage)
+	}
+}
+
+// project id for a -project flag; "" stays "" (default or all, depending on the command)
+func lookupProject(ctx context.Context, st store.Store, name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	p, err := st.GetProjectByName(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("project %q: %w", name, err)
+	}
+	return p.ID, nil
+}
+
+func renderProjects(format string, items []store.Project) error {
+	rows := make([][]string, 0, len(items))
+	for _, p := range items {
+		max, minIv := "-", "-"
+		if p.MaxTargets != nil {
+			max = strconv.Itoa(*p.MaxTargets)
+		}
+		if p.MinIntervalSeconds != nil {
+			minIv = p.MinInterval().String()
+		}
+		rows = append(rows, []string{p.ID, p.Name, max, minIv, p.CreatedAt.Format(time.RFC3339)})
+	}
+	return render(format, map[string]any{"items": items}, []string{"ID", "NAME", "MAX_TARGETS", "MIN_INTERVAL", "CREATED"}, rows)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/nurzh/linkwatch/internal/config"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/internal/targetsync"
+)
+
+// re-reads file/env/flags on SIGHUP; an invalid config keeps the old one
+func reloadOnSIGHUP(ctx context.Context, flags *config.Flags, cur config.Config, st store.Store, chk *checker.Checker, pub events.Publisher) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+		next, err := flags.Load()
+		if err != nil {
+			log.Printf("config reload rejected:\n%v", err)
+			continue
+		}
+		if next.DatabaseURL != cur.DatabaseURL || next.ListenAddr != cur.ListenAddr || next.GRPCListenAddr != cur.GRPCListenAddr ||
+			next.SecretsKey != cur.SecretsKey || next.SecretsKeyFile != cur.SecretsKeyFile {
+			log.Println("config reload: database_url/listen_addr/grpc_listen_addr/secrets key changes need a restart, ignoring them")
+		}
+		if next.MaxConcurrency != cur.MaxConcurrency {
+			chk.SetConcurrency(next.MaxConcurrency)
+		}
+		if next.CheckInterval != cur.CheckInterval {
+			chk.SetInterval(next.CheckInterval.D())
+		}
+		if next.HTTPTimeout != cur.HTTPTimeout {
+			chk.SetTimeout(next.HTTPTimeout.D())
+		}
+		if p, err := next.Egress.Policy(); err == nil {
+			chk.SetEgressPolicy(p)
+		}
+		chk.SetDNSResolver(next.DNSResolver)
+		chk.SetRetryPolicy(next.RetryPolicy())
+		if !reflect.DeepEqual(next.HostLimits, cur.HostLimits) {
+			//a new limiter forgets booked slots and crawl delays
+			chk.SetHostLimits(next.HostLimits)
+		}
+		if next.Breaker != cur.Breaker {
+			chk.SetBreaker(next.Breaker)
+		}
+		if !reflect.DeepEqual(next.Transport, cur.Transport) {
+			chk.SetTransport(next.Transport)
+		}
+		if st != nil && next.HasDeclaredTargets() {
+			syncTargets(ctx, st, next, pub)
+		}
+		next.DatabaseURL, next.ListenAddr, next.GRPCListenAddr = cur.DatabaseURL, cur.ListenAddr, cur.GRPCListenAddr
+		cur = next
+		log.Printf("config reloaded: max_concurrency=%d check_interval=%s http_timeout=%s", cur.MaxConcurrency, cur.CheckInterval, cur.HTTPTimeout)
+	}
+}
+
+// reconciles declared targets and publishes the writes to pub (may be nil);
+// errors are logged, the previous state stays
+func syncTargets(ctx context.Context, st store.Store, cfg config.Config, pub events.Publisher) {
+	p, err := targetsync.Sync(ctx, st, cfg)
+	if err != nil {
+		log.Printf("targets sync: %v", err)
+		return
+	}
+	for _, c := range p.Changes {
+		log.Printf("targets sync: %s %s %s", c.Action, c.Target.URL, c.Reason)
+	}
+	if p.HasWrites() {
+		log.Printf("targets sync: %s", p.Summary())
+	}
+	if pub != nil {
+		p.Publish(pub)
+	}
+}
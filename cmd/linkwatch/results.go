@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// linkwatch results <id>
+func runResults(args []string) error {
+	fs := flag.NewFlagSet("results", flag.ExitOnError)
+	out := outputFlag(fs)
+	limit := fs.Int("limit", 20, "max results")
+	since := fs.Duration("since", 0, "only results newer than this (e.g. 1h)")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return errors.New("usage: linkwatch results <target-id> [-limit n] [-since 1h]")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	st, err := openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	var sincePtr *time.Time
+	if *since > 0 {
+		t := time.Now().Add(-*since)
+		sincePtr = &t
+	}
+	items, err := st.ListResults(ctx, pos[0], sincePtr, *limit)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(items))
+	for _, r := range items {
+		rows = append(rows, []string{r.CheckedAt.Format(time.RFC3339), orDash(r.StatusCode), orDash(r.LatencyMS), orDash(r.Error)})
+	}
+	return render(*out, map[string]any{"items": items}, []string{"CHECKED", "STATUS", "LATENCY_MS", "ERROR"}, rows)
+}
+
+// linkwatch report uptime
+func runReport(args []string) error {
+	if len(args) == 0 || args[0] != "uptime" {
+		return errors.New("usage: linkwatch report uptime [-since 24h] [-project name]")
+	}
+	fs := flag.NewFlagSet("report uptime", flag.ExitOnError)
+	out := outputFlag(fs)
+	since := fs.Duration("since", 24*time.Hour, "report window")
+	projectName := fs.String("project", "", "only this project (default: all)")
+	if _, err := parseArgs(fs, args[1:]); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	st, err := openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	project, err := lookupProject(ctx, st, *projectName)
+	if err != nil {
+		return err
+	}
+
+	items, err := st.Uptime(ctx, project, time.Now().Add(-*since))
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(items))
+	for _, u := range items {
+		uptime := "-"
+		if u.Checks > 0 {
+			uptime = fmt.Sprintf("%.2f%%", u.Percent())
+		}
+		avg := "-"
+		if u.AvgLatencyMS != nil {
+			avg = fmt.Sprintf("%.0f", *u.AvgLatencyMS)
+		}
+		rows = append(rows, []string{u.TargetID, u.URL, fmt.Sprint(u.Checks), uptime, avg})
+	}
+	return render(*out, map[string]any{"since": time.Now().Add(-*since).UTC(), "items": items},
+		[]string{"ID", "URL", "CHECKS", "UPTIME", "AVG_LATENCY_MS"}, rows)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/config"
+	"github.com/nurzh/linkwatch/internal/secrets"
+	"github.com/nurzh/linkwatch/internal/store"
+)
+
+const secretsUsage = "usage: linkwatch secrets set <name> [-file f] [-project name] | list [-project name] | rm <name> [-project name] | keygen"
+
+func runSecrets(args []string) error {
+	if len(args) == 0 {
+		return errors.New(secretsUsage)
+	}
+	sub, args := args[0], args[1:]
+	if sub == "keygen" {
+		fmt.Println(secrets.NewKey())
+		return nil
+	}
+	fs := flag.NewFlagSet("secrets "+sub, flag.ExitOnError)
+	out := outputFlag(fs)
+	file := fs.String("file", "", "read the value from this file instead of stdin (set)")
+	projectName := fs.String("project", "", "project name (default project)")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	st, err := openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	project, err := lookupProject(ctx, st, *projectName)
+	if err != nil {
+		return err
+	}
+	if project == "" {
+		project = store.DefaultProjectID
+	}
+
+	switch sub {
+	case "set":
+		if len(pos) != 1 {
+			return errors.New("usage: linkwatch secrets set <name> [-file f]")
+		}
+		name := pos[0]
+		if err := secrets.ValidateName(name); err != nil {
+			return err
+		}
+		box, err := secretsBox()
+		if err != nil {
+			return err
+		}
+		value, err := readSecret(*file)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		sec := store.Secret{ProjectID: project, Name: name, CreatedAt: now, UpdatedAt: now}
+		if old, err := st.GetSecret(ctx, project, name); err == nil {
+			sec.CreatedAt = old.CreatedAt
+		} else if !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		sec.Value = box.Seal(project, name, value)
+		if err := st.PutSecret(ctx, sec); err != nil {
+			return err
+		}
+		return renderSecrets(*out, []store.Secret{sec})
+	case "list":
+		items, err := st.ListSecrets(ctx, project)
+		if err != nil {
+			return err
+		}
+		return renderSecrets(*out, items)
+	case "rm":
+		if len(pos) != 1 {
+			return errors.New("usage: linkwatch secrets rm <name>")
+		}
+		if err := st.DeleteSecret(ctx, project, pos[0]); err != nil {
+			return err
+		}
+		fmt.Println("deleted", pos[0])
+		return nil
+	default:
+		return errors.New(secretsUsage)
+	}
+}
+
+// the key serve uses, from LINKWATCH_CONFIG / SECRETS_KEY / SECRETS_KEY_FILE
+func secretsBox() (*secrets.Box, error) {
+	cfg, err := config.Load(os.Getenv("LINKWATCH_CONFIG"), os.Getenv, nil)
+	if err != nil {
+		return nil, err
+	}
+	box, err := cfg.SecretsBox()
+	if err != nil {
+		return nil, err
+	}
+	if box == nil {
+		return nil, secrets.ErrNoKey
+	}
+	return box, nil
+}
+
+// the value from file as is, or from stdin without its trailing newline so
+// that `echo token |` works
+func readSecret(file string) ([]byte, error) {
+	var b []byte
+	var err error
+	if file != "" {
+		b, err = os.ReadFile(file)
+	} else if b, err = io.ReadAll(os.Stdin); err == nil {
+		b = []byte(strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return b, nil
+}
+
+func renderSecrets(format string, items []store.Secret) error {
+	rows := make([][]string, 0, len(items))
+	for _, s := range items {
+		rows = append(rows, []string{s.ProjectID, s.Name, s.CreatedAt.Format(time.RFC3339), s.UpdatedAt.Format(time.RFC3339)})
+	}
+	return render(format, map[string]any{"items": items}, []string{"PROJECT", "NAME", "CREATED", "UPDATED"}, rows)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/nurzh/linkwatch/internal/config"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/grpcapi"
+	"github.com/nurzh/linkwatch/internal/httpapi"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/internal/targetsync"
+
+	"google.golang.org/grpc"
+)
+
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	migrateOnStart := fs.Bool("migrate", true, "apply pending migrations on startup")
+	migrateTimeout := fs.Duration("migrate-timeout", 0, "limit for startup migrations, including the wait for another replica's lock (0 = none)")
+	cfgFlags := config.RegisterFlags(fs)
+	_ = fs.Parse(args)
+
+	cfg, err := cfgFlags.Load()
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	var st store.Store
+	if cfg.DatabaseURL == "" {
+		log.Println("DATABASE_URL is not set")
+	} else {
+		//the deadline is for connecting only: migrations may wait for
+		//another replica's lock or rebuild large tables
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var err error
+		st, err = store.Open(ctx, cfg.DatabaseURL)
+		if err != nil {
+			log.Printf("store open error: %v", err)
+			st = nil // run without a store, like without DATABASE_URL
+		} else if err := st.Ping(ctx); err != nil {
+			log.Printf("DB ping error: %v", err)
+		} else {
+			log.Println("DB connected")
+			if *migrateOnStart {
+				mctx, mcancel := context.Background(), context.CancelFunc(func() {})
+				if *migrateTimeout > 0 {
+					mctx, mcancel = context.WithTimeout(mctx, *migrateTimeout)
+				}
+				err := migrateUp(mctx, st)
+				mcancel()
+				if err != nil {
+					log.Fatalf("migrations: %v", err)
+				}
+			}
+			if cfg.HasDeclaredTargets() {
+				sctx, scancel := context.WithTimeout(context.Background(), 30*time.Second)
+				syncTargets(sctx, st, cfg, nil) // nobody is subscribed yet
+				scancel()
+			}
+		}
+	}
+
+	egress, err := cfg.Egress.Policy() // validated by Load
+	if err != nil {
+		log.Fatalf("egress: %v", err)
+	}
+	if cfg.Egress.AllowPrivate {
+		log.Println("WARNING: egress.allow_private is set, checks may reach internal addresses")
+	}
+	if cfg.Transport.Insecure() {
+		log.Println("WARNING: transport.insecure_skip_verify is set, TLS certificates of targets are not verified")
+	}
+	box, err := cfg.SecretsBox() // validated by Load
+	if err != nil {
+		log.Fatalf("secrets key: %v", err)
+	}
+	if box == nil {
+		log.Println("no secrets key (SECRETS_KEY or secrets_key_file): targets cannot use secrets")
+	}
+	broker := events.NewBroker(0)
+	chk := checker.New(st, cfg.MaxConcurrency, cfg.HTTPTimeout.D(), cfg.CheckInterval.D(),
+		checker.WithEgressPolicy(egress), checker.WithDNSResolver(cfg.DNSResolver), checker.WithRetryPolicy(cfg.RetryPolicy()),
+		checker.WithHostLimits(cfg.HostLimits), checker.WithBreaker(cfg.Breaker), checker.WithTransport(cfg.Transport),
+		checker.WithSecrets(box), checker.WithEvents(broker))
+
+	authn := &auth.Authenticator{Store: st, Disabled: cfg.AuthDisabled}
+	if cfg.AuthDisabled {
+		log.Println("WARNING: auth_disabled is set, the API is open to anyone who can reach it")
+	}
+
+	handler := httpapi.New(st, chk, authn, httpapi.WithEvents(broker), httpapi.WithSecrets(box))
+	srv := &http.Server{Addr: cfg.ListenAddr, Handler: handler}
+	srv.RegisterOnShutdown(handler.CloseStreams)
+	go func() {
+		log.Printf("listening on %s", cfg.ListenAddr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("server error: %v", err)
+		}
+	}()
+
+	//same store, auth and events as the HTTP API, on its own port
+	var grpcAPI *grpcapi.Server
+	var grpcSrv *grpc.Server
+	if cfg.GRPCListenAddr != "" {
+		lis, err := net.Listen("tcp", cfg.GRPCListenAddr)
+		if err != nil {
+			log.Fatalf("grpc listen: %v", err)
+		}
+		grpcAPI = grpcapi.New(st, chk, authn, grpcapi.WithEvents(broker))
+		grpcSrv = grpcAPI.GRPC()
+		go func() {
+			log.Printf("gRPC listening on %s", cfg.GRPCListenAddr)
+			if err := grpcSrv.Serve(lis); err != nil {
+				log.Fatalf("grpc server error: %v", err)
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	if st != nil {
+		go chk.Start(ctx)
+	}
+	//other replicas' checks reach this replica's streams through Postgres
+	if pg, ok := st.(*store.Postgres); ok {
+		go events.RelayPostgres(ctx, pg.Pool, broker)
+	}
+	go reloadOnSIGHUP(ctx, cfgFlags, cfg, st, chk, broker)
+	if st != nil && cfg.TargetsFile != "" {
+		go targetsync.Watch(ctx, cfg.TargetsFile, cfg.TargetsSyncInterval.D(), func() {
+			//re-read so env/flag changes to the path are honored the same way as on SIGHUP
+			c, err := cfgFlags.Load()
+			if err != nil {
+				log.Printf("targets sync: config: %v", err)
+				return
+			}
+			syncTargets(ctx, st, c, broker)
+		})
+	}
+
+	<-ctx.Done()
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownGrace.D())
+	defer cancel()
+	_ = srv.Shutdown(shutdownCtx)
+	if grpcSrv != nil {
+		grpcAPI.CloseStreams()
+		stopped := make(chan struct{})
+		go func() { grpcSrv.GracefulStop(); close(stopped) }()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			grpcSrv.Stop()
+		}
+	}
+	if st != nil {
+		st.Close()
+	}
+	log.Println("shutdown complete")
+}
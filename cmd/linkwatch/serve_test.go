@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// output of a child process, read while exec's copy goroutine writes it
+type syncBuffer struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+// a DATABASE_URL that cannot be opened leaves serve running without a store
+func TestServeStoreOpenError(t *testing.T) {
+	if os.Getenv("LINKWATCH_TEST_SERVE") == "1" {
+		serve(nil)
+		return
+	}
+	for _, dsn := range []string{"sqlite://", "postgres://u@host:notaport/db"} {
+		t.Run(dsn, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			addr := l.Addr().String()
+			require.NoError(t, l.Close())
+
+			cmd := exec.Command(os.Args[0], "-test.run=^TestServeStoreOpenError$")
+			cmd.Env = append(os.Environ(), "LINKWATCH_TEST_SERVE=1", "LINKWATCH_CONFIG=", "DATABASE_URL="+dsn,
+				"LISTEN_ADDR="+addr, "GRPC_LISTEN_ADDR=", "CHECK_INTERVAL=1s")
+			var out syncBuffer
+			cmd.Stdout, cmd.Stderr = &out, &out
+			require.NoError(t, cmd.Start())
+			done := make(chan error, 1)
+			go func() { done <- cmd.Wait() }()
+
+			require.Eventually(t, func() bool {
+				resp, err := http.Get("http://" + addr + "/healthz")
+				if err != nil {
+					return false
+				}
+				defer resp.Body.Close()
+				b, _ := io.ReadAll(resp.Body)
+				return resp.StatusCode == http.StatusServiceUnavailable && strings.Contains(string(b), `"db":"down"`)
+			}, 5*time.Second, 50*time.Millisecond, out.String())
+
+			//the checker's first tick and the relay would have crashed by now
+			select {
+			case err := <-done:
+				t.Fatalf("serve exited: %v\n%s", err, out.String())
+			case <-time.After(1500 * time.Millisecond):
+			}
+			require.NoError(t, cmd.Process.Signal(syscall.SIGTERM))
+			select {
+			case err := <-done:
+				require.NoError(t, err, out.String())
+			case <-time.After(10 * time.Second):
+				_ = cmd.Process.Kill()
+				t.Fatal("serve did not stop")
+			}
+			require.Contains(t, out.String(), "store open error")
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/config"
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/internal/targetsync"
+)
+
+const targetsUsage = "usage: linkwatch targets add <url> | list [-host h] | rm <id> | import <file|-> | diff [-f file] | sync [-f file] (add, list, import take -project <name>)"
+
+func runTargets(args []string) error {
+	if len(args) == 0 {
+		return errors.New(targetsUsage)
+	}
+	sub, args := args[0], args[1:]
+	fs := flag.NewFlagSet("targets "+sub, flag.ExitOnError)
+	out := outputFlag(fs)
+	host := fs.String("host", "", "only targets on this host (list)")
+	file := fs.String("f", "", "targets file (diff, sync); default: targets_file from the config")
+	projectName := fs.String("project", "", "project name (add, import: default project; list: all projects)")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	st, err := openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	project, err := lookupProject(ctx, st, *projectName)
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "add":
+		if len(pos) != 1 {
+			return errors.New("usage: linkwatch targets add <url>")
+		}
+		t, _, err := addTarget(ctx, st, project, pos[0])
+		if err != nil {
+			return err
+		}
+		return renderTargets(*out, []store.Target{t})
+	case "list":
+		var h *string
+		if *host != "" {
+			lh := strings.ToLower(*host)
+			h = &lh
+		}
+		var all []store.Target
+		var after *api.Cursor
+		for {
+			items, next, err := st.ListTargets(ctx, project, h, after, 100)
+			if err != nil {
+				return err
+			}
+			all = append(all, items...)
+			if next == nil {
+				break
+			}
+			after = next
+		}
+		return renderTargets(*out, all)
+	case "rm":
+		if len(pos) != 1 {
+			return errors.New("usage: linkwatch targets rm <id>")
+		}
+		if err := st.DeleteTarget(ctx, pos[0]); err != nil {
+			return err
+		}
+		fmt.Println("deleted", pos[0])
+		return nil
+	case "import":
+		if len(pos) != 1 {
+			return errors.New("usage: linkwatch targets import <file|->")
+		}
+		return importTargets(ctx, st, project, pos[0], *out)
+	case "diff", "sync":
+		return syncCommand(ctx, st, *file, sub == "sync")
+	default:
+		return errors.New(targetsUsage)
+	}
+}
+
+// diff prints the reconcile plan, sync also applies it
+func syncCommand(ctx context.Context, st store.Store, file string, apply bool) error {
+	cfg, err := config.Load(os.Getenv("LINKWATCH_CONFIG"), os.Getenv, func(c *config.Config) {
+		if file != "" {
+			c.TargetsFile = file
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !cfg.HasDeclaredTargets() {
+		return errors.New("no declared targets: pass -f or set targets_file")
+	}
+	specs, err := targetsync.Specs(cfg)
+	if err != nil {
+		return err
+	}
+	plan, err := targetsync.Compute(ctx, st, specs)
+	if err != nil {
+		return err
+	}
+	fmt.Print(plan.String())
+	if !apply || !plan.HasWrites() {
+		return nil
+	}
+	if err := plan.Apply(ctx, st); err != nil {
+		return err
+	}
+	fmt.Println("applied")
+	return nil
+}
+
+// canonicalizes like POST /v1/targets; project "" is the default project
+func addTarget(ctx context.Context, st store.Store, project, raw string) (store.Target, bool, error) {
+	canon, host, err := core.Canonicalize(raw)
+	if err != nil {
+		return store.Target{}, false, fmt.Errorf("bad url %q: %w", raw, err)
+	}
+	return st.CreateOrGetTarget(ctx, project, core.NewID("t"), canon, host)
+}
+
+// one URL per line, blank lines and # comments skipped
+func importTargets(ctx context.Context, st store.Store, project, path, format string) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var imported []store.Target
+	var failed int
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		raw := strings.TrimSpace(sc.Text())
+		if raw == "" || strings.HasPrefix(raw, "#") {
+			continue
+		}
+		t, _, err := addTarget(ctx, st, project, raw)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "line %d: %v\n", line, err)
+			continue
+		}
+		imported = append(imported, t)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if err := renderTargets(format, imported); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d line(s) failed", failed)
+	}
+	return nil
+}
+
+func renderTargets(format string, items []store.Target) error {
+	rows := make([][]string, 0, len(items))
+	for i, t := range items {
+		items[i] = t.Redacted()
+		rows = append(rows, []string{t.ID, t.ProjectID, t.URL, t.Host, t.Source, t.CreatedAt.Format(time.RFC3339)})
+	}
+	return render(format, map[string]any{"items": items}, []string{"ID", "PROJECT", "URL", "HOST", "SOURCE", "CREATED"}, rows)
+}
@@ -30,6 +30,9 @@ func DecodeCursor(s string) (Cursor, error) {
 	if err := json.Unmarshal(dec, &c); err != nil {
 		return Cursor{}, err
 	}
+	if c.ID == "" || c.CreatedAt.IsZero() {
+		return Cursor{}, errors.New("incomplete cursor")
+	}
 	return c, nil
 }
 
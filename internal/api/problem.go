@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RFC 7807 error bodies; every non-2xx API response uses them
+const ProblemContentType = "application/problem+json"
+
+// problem types beyond the plain HTTP status ("about:blank")
+const (
+	TypeValidation          = "/problems/validation"
+	TypeUnauthorized        = "/problems/unauthorized"
+	TypeForbidden           = "/problems/forbidden"
+	TypeQuotaExceeded       = "/problems/quota-exceeded"
+	TypeIdempotencyConflict = "/problems/idempotency-conflict"
+)
+
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"` // request path
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// set when a key lacks a scope (403)
+	RequiredScope string `json:"required_scope,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"` // JSON field or query parameter
+	Message string `json:"message"`
+}
+
+func (p Problem) Error() string { return fmt.Sprintf("%d %s: %s", p.Status, p.Title, p.Detail) }
+
+// WriteProblem fills type/title/instance/request id if unset
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if r != nil {
+		if p.Instance == "" {
+			p.Instance = r.URL.Path
+		}
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			p.RequestID = id
+			w.Header().Set("X-Request-Id", id)
+		}
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// Error answers with a plain status problem; detail must be safe to show
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	WriteProblem(w, r, Problem{Status: status, Detail: detail})
+}
+
+// Invalid answers 400 with one entry per offending field
+func Invalid(w http.ResponseWriter, r *http.Request, fields ...FieldError) {
+	detail := "the request has invalid fields"
+	if len(fields) == 1 {
+		detail = fields[0].Field + ": " + fields[0].Message
+	}
+	WriteProblem(w, r, Problem{
+		Type: TypeValidation, Title: "Invalid request", Status: http.StatusBadRequest,
+		Detail: detail, Errors: fields,
+	})
+}
+
+// Internal logs err with the request id and answers a generic 500
+func Internal(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("request %s %s %s: %v", middleware.GetReqID(r.Context()), r.Method, r.URL.Path, err)
+	WriteProblem(w, r, Problem{Status: http.StatusInternalServerError,
+		Detail: "internal error, see the server log for this request id"})
+}
+
+// Recoverer is chi's middleware.Recoverer with a problem body
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				Internal(w, r, fmt.Errorf("panic: %v\n%s", rec, debug.Stack()))
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	Error(w, r, http.StatusNotFound, "no such route")
+}
+
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	Error(w, r, http.StatusMethodNotAllowed, r.Method+" is not supported on this route")
+}
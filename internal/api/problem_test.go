@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	require.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+	var p Problem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+	return p
+}
+
+func TestProblemResponses(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID, Recoverer)
+	r.NotFound(NotFoundHandler)
+	r.MethodNotAllowed(MethodNotAllowedHandler)
+	r.Get("/invalid", func(w http.ResponseWriter, r *http.Request) {
+		Invalid(w, r, FieldError{Field: "limit", Message: "must be between 1 and 200"})
+	})
+	r.Get("/internal", func(w http.ResponseWriter, r *http.Request) {
+		Internal(w, r, errors.New("pq: password authentication failed"))
+	})
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	do := func(method, path string) Problem {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		p := decodeProblem(t, rec)
+		require.Equal(t, rec.Code, p.Status)
+		require.Equal(t, path, p.Instance)
+		require.NotEmpty(t, p.RequestID)
+		require.Equal(t, p.RequestID, rec.Header().Get("X-Request-Id"))
+		return p
+	}
+
+	p := do(http.MethodGet, "/invalid")
+	require.Equal(t, http.StatusBadRequest, p.Status)
+	require.Equal(t, TypeValidation, p.Type)
+	require.Equal(t, []FieldError{{Field: "limit", Message: "must be between 1 and 200"}}, p.Errors)
+
+	p = do(http.MethodGet, "/internal")
+	require.Equal(t, http.StatusInternalServerError, p.Status)
+	require.False(t, strings.Contains(p.Detail, "password"), "internal errors must not leak")
+
+	p = do(http.MethodGet, "/panic")
+	require.Equal(t, http.StatusInternalServerError, p.Status)
+	require.NotContains(t, p.Detail, "boom")
+
+	p = do(http.MethodGet, "/nope")
+	require.Equal(t, http.StatusNotFound, p.Status)
+	require.Equal(t, "about:blank", p.Type)
+	require.Equal(t, "Not Found", p.Title)
+
+	p = do(http.MethodDelete, "/invalid")
+	require.Equal(t, http.StatusMethodNotAllowed, p.Status)
+}
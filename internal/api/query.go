@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxBodyBytes caps JSON request bodies
+const MaxBodyBytes = 64 << 10
+
+// Query parses query parameters strictly; every problem is collected so a
+// client sees all of them in one 400
+//
+//	q := api.NewQuery(r)
+//	limit := q.Limit(20, 100)
+//	if q.Invalid(w, r) {
+//		return
+//	}
+type Query struct {
+	values url.Values
+	errs   []FieldError
+}
+
+func NewQuery(r *http.Request) *Query {
+	return &Query{values: r.URL.Query()}
+}
+
+func (q *Query) fail(field, format string, args ...any) {
+	q.errs = append(q.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// single value of name, "" if absent; repeating a parameter is an error
+func (q *Query) get(name string) string {
+	vs := q.values[name]
+	if len(vs) > 1 {
+		q.fail(name, "must be given at most once")
+		return ""
+	}
+	if len(vs) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(vs[0])
+}
+
+// Limit is def when absent, otherwise an integer in [1, max]
+func (q *Query) Limit(def, max int) int {
+	v := q.get("limit")
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		q.fail("limit", "must be an integer, got %q", v)
+		return def
+	}
+	if n < 1 || n > max {
+		q.fail("limit", "must be between 1 and %d, got %d", max, n)
+		return def
+	}
+	return n
+}
+
+// Cursor decodes page_token, nil when absent
+func (q *Query) Cursor() *Cursor {
+	v := q.get("page_token")
+	if v == "" {
+		return nil
+	}
+	c, err := DecodeCursor(v)
+	if err != nil {
+		q.fail("page_token", "malformed token, pass next_page_token back unchanged")
+		return nil
+	}
+	return &c
+}
+
+// Since is an RFC 3339 timestamp, nil when absent
+func (q *Query) Since() *time.Time {
+	v := q.get("since")
+	if v == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		q.fail("since", "must be an RFC 3339 timestamp like 2006-01-02T15:04:05Z, got %q", v)
+		return nil
+	}
+	return &t
+}
+
+// Host is a lower-cased host[:port] filter, nil when absent
+func (q *Query) Host() *string {
+	v := q.get("host")
+	if v == "" {
+		return nil
+	}
+	h, err := ParseHost(v)
+	if err != nil {
+		q.fail("host", "%s", err)
+		return nil
+	}
+	return &h
+}
+
+// ParseHost lower-cases a host[:port] filter value and rejects anything else
+func ParseHost(v string) (string, error) {
+	v = strings.ToLower(v)
+	if strings.Contains(v, "://") {
+		return "", fmt.Errorf("must be a host name without scheme, got %q", v)
+	}
+	if u, err := url.Parse("//" + v); err != nil || u.Host != v || len(v) > 261 {
+		return "", fmt.Errorf("must be a host name, optionally with :port, got %q", v)
+	}
+	return v, nil
+}
+
+// String is a free-form parameter, "" when absent
+func (q *Query) String(name string) string { return q.get(name) }
+
+// Labels parses repeated label=key=value selectors, nil when absent
+func (q *Query) Labels() map[string]string {
+	vs := q.values["label"]
+	if len(vs) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(vs))
+	for _, v := range vs {
+		k, val, ok := strings.Cut(v, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			q.fail("label", "must be key=value, got %q", v)
+			return nil
+		}
+		out[strings.TrimSpace(k)] = val
+	}
+	return out
+}
+
+// Invalid answers 400 if any parameter was rejected and reports whether it did
+func (q *Query) Invalid(w http.ResponseWriter, r *http.Request) bool {
+	if len(q.errs) == 0 {
+		return false
+	}
+	Invalid(w, r, q.errs...)
+	return true
+}
+
+// DecodeJSON reads a single JSON object of at most MaxBodyBytes into dst,
+// rejecting unknown fields. On failure it has already answered and returns false.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(dst)
+	if err == nil {
+		if extra := dec.Decode(&json.RawMessage{}); extra != io.EOF {
+			err = errors.New("unexpected data after the JSON object")
+		}
+	}
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &tooLarge):
+		WriteProblem(w, r, Problem{Status: http.StatusRequestEntityTooLarge,
+			Detail: fmt.Sprintf("request body must not exceed %d bytes", MaxBodyBytes)})
+	case errors.Is(err, io.EOF):
+		Invalid(w, r, FieldError{Field: "body", Message: "must be a JSON object, got an empty body"})
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		Invalid(w, r, FieldError{Field: typeErr.Field, Message: "must be " + jsonKind(typeErr.Type.Kind())})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		Invalid(w, r, FieldError{Field: field, Message: "is not a known field"})
+	default:
+		Invalid(w, r, FieldError{Field: "body", Message: "invalid JSON: " + err.Error()})
+	}
+	return false
+}
+
+func jsonKind(k reflect.Kind) string {
+	switch k {
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Bool:
+		return "a boolean"
+	default:
+		return "a number"
+	}
+}
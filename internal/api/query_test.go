@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryRejectsBadValues(t *testing.T) {
+	tok := EncodeCursor(Cursor{CreatedAt: time.Unix(1700000000, 0).UTC(), ID: "t_1"})
+	cases := []struct {
+		query  string
+		fields []string // rejected parameters, in order
+	}{
+		{"", nil},
+		{"limit=5&host=Example.ORG:8080&since=2024-01-02T03:04:05Z&page_token=" + tok, nil},
+		{"limit=0", []string{"limit"}},
+		{"limit=500", []string{"limit"}},
+		{"limit=abc", []string{"limit"}},
+		{"limit=1&limit=2", []string{"limit"}},
+		{"since=yesterday", []string{"since"}},
+		{"page_token=garbage", []string{"page_token"}},
+		{"page_token=" + EncodeCursor(Cursor{}), []string{"page_token"}},
+		{"host=https://example.org", []string{"host"}},
+		{"host=example.org/path", []string{"host"}},
+		{"limit=-1&since=x&host=a%20b", []string{"host", "limit", "since"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			q := NewQuery(httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil))
+			q.Host()
+			q.Limit(20, 100)
+			q.Since()
+			q.Cursor()
+			var got []string
+			for _, e := range q.errs {
+				got = append(got, e.Field)
+			}
+			require.Equal(t, tc.fields, got)
+		})
+	}
+
+	q := NewQuery(httptest.NewRequest(http.MethodGet, "/?limit=7&host=Example.ORG", nil))
+	require.Equal(t, 7, q.Limit(20, 100))
+	require.Equal(t, "example.org", *q.Host())
+	require.Nil(t, q.Since())
+	rec := httptest.NewRecorder()
+	require.False(t, q.Invalid(rec, httptest.NewRequest(http.MethodGet, "/", nil)))
+
+	q = NewQuery(httptest.NewRequest(http.MethodGet, "/?limit=500", nil))
+	require.Equal(t, 20, q.Limit(20, 100))
+	require.True(t, q.Invalid(rec, httptest.NewRequest(http.MethodGet, "/", nil)))
+	p := decodeProblem(t, rec)
+	require.Equal(t, http.StatusBadRequest, p.Status)
+	require.Equal(t, "must be between 1 and 100, got 500", p.Errors[0].Message)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	type req struct {
+		URL   string `json:"url"`
+		Count int    `json:"count"`
+	}
+	cases := []struct {
+		name, body string
+		status     int
+		field      string
+	}{
+		{"ok", `{"url":"https://a.test/","count":2}`, 0, ""},
+		{"unknown field", `{"url":"https://a.test/","uri":"x"}`, http.StatusBadRequest, "uri"},
+		{"wrong type", `{"count":"two"}`, http.StatusBadRequest, "count"},
+		{"empty", ``, http.StatusBadRequest, "body"},
+		{"syntax", `{"url":`, http.StatusBadRequest, "body"},
+		{"trailing", `{"url":"a"} {"url":"b"}`, http.StatusBadRequest, "body"},
+		{"too large", `{"url":"` + strings.Repeat("a", MaxBodyBytes) + `"}`, http.StatusRequestEntityTooLarge, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			var dst req
+			ok := DecodeJSON(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)), &dst)
+			if tc.status == 0 {
+				require.True(t, ok)
+				require.Equal(t, req{URL: "https://a.test/", Count: 2}, dst)
+				return
+			}
+			require.False(t, ok)
+			require.Equal(t, tc.status, rec.Code)
+			p := decodeProblem(t, rec)
+			if tc.field != "" {
+				require.Len(t, p.Errors, 1)
+				require.Equal(t, tc.field, p.Errors[0].Field)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// Package auth implements API key authentication and scope checks.
+// Keys look like "lw_<8 hex>_<secret>"; only their sha256 is stored.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/store"
+)
+
+const (
+	ScopeTargetsRead  = "targets:read"
+	ScopeTargetsWrite = "targets:write"
+	ScopeResultsRead  = "results:read"
+	ScopeAdmin        = "admin" // implies every other scope within the key's project
+	// instance-wide: manage projects and keys of any project; not implied by admin
+	ScopeProjectsAdmin = "projects:admin"
+)
+
+var AllScopes = []string{ScopeTargetsRead, ScopeTargetsWrite, ScopeResultsRead, ScopeAdmin, ScopeProjectsAdmin}
+
+// last_used_at is written at most this often per key
+const touchEvery = time.Minute
+
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, s := range scopes {
+		if !slices.Contains(AllScopes, s) {
+			return fmt.Errorf("unknown scope %q (valid: %s)", s, strings.Join(AllScopes, ", "))
+		}
+	}
+	return nil
+}
+
+// NewKey returns the plaintext key (shown once) and the row to store
+func NewKey(projectID, name string, scopes []string) (string, store.APIKey, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", store.APIKey{}, errors.New("name is required")
+	}
+	if err := ValidateScopes(scopes); err != nil {
+		return "", store.APIKey{}, err
+	}
+	var b [28]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", store.APIKey{}, err
+	}
+	prefix := "lw_" + hex.EncodeToString(b[:4])
+	key := prefix + "_" + hex.EncodeToString(b[4:])
+	return key, store.APIKey{
+		ID:        core.NewID("k"),
+		ProjectID: projectID,
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      Hash(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+func Hash(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+type keyScopes []string
+
+func (k keyScopes) has(scope string) bool {
+	if slices.Contains(k, scope) {
+		return true
+	}
+	return scope != ScopeProjectsAdmin && slices.Contains(k, ScopeAdmin)
+}
+
+type ctxKey struct{}
+
+// key that authenticated the request, if any; with auth disabled it is a
+// synthetic key holding every scope
+func FromContext(ctx context.Context) (store.APIKey, bool) {
+	k, ok := ctx.Value(ctxKey{}).(store.APIKey)
+	return k, ok
+}
+
+// ProjectID is the project the request acts in
+func ProjectID(ctx context.Context) string {
+	if k, ok := FromContext(ctx); ok && k.ProjectID != "" {
+		return k.ProjectID
+	}
+	return store.DefaultProjectID
+}
+
+func HasScope(ctx context.Context, scope string) bool {
+	k, ok := FromContext(ctx)
+	return ok && keyScopes(k.Scopes).has(scope)
+}
+
+type Authenticator struct {
+	Store    store.Store
+	Disabled bool // every request is allowed (local development)
+
+	touched sync.Map // key id → time.Time of last persisted use
+}
+
+// Authenticate errors; ErrUnavailable and ErrNoStore mean the key could not be checked
+var (
+	ErrMissingKey  = errors.New("missing API key")
+	ErrInvalidKey  = errors.New("invalid API key")
+	ErrRevokedKey  = errors.New("API key has been revoked")
+	ErrUnavailable = errors.New("could not verify API key")
+	ErrNoStore     = errors.New("DB not configured")
+)
+
+// RawKey takes the key from an X-API-Key value or else an "Authorization:
+// Bearer" value; both empty is not an error here
+func RawKey(apiKey, authorization string) (string, error) {
+	if apiKey != "" || authorization == "" {
+		return apiKey, nil
+	}
+	scheme, tok, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", errors.New("Authorization header must be 'Bearer <key>'")
+	}
+	return strings.TrimSpace(tok), nil
+}
+
+// Authenticate resolves a raw key and returns ctx carrying it. With auth
+// disabled every caller gets a key with all scopes in project ("" = default).
+func (a *Authenticator) Authenticate(ctx context.Context, raw, project string) (context.Context, error) {
+	if a.Disabled {
+		return context.WithValue(ctx, ctxKey{}, store.APIKey{ProjectID: project, Scopes: AllScopes}), nil
+	}
+	if raw == "" {
+		return ctx, ErrMissingKey
+	}
+	if a.Store == nil {
+		return ctx, ErrNoStore
+	}
+	lctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	k, err := a.Store.GetAPIKeyByHash(lctx, Hash(raw))
+	cancel()
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return ctx, ErrInvalidKey
+	case err != nil:
+		return ctx, ErrUnavailable
+	case k.RevokedAt != nil:
+		return ctx, ErrRevokedKey
+	}
+	a.touch(k.ID)
+	return context.WithValue(ctx, ctxKey{}, k), nil
+}
+
+// Middleware resolves the key from "Authorization: Bearer" or "X-API-Key";
+// requests without a valid key get 401. With auth disabled the project
+// comes from the X-Project header (default project if absent).
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := RawKey(r.Header.Get("X-API-Key"), r.Header.Get("Authorization"))
+		if err != nil && !a.Disabled {
+			deny(w, r, http.StatusUnauthorized, err.Error(), "")
+			return
+		}
+		ctx, err := a.Authenticate(r.Context(), raw, r.Header.Get("X-Project"))
+		switch {
+		case errors.Is(err, ErrUnavailable), errors.Is(err, ErrNoStore):
+			deny(w, r, http.StatusServiceUnavailable, err.Error(), "")
+			return
+		case err != nil:
+			deny(w, r, http.StatusUnauthorized, err.Error(), "")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Require answers 403 unless the request's key has scope (or admin)
+func (a *Authenticator) Require(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if a.Disabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+			k, ok := FromContext(r.Context())
+			if !ok {
+				deny(w, r, http.StatusUnauthorized, "missing API key", "")
+				return
+			}
+			if !keyScopes(k.Scopes).has(scope) {
+				deny(w, r, http.StatusForbidden, "API key lacks the required scope", scope)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (a *Authenticator) touch(id string) {
+	now := time.Now()
+	if v, ok := a.touched.Load(id); ok && now.Sub(v.(time.Time)) < touchEvery {
+		return
+	}
+	a.touched.Store(id, now)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = a.Store.TouchAPIKey(ctx, id, now.UTC())
+	}()
+}
+
+func deny(w http.ResponseWriter, r *http.Request, status int, msg, scope string) {
+	p := api.Problem{Type: api.TypeUnauthorized, Status: status, Detail: msg, RequiredScope: scope}
+	switch status {
+	case http.StatusUnauthorized:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="linkwatch"`)
+	case http.StatusForbidden:
+		p.Type = api.TypeForbidden
+	default:
+		p.Type = ""
+	}
+	api.WriteProblem(w, r, p)
+}
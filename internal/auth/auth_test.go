@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T) store.Store {
+	ctx := context.Background()
+	s, err := store.OpenSQLite(ctx, ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	m, err := store.Migrator(s)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	return s
+}
+
+func TestMiddlewareAndScopes(t *testing.T) {
+	st := testStore(t)
+	ctx := context.Background()
+	a := &Authenticator{Store: st}
+
+	r := chi.NewRouter()
+	r.Use(a.Middleware)
+	r.With(a.Require(ScopeTargetsRead)).Get("/read", func(w http.ResponseWriter, r *http.Request) {})
+	r.With(a.Require(ScopeTargetsWrite)).Post("/write", func(w http.ResponseWriter, r *http.Request) {})
+	r.With(a.Require(ScopeProjectsAdmin)).Get("/projects", func(w http.ResponseWriter, r *http.Request) {})
+
+	reader, readerRow, err := NewKey(store.DefaultProjectID, "reader", []string{ScopeTargetsRead})
+	require.NoError(t, err)
+	require.NoError(t, st.CreateAPIKey(ctx, readerRow))
+	admin, adminRow, err := NewKey(store.DefaultProjectID, "root", []string{ScopeAdmin})
+	require.NoError(t, err)
+	require.NoError(t, st.CreateAPIKey(ctx, adminRow))
+
+	do := func(method, path string, hdr map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range hdr {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("GET", "/read", nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Equal(t, api.ProblemContentType, rec.Header().Get("Content-Type"))
+	var body api.Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, api.TypeUnauthorized, body.Type)
+	require.Equal(t, http.StatusUnauthorized, body.Status)
+
+	require.Equal(t, http.StatusUnauthorized, do("GET", "/read", map[string]string{"Authorization": "Bearer lw_nope"}).Code)
+	require.Equal(t, http.StatusUnauthorized, do("GET", "/read", map[string]string{"Authorization": "Basic xyz"}).Code)
+	require.Equal(t, http.StatusOK, do("GET", "/read", map[string]string{"Authorization": "Bearer " + reader}).Code)
+	require.Equal(t, http.StatusOK, do("GET", "/read", map[string]string{"X-API-Key": reader}).Code)
+
+	rec = do("POST", "/write", map[string]string{"X-API-Key": reader})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, api.TypeForbidden, body.Type)
+	require.Equal(t, ScopeTargetsWrite, body.RequiredScope)
+
+	require.Equal(t, http.StatusOK, do("POST", "/write", map[string]string{"X-API-Key": admin}).Code, "admin implies all scopes")
+	require.Equal(t, http.StatusForbidden, do("GET", "/projects", map[string]string{"X-API-Key": admin}).Code,
+		"admin is project-local, projects:admin must be granted explicitly")
+
+	//last use is recorded
+	require.Eventually(t, func() bool {
+		k, err := st.GetAPIKeyByHash(ctx, Hash(reader))
+		return err == nil && k.LastUsedAt != nil
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, st.RevokeAPIKey(ctx, readerRow.ID))
+	require.Equal(t, http.StatusUnauthorized, do("GET", "/read", map[string]string{"X-API-Key": reader}).Code)
+}
+
+func TestDisabledAllowsEverything(t *testing.T) {
+	a := &Authenticator{Disabled: true}
+	var project string
+	h := a.Middleware(a.Require(ScopeAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		project = ProjectID(r.Context())
+	})))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, store.DefaultProjectID, project)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Project", "p_other")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	require.Equal(t, "p_other", project)
+}
+
+func TestNewKeyValidates(t *testing.T) {
+	_, _, err := NewKey(store.DefaultProjectID, "", []string{ScopeAdmin})
+	require.Error(t, err)
+	_, _, err = NewKey(store.DefaultProjectID, "x", nil)
+	require.Error(t, err)
+	_, _, err = NewKey(store.DefaultProjectID, "x", []string{"targets:delete"})
+	require.ErrorContains(t, err, "unknown scope")
+
+	key, k, err := NewKey(store.DefaultProjectID, "x", []string{ScopeResultsRead})
+	require.NoError(t, err)
+	require.Equal(t, Hash(key), k.Hash)
+	require.Contains(t, key, k.Prefix+"_")
+}
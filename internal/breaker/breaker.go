@@ -0,0 +1,148 @@
+// Package breaker stops checking hosts that keep failing. After Threshold
+// failed checks in a row a host's circuit opens: its checks are skipped
+// except for one probe per Cooldown, and the first probe that succeeds
+// closes it again.
+package breaker
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// circuit states
+const (
+	Closed   = "closed"    // checks run
+	Open     = "open"      // checks are skipped until the cooldown is over
+	HalfOpen = "half_open" // one probe check is running
+)
+
+const (
+	DefaultThreshold = 5
+	DefaultCooldown  = 30 * time.Second
+)
+
+// Config is the config file shape ("breaker:")
+type Config struct {
+	Threshold int    `json:"threshold" yaml:"threshold"`                   // failed checks in a row that open a host, 0 = off
+	Cooldown  string `json:"cooldown,omitempty" yaml:"cooldown,omitempty"` // Go duration between probes, "" = DefaultCooldown
+}
+
+func Default() Config { return Config{Threshold: DefaultThreshold} }
+
+func (c Config) Validate() error {
+	if c.Threshold < 0 || c.Threshold > 1000 {
+		return fmt.Errorf("threshold: must be between 0 and 1000, got %d", c.Threshold)
+	}
+	if c.Cooldown != "" {
+		if d, err := time.ParseDuration(c.Cooldown); err != nil || d <= 0 {
+			return fmt.Errorf("cooldown: invalid duration %q", c.Cooldown)
+		}
+	}
+	return nil
+}
+
+// CooldownD is the parsed cooldown; c must be valid
+func (c Config) CooldownD() time.Duration {
+	if d, err := time.ParseDuration(c.Cooldown); err == nil && d > 0 {
+		return d
+	}
+	return DefaultCooldown
+}
+
+// HostState is a snapshot of one host that has failed recently
+type HostState struct {
+	Host     string     `json:"host"`
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`            // failed checks in a row
+	OpenedAt *time.Time `json:"opened_at,omitempty"` // last time the circuit opened
+	ProbeAt  *time.Time `json:"probe_at,omitempty"`  // earliest next probe while open
+}
+
+type Breaker struct {
+	cfg      Config
+	cooldown time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*host // only hosts with failures; success forgets them
+}
+
+type host struct {
+	failures int
+	state    string
+	openedAt time.Time
+}
+
+func New(cfg Config) *Breaker {
+	return &Breaker{cfg: cfg, cooldown: cfg.CooldownD(), hosts: map[string]*host{}}
+}
+
+func (b *Breaker) Config() Config { return b.cfg }
+
+// Allow reports whether a check of host may run now. While the circuit is
+// open it lets one probe through per cooldown, and none while it runs
+func (b *Breaker) Allow(name string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h, ok := b.hosts[name]
+	if !ok || h.state == Closed {
+		return true
+	}
+	if h.state == Open && !now.Before(h.openedAt.Add(b.cooldown)) {
+		h.state = HalfOpen
+		return true
+	}
+	return false
+}
+
+// Record counts the outcome of a check that Allow let through
+func (b *Breaker) Record(name string, ok bool, now time.Time) {
+	if b.cfg.Threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ok {
+		delete(b.hosts, name)
+		return
+	}
+	h, found := b.hosts[name]
+	if !found {
+		h = &host{state: Closed}
+		b.hosts[name] = h
+	}
+	h.failures++
+	if h.state == HalfOpen || h.failures >= b.cfg.Threshold {
+		h.state, h.openedAt = Open, now
+	}
+}
+
+// States lists hosts with failures, open ones first, then by host
+func (b *Breaker) States() []HostState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]HostState, 0, len(b.hosts))
+	for name, h := range b.hosts {
+		s := HostState{Host: name, State: h.state, Failures: h.failures}
+		if !h.openedAt.IsZero() {
+			opened, probe := h.openedAt.UTC(), h.openedAt.Add(b.cooldown).UTC()
+			s.OpenedAt = &opened
+			if h.state == Open {
+				s.ProbeAt = &probe
+			}
+		}
+		out = append(out, s)
+	}
+	slices.SortFunc(out, func(a, b HostState) int {
+		if (a.State == Closed) != (b.State == Closed) {
+			if a.State == Closed {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.Host, b.Host)
+	})
+	return out
+}
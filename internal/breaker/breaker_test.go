@@ -0,0 +1,79 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenProbeClose(t *testing.T) {
+	b := New(Config{Threshold: 3, Cooldown: "10s"})
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for range 2 {
+		require.True(t, b.Allow("a.test", now))
+		b.Record("a.test", false, now)
+	}
+	require.Equal(t, []HostState{{Host: "a.test", State: Closed, Failures: 2}}, b.States())
+	b.Record("a.test", true, now)
+	require.Empty(t, b.States(), "a success resets the count")
+
+	for range 3 {
+		require.True(t, b.Allow("a.test", now))
+		b.Record("a.test", false, now)
+	}
+	require.False(t, b.Allow("a.test", now), "open")
+	require.True(t, b.Allow("b.test", now), "other hosts are not affected")
+	st := b.States()
+	require.Len(t, st, 1)
+	require.Equal(t, Open, st[0].State)
+	require.Equal(t, now.Add(10*time.Second), *st[0].ProbeAt)
+
+	//one probe after the cooldown, nothing else while it runs
+	later := now.Add(10 * time.Second)
+	require.True(t, b.Allow("a.test", later))
+	require.False(t, b.Allow("a.test", later))
+	require.Equal(t, HalfOpen, b.States()[0].State)
+
+	//a failed probe opens it for another cooldown
+	b.Record("a.test", false, later)
+	require.False(t, b.Allow("a.test", later.Add(5*time.Second)))
+	require.True(t, b.Allow("a.test", later.Add(10*time.Second)))
+	b.Record("a.test", true, later.Add(10*time.Second))
+	require.True(t, b.Allow("a.test", later.Add(10*time.Second)))
+	require.Empty(t, b.States())
+}
+
+func TestOff(t *testing.T) {
+	b := New(Config{})
+	now := time.Now()
+	for range 100 {
+		b.Record("a.test", false, now)
+	}
+	require.True(t, b.Allow("a.test", now))
+	require.Empty(t, b.States())
+}
+
+func TestStatesOrder(t *testing.T) {
+	b := New(Config{Threshold: 2})
+	now := time.Now()
+	b.Record("c.test", false, now)
+	b.Record("b.test", false, now)
+	b.Record("b.test", false, now)
+	b.Record("a.test", false, now)
+	var hosts []string
+	for _, s := range b.States() {
+		hosts = append(hosts, s.Host)
+	}
+	require.Equal(t, []string{"b.test", "a.test", "c.test"}, hosts, "open first")
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Default().Validate())
+	require.Equal(t, DefaultCooldown, Default().CooldownD())
+	require.Equal(t, time.Minute, Config{Cooldown: "1m"}.CooldownD())
+	require.ErrorContains(t, Config{Threshold: -1}.Validate(), "threshold")
+	require.ErrorContains(t, Config{Cooldown: "soon"}.Validate(), "cooldown")
+	require.ErrorContains(t, Config{Cooldown: "0s"}.Validate(), "cooldown")
+}
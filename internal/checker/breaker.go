@@ -0,0 +1,40 @@
+package checker
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/breaker"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/store"
+)
+
+// error of the results recorded instead of checks while a host's circuit is open
+const SkippedCircuitOpen = store.SkippedPrefix + "host circuit open"
+
+// WithBreaker replaces breaker.Default
+func WithBreaker(cfg breaker.Config) Option {
+	return func(c *Checker) { c.SetBreaker(cfg) }
+}
+
+// SetBreaker replaces the per-host circuit breaker, also while running; all
+// circuits start closed
+func (c *Checker) SetBreaker(cfg breaker.Config) { c.breaker.Store(breaker.New(cfg)) }
+
+// Breakers lists the hosts that failed recently and their circuit state
+func (c *Checker) Breakers() []breaker.HostState { return c.breaker.Load().States() }
+
+// the host is down or broken, as opposed to refusing this one URL; blocked
+// dials never reached it
+func hostFailed(r Result) bool {
+	var blocked *netguard.BlockedError
+	if errors.As(r.Err, &blocked) {
+		return false
+	}
+	return r.Err != nil || (r.Status != nil && *r.Status >= 500)
+}
+
+func skipped(j job) store.CheckResult {
+	s := SkippedCircuitOpen
+	return store.CheckResult{TargetID: j.ID, CheckedAt: time.Now(), Error: &s}
+}
@@ -0,0 +1,107 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/breaker"
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/retry"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakerSkipsOpenHostUntilProbeSucceeds(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+	var hits atomic.Int32
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+
+	var jobs []job
+	for _, p := range []string{"/a", "/b"} {
+		canon, host, err := core.Canonicalize(srv.URL + p)
+		require.NoError(t, err)
+		tg, _, err := s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), canon, host)
+		require.NoError(t, err)
+		jobs = append(jobs, job{ID: tg.ID, URL: tg.URL, Host: tg.Host})
+	}
+	a, b := jobs[0], jobs[1]
+
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()),
+		WithRetryPolicy(retry.Policy{MaxAttempts: 1}), WithBreaker(breaker.Config{Threshold: 2, Cooldown: "50ms"}))
+	c.doCheck(ctx, a)
+	c.doCheck(ctx, b)
+	require.Equal(t, int32(2), hits.Load())
+	st := c.Breakers()
+	require.Len(t, st, 1)
+	require.Equal(t, a.Host, st[0].Host)
+	require.Equal(t, breaker.Open, st[0].State)
+
+	//open: recorded as skipped without a request
+	c.doCheck(ctx, a)
+	require.Equal(t, int32(2), hits.Load())
+	r := lastResult(t, s, a.ID)
+	require.Equal(t, SkippedCircuitOpen, *r.Error)
+	require.Nil(t, r.StatusCode)
+	//and left out of uptime: only the real 503 counts
+	up, err := s.Uptime(ctx, "", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	for _, u := range up {
+		if u.TargetID == a.ID {
+			require.Equal(t, 1, u.Checks)
+		}
+	}
+
+	//after the cooldown one probe goes through and closes it
+	time.Sleep(60 * time.Millisecond)
+	status.Store(http.StatusOK)
+	c.doCheck(ctx, b)
+	require.Equal(t, int32(3), hits.Load())
+	require.Empty(t, c.Breakers())
+	c.doCheck(ctx, a)
+	require.Equal(t, int32(4), hits.Load())
+	require.Equal(t, 200, *lastResult(t, s, a.ID).StatusCode)
+}
+
+// a dns: target's host is the name it asks about, not a server of that name
+func TestBreakerKeepsDNSTargetsApart(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+	canon, host, err := core.Canonicalize("dns:missing.test")
+	require.NoError(t, err)
+	tg, _, err := s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), canon, host)
+	require.NoError(t, err)
+	j := job{ID: tg.ID, URL: tg.URL, Host: tg.Host}
+
+	c := New(s, 1, time.Second, time.Hour, WithDNSResolver(dnsServer(t, testZone())),
+		WithRetryPolicy(retry.Policy{MaxAttempts: 1}), WithBreaker(breaker.Config{Threshold: 1, Cooldown: "1h"}))
+	c.doCheck(ctx, j)
+	require.NotNil(t, lastResult(t, s, j.ID).Error)
+	st := c.Breakers()
+	require.Len(t, st, 1)
+	require.Equal(t, "dns:missing.test", st[0].Host)
+
+	web := job{ID: "t_web", URL: "https://missing.test/", Host: "missing.test"}
+	require.Equal(t, "missing.test", web.key())
+	require.True(t, c.breaker.Load().Allow(web.key(), time.Now()), "web checks of the name are not skipped")
+}
+
+// 4xx is the URL's problem, a blocked dial never reached the host
+func TestBreakerIgnoresClientErrorsAndBlocks(t *testing.T) {
+	code := func(n int) *int { return &n }
+	require.False(t, hostFailed(Result{Status: code(404)}))
+	require.False(t, hostFailed(Result{Status: code(200)}))
+	require.True(t, hostFailed(Result{Status: code(502)}))
+	require.False(t, hostFailed(Result{Err: &netguard.BlockedError{}}))
+}
@@ -2,30 +2,104 @@ package checker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/breaker"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/headers"
+	"github.com/nurzh/linkwatch/internal/hostlimit"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/retry"
+	"github.com/nurzh/linkwatch/internal/secrets"
 	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/internal/transport"
 )
 
 type job struct {
-	ID, URL, Host string
+	ID, ProjectID, URL, Host string
+	Labels                   map[string]string
+	Timeout                  time.Duration       // 0 → checker default
+	Retry                    *retry.Overrides    // nil → global policy
+	Transport                *transport.Settings // nil → global settings
+	Headers                  map[string]string   // values may reference secrets
+	BasicAuth                *headers.BasicAuth
+	retry                    *attempts // nil before the first attempt
+}
+
+// a check between its attempts. A retry goes back to the dispatcher, so
+// neither its backoff nor the host's next slot is waited for in a worker
+type attempts struct {
+	n         int // made so far
+	res       store.CheckResult
+	failed    bool // for the breaker: the host itself looks broken
+	policy    retry.Policy
+	cr        *credentials
+	brk       *breaker.Breaker
+	notBefore time.Time // end of the backoff
+}
+
+// key of j's host for the per-host queue, limits and breaker. A dns: target's
+// host is the name it asks about, not a server it talks to, so it is kept
+// apart from web and tcp targets on that name
+func (j job) key() string {
+	if strings.HasPrefix(j.URL, "dns:") {
+		return "dns:" + j.Host
+	}
+	return j.Host
 }
 
 type Checker struct {
-	db       *store.Postgres
-	client   *http.Client
-	jobs     chan job
-	state    atomic.Value // starting, running, stopped
-	hostLock sync.Map
-	interval time.Duration
-	workers  int
+	db          store.Store
+	client      *http.Client // default transport settings
+	dial        dialFunc     // through the egress policy
+	transports  atomic.Pointer[transports]
+	probers     map[string]Prober // by URL scheme
+	timeout     atomic.Int64      // per attempt, unless the target overrides it
+	queue       *dispatcher
+	state       atomic.Value // starting, running, stopped
+	limiter     atomic.Pointer[hostlimit.Limiter]
+	breaker     atomic.Pointer[breaker.Breaker]
+	interval    atomic.Int64
+	reconf      chan struct{} // interval changed
+	egress      atomic.Pointer[netguard.Policy]
+	retry       atomic.Pointer[retry.Policy] // global, targets may override it
+	dnsResolver atomic.Value                 // string, default for dns: targets
+	lastRun     map[string]time.Time         // target id → last enqueue, scheduler goroutine only
+	events      events.Publisher             // nil: no live events
+	secrets     *secrets.Box                 // nil: targets cannot use secrets
+
+	upMu sync.Mutex
+	up   map[string]bool // target id → last result was up, for state events
+
+	mu      sync.Mutex
+	workers int // desired
+	running int // started worker goroutines
+	runCtx  context.Context
+	wg      sync.WaitGroup
+	quit    chan struct{} // each receive stops one worker
 }
 
-func New(db *store.Postgres, workers int, reqTimeout, interval time.Duration) *Checker {
+type Option func(*Checker)
+
+// WithEvents publishes every result and up/down change to p
+func WithEvents(p events.Publisher) Option {
+	return func(c *Checker) { c.events = p }
+}
+
+// WithEgressPolicy replaces netguard.Default
+func WithEgressPolicy(p *netguard.Policy) Option {
+	return func(c *Checker) { c.SetEgressPolicy(p) }
+}
+
+func New(db store.Store, workers int, reqTimeout, interval time.Duration, opts ...Option) *Checker {
 	if workers <= 0 {
 		workers = 4
 	}
@@ -36,25 +110,158 @@ func New(db *store.Postgres, workers int, reqTimeout, interval time.Duration) *C
 		interval = 15 * time.Second
 	}
 
-	client := &http.Client{
-		Timeout: reqTimeout,
+	c := &Checker{
+		db:      db,
+		lastRun: map[string]time.Time{},
+		up:      map[string]bool{},
+		reconf:  make(chan struct{}, 1),
+		workers: workers,
+		quit:    make(chan struct{}),
+	}
+	c.interval.Store(int64(interval))
+	c.timeout.Store(int64(reqTimeout))
+	c.state.Store("starting")
+	c.egress.Store(netguard.Default())
+	c.dnsResolver.Store("")
+	c.SetRetryPolicy(retry.Default())
+	c.SetHostLimits(hostlimit.Config{})
+	c.SetBreaker(breaker.Default())
+	c.queue = newDispatcher(c.limiter.Load)
+	//per-attempt context deadline instead of a client timeout;
+	//the policy is looked up per dial so SetEgressPolicy applies to pooled transports too
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	c.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return c.egress.Load().DialContext(dialer)(ctx, network, addr)
+	}
+	c.client = newHTTPClient(0, c.dial)
+	c.SetTransport(transport.Settings{})
+	c.probers = map[string]Prober{
+		"http":  httpProber{c.httpClient},
+		"https": httpProber{c.httpClient},
+		"tcp":   tcpProber{dial: c.dial, tlsFor: c.tlsConfig},
+		"dns":   dnsProber{dial: c.dial, resolver: c.DNSResolver},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+func (c *Checker) EgressPolicy() *netguard.Policy { return c.egress.Load() }
+
+// WithRetryPolicy replaces retry.Default
+func WithRetryPolicy(p retry.Policy) Option {
+	return func(c *Checker) { c.SetRetryPolicy(p) }
+}
+
+func (c *Checker) RetryPolicy() retry.Policy { return *c.retry.Load() }
+
+// SetRetryPolicy changes the global retry policy, also while running
+func (c *Checker) SetRetryPolicy(p retry.Policy) { c.retry.Store(&p) }
+
+// SetEgressPolicy swaps the policy applied to new connections, also while running
+func (c *Checker) SetEgressPolicy(p *netguard.Policy) {
+	if p != nil {
+		c.egress.Store(p)
+	}
+}
+
+// SetConcurrency changes the number of workers, also while running
+func (c *Checker) SetConcurrency(n int) {
+	if n <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.workers = n
+	if c.runCtx != nil {
+		c.resizeLocked()
+	}
+}
+
+func (c *Checker) Timeout() time.Duration { return time.Duration(c.timeout.Load()) }
+
+// SetTimeout changes the per-attempt timeout, from the next check on
+func (c *Checker) SetTimeout(d time.Duration) {
+	if d > 0 {
+		c.timeout.Store(int64(d))
+	}
+}
+
+// SetInterval changes the schedule period, also while running
+func (c *Checker) SetInterval(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.interval.Store(int64(d))
+	select {
+	case c.reconf <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Checker) resizeLocked() {
+	for c.running < c.workers {
+		c.running++
+		c.wg.Add(1)
+		go c.worker(c.runCtx)
+	}
+	for c.running > c.workers {
+		c.running--
+		//an idle worker picks it up; busy ones finish their check first
+		go func(ctx context.Context) {
+			select {
+			case c.quit <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}(c.runCtx)
+	}
+}
+
+func (c *Checker) worker(ctx context.Context) {
+	defer c.wg.Done()
+	for {
+		j, ok := c.queue.next(ctx, c.quit)
+		if !ok {
+			return
+		}
+		c.fetchRobots(ctx, j)
+		if next := c.attempt(ctx, j); next != nil {
+			c.queue.retry(*next)
+		} else {
+			c.queue.done(j)
+		}
+	}
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dial nil = default transport (unrestricted)
+func newHTTPClient(timeout time.Duration, dial dialFunc) *http.Client {
+	var rt http.RoundTripper = http.DefaultTransport
+	if dial != nil {
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.DialContext = dial
+		//an environment proxy would make the request on our behalf, past the policy
+		tr.Proxy = nil
+		rt = tr
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: rt,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= 5 {
 				//stop, return 3xx
 				return http.ErrUseLastResponse
 			}
+			//Go only drops Authorization and Cookie, and only for other domains;
+			//a downgrade to http would send them in clear text
+			if req.URL.Host != via[0].URL.Host || (req.URL.Scheme == "http" && via[0].URL.Scheme == "https") {
+				credentialsFrom(req.Context()).strip(req)
+			}
 			return nil
 		},
 	}
-	c := &Checker{
-		db:       db,
-		client:   client,
-		jobs:     make(chan job, workers*4),
-		interval: interval,
-		workers:  workers,
-	}
-	c.state.Store("starting")
-	return c
 }
 
 func (c *Checker) State() string {
@@ -69,36 +276,33 @@ func (c *Checker) Start(ctx context.Context) {
 	defer c.state.Store("stopped")
 
 	// workers
-	var wg sync.WaitGroup
-	for i := 0; i < c.workers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for j := range c.jobs {
-				c.doCheck(ctx, j)
-			}
-		}()
-	}
+	c.mu.Lock()
+	c.runCtx = ctx
+	c.resizeLocked()
+	c.mu.Unlock()
 
 	//scheduler
-	ticker := time.NewTicker(c.interval)
+	ticker := time.NewTicker(time.Duration(c.interval.Load()))
 	defer ticker.Stop()
 
 	enqueueAll := func() {
+		floors := c.projectFloors(ctx)
 		var after *api.Cursor
 		for {
-			items, next, err := c.db.ListTargets(ctx, nil, after, 500)
+			items, next, err := c.db.ListTargets(ctx, "", nil, after, 500)
 			if err != nil {
 				return
 			}
 			for _, t := range items {
-				select {
-				case c.jobs <- job{ID: t.ID, URL: t.URL, Host: t.Host}:
-				case <-ctx.Done():
-					close(c.jobs)
-					wg.Wait()
-					return
+				if c.queue.queued(t.ID) {
+					//still waiting for its host from an earlier tick
+					continue
 				}
+				if !c.due(t, floors[t.ProjectID], time.Now()) {
+					continue
+				}
+				c.queue.submit(job{ID: t.ID, ProjectID: t.ProjectID, URL: t.URL, Host: t.Host, Labels: t.Labels, Timeout: t.Settings.TimeoutD(), Retry: t.Settings.Retry, Transport: t.Settings.Transport,
+					Headers: t.Settings.Headers, BasicAuth: t.Settings.BasicAuth})
 			}
 			if next == nil {
 				break
@@ -111,66 +315,205 @@ func (c *Checker) Start(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			close(c.jobs)
-			wg.Wait()
+			c.wg.Wait()
+			//retries that did not get their turn keep their last attempt
+			for _, j := range c.queue.retries() {
+				c.finish(j)
+			}
 			return
+		case <-c.reconf:
+			ticker.Reset(time.Duration(c.interval.Load()))
 		case <-ticker.C:
 			enqueueAll()
 		}
 	}
 }
 
+// project id → min check interval, for projects that set one
+func (c *Checker) projectFloors(ctx context.Context) map[string]time.Duration {
+	projects, err := c.db.ListProjects(ctx)
+	if err != nil {
+		return nil
+	}
+	floors := make(map[string]time.Duration, len(projects))
+	for _, p := range projects {
+		if d := p.MinInterval(); d > 0 {
+			floors[p.ID] = d
+		}
+	}
+	return floors
+}
+
+// targets with their own interval are skipped until it has elapsed
+// (within half a tick, so a 30s target on a 15s tick runs every other tick);
+// floor is the project's minimum interval and wins over anything shorter
+func (c *Checker) due(t store.Target, floor time.Duration, now time.Time) bool {
+	iv := max(t.Settings.IntervalD(), floor)
+	if iv <= 0 {
+		return true
+	}
+	if last, ok := c.lastRun[t.ID]; ok && now.Sub(last)+time.Duration(c.interval.Load())/2 < iv {
+		return false
+	}
+	c.lastRun[t.ID] = now
+	return true
+}
+
+// end of the backoff, zero for a first attempt
+func (a *attempts) backoff() time.Time {
+	if a == nil {
+		return time.Time{}
+	}
+	return a.notBefore
+}
+
+// runs all of j's attempts inline, sleeping in between; workers use
+// attempt and hand retries back to the dispatcher instead
 func (c *Checker) doCheck(ctx context.Context, j job) {
-	unlock := c.lockHost(j.Host)
-	defer unlock()
-
-	var statusPtr *int
-	var latencyPtr *int
-	var errStrPtr *string
-
-	for attempt := 1; attempt <= 3; attempt++ {
-		t0 := time.Now()
-		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, j.URL, nil)
-		req.Header.Set("Accept", "*/*")
-		req.Header.Set("User-Agent", "linkwatch/1.0 (+https://example)")
-		resp, err := c.client.Do(req)
-		elapsed := int(time.Since(t0) / time.Millisecond)
-		latencyPtr = &elapsed
-
-		if err == nil {
-			code := resp.StatusCode
-			resp.Body.Close()
-			statusPtr = &code
-			// retry on 5xx only
-			if code >= 500 && code <= 599 && attempt < 3 {
-				time.Sleep(time.Duration(200*(1<<(attempt-1))) * time.Millisecond)
-				continue
-			}
-			break
+	for {
+		next := c.attempt(ctx, j)
+		if next == nil {
+			return
+		}
+		//retries count against the host's rate too
+		wait := max(time.Until(next.retry.notBefore), c.limiter.Load().Reserve(j.key(), time.Now()))
+		if retry.Sleep(ctx, wait) != nil {
+			c.finish(*next)
+			return
+		}
+		j = *next
+	}
+}
+
+// makes j's next attempt and records the result when it is the last one;
+// otherwise returns the job to retry once next.retry.notBefore has passed
+func (c *Checker) attempt(ctx context.Context, j job) *job {
+	st := j.retry
+	if st == nil {
+		cr, err := c.credentials(ctx, j)
+		if err != nil {
+			//a configuration problem, not the host's: the breaker is not asked
+			msg := "secrets: " + err.Error()
+			c.record(j, store.CheckResult{TargetID: j.ID, CheckedAt: time.Now(), Error: &msg})
+			return nil
 		}
+		brk := c.breaker.Load()
+		if !brk.Allow(j.key(), time.Now()) {
+			c.record(j, skipped(j))
+			return nil
+		}
+		st = &attempts{res: store.CheckResult{TargetID: j.ID}, policy: c.RetryPolicy().With(j.Retry), cr: cr, brk: brk}
+		j.retry = st
+	}
+	timeout := c.Timeout()
+	if j.Timeout > 0 {
+		timeout = j.Timeout
+	}
+	ctx = withTargetTransport(withCredentials(ctx, st.cr), j.Transport)
 
-		s := err.Error()
-		errStrPtr = &s
-		statusPtr = nil
-		if attempt < 3 {
-			time.Sleep(time.Duration(200*(1<<(attempt-1))) * time.Millisecond)
-			continue
+	prober := c.prober(j.URL)
+	if prober == nil {
+		s := "unsupported target scheme"
+		st.res.Error = &s
+		c.finish(j)
+		return nil
+	}
+	st.n++
+	r := c.probe(ctx, prober, j.URL, timeout)
+	st.failed = hostFailed(r)
+	//each attempt replaces the previous one as the result
+	a := attemptOf(st.n, r)
+	if a.Error != nil {
+		*a.Error = st.cr.redact(*a.Error)
+	}
+	res := &st.res
+	res.StatusCode, res.LatencyMS, res.Error, res.Details = a.StatusCode, a.LatencyMS, a.Error, nil
+	if r.Details != nil {
+		res.Details, _ = json.Marshal(r.Details)
+	}
+	res.Attempts = append(res.Attempts, a)
+
+	var blocked *netguard.BlockedError
+	if errors.As(r.Err, &blocked) {
+		//not transient
+		c.finish(j)
+		return nil
+	}
+	wait, again := st.policy.Next(st.n, r.Status, r.Err, r.RetryAfter)
+	if !again {
+		c.finish(j)
+		return nil
+	}
+	st.notBefore = time.Now().Add(wait)
+	return &j
+}
+
+// records the result of j's last attempt
+func (c *Checker) finish(j job) {
+	st := j.retry
+	st.res.CheckedAt = time.Now()
+	st.brk.Record(j.key(), !st.failed, st.res.CheckedAt)
+	c.record(j, st.res)
+}
+
+// stores and publishes a result
+func (c *Checker) record(j job, res store.CheckResult) {
+	if err := c.db.AppendCheckResult(context.Background(), res); err != nil {
+		return
+	}
+	c.publish(j, res)
+}
+
+// stored form of one probe attempt
+func attemptOf(n int, r Result) store.Attempt {
+	ms := int(r.Latency / time.Millisecond)
+	a := store.Attempt{Attempt: n, LatencyMS: &ms}
+	if r.Err != nil {
+		s := r.Err.Error()
+		var blocked *netguard.BlockedError
+		if errors.As(r.Err, &blocked) {
+			//the bare policy message is clearer than the wrapped dial error
+			s = blocked.Error()
 		}
-		break
+		a.Error = &s
+		return a
+	}
+	a.StatusCode = r.Status
+	return a
+}
+
+// result event, plus a state event when the target flipped between up and down
+func (c *Checker) publish(j job, res store.CheckResult) {
+	if c.events == nil {
+		return
+	}
+	ev := events.Event{ProjectID: j.ProjectID, TargetID: j.ID, URL: j.URL, Host: j.Host, Labels: j.Labels}
+	result := ev
+	result.Type, result.Time, result.Result = events.TypeResult, res.CheckedAt.UTC(), &res
+	c.events.Publish(result)
+	if res.Skipped() {
+		//says nothing about the target's state
+		return
 	}
 
-	_ = c.db.AppendCheckResult(context.Background(), store.CheckResult{
-		TargetID:   j.ID,
-		CheckedAt:  time.Now(),
-		StatusCode: statusPtr,
-		LatencyMS:  latencyPtr,
-		Error:      errStrPtr,
-	})
+	up := events.Up(res)
+	c.upMu.Lock()
+	was, known := c.up[j.ID]
+	c.up[j.ID] = up
+	c.upMu.Unlock()
+	if known && was != up {
+		change := ev
+		change.Type, change.Time, change.State = events.TypeState, res.CheckedAt.UTC(), events.Transition(was, up)
+		c.events.Publish(change)
+	}
 }
 
-func (c *Checker) lockHost(host string) func() {
-	v, _ := c.hostLock.LoadOrStore(host, make(chan struct{}, 1))
-	ch := v.(chan struct{})
-	ch <- struct{}{}
-	return func() { <-ch }
+func newRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("User-Agent", "linkwatch/1.0 (+https://example)")
+	return req, nil
 }
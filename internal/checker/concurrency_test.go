@@ -11,6 +11,7 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/netguard"
 	"github.com/nurzh/linkwatch/internal/store"
 	"github.com/stretchr/testify/require"
 )
@@ -75,7 +76,7 @@ func TestPerHostSerialization(t *testing.T) {
 
 	_ = add(srvB.URL + "/x")
 
-	c := New(pg, 4, 2*time.Second, 1*time.Hour)
+	c := New(pg, 4, 2*time.Second, 1*time.Hour, WithEgressPolicy(netguard.AllowAll()))
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 	c.Start(ctx)
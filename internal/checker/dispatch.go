@@ -0,0 +1,170 @@
+package checker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/hostlimit"
+)
+
+// dispatcher keeps a FIFO queue per host and hands workers only jobs whose
+// host is free (nothing in flight) and whose rate slot has come, so a slow
+// or rate-limited host never holds a worker that another host could use
+type dispatcher struct {
+	limiter func() *hostlimit.Limiter
+
+	mu      sync.Mutex
+	hosts   map[string]*hostQueue
+	ready   []string            // hosts whose head job can start now, oldest first
+	pending map[string]struct{} // target ids queued or running
+	changed chan struct{}       // closed and replaced when ready grows
+}
+
+type hostQueue struct {
+	jobs    []job
+	busy    bool // a worker is checking this host
+	waiting bool // the head's slot is booked, a timer makes it ready
+	ready   bool // listed in dispatcher.ready
+}
+
+func newDispatcher(limiter func() *hostlimit.Limiter) *dispatcher {
+	return &dispatcher{
+		limiter: limiter,
+		hosts:   map[string]*hostQueue{},
+		pending: map[string]struct{}{},
+		changed: make(chan struct{}),
+	}
+}
+
+// submit queues j behind its host's other jobs; false if the target is
+// already queued or running
+func (d *dispatcher) submit(j job) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.pending[j.ID]; ok {
+		return false
+	}
+	d.pending[j.ID] = struct{}{}
+	q, ok := d.hosts[j.key()]
+	if !ok {
+		q = &hostQueue{}
+		d.hosts[j.key()] = q
+	}
+	q.jobs = append(q.jobs, j)
+	d.considerLocked(j.key(), q)
+	return true
+}
+
+func (d *dispatcher) queued(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.pending[id]
+	return ok
+}
+
+// next blocks until a job is ready, ctx is done or quit fires; a returned job
+// holds its host until done
+func (d *dispatcher) next(ctx context.Context, quit <-chan struct{}) (job, bool) {
+	for {
+		d.mu.Lock()
+		if len(d.ready) > 0 {
+			host := d.ready[0]
+			d.ready = d.ready[1:]
+			q := d.hosts[host]
+			j := q.jobs[0]
+			q.jobs = q.jobs[1:]
+			q.ready, q.busy = false, true
+			d.mu.Unlock()
+			return j, true
+		}
+		changed := d.changed
+		d.mu.Unlock()
+		select {
+		case <-changed:
+		case <-quit:
+			return job{}, false
+		case <-ctx.Done():
+			return job{}, false
+		}
+	}
+}
+
+// done frees j's host for its next job
+func (d *dispatcher) done(j job) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pending, j.ID)
+	q := d.hosts[j.key()]
+	q.busy = false
+	d.considerLocked(j.key(), q)
+}
+
+// retry puts j back at the head of its host's queue, which j still holds;
+// it becomes ready once its backoff is over and the host's next slot came
+func (d *dispatcher) retry(j job) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	q := d.hosts[j.key()]
+	q.busy = false
+	q.jobs = append([]job{j}, q.jobs...)
+	d.considerLocked(j.key(), q)
+}
+
+// retries still queued, e.g. at shutdown; they are removed
+func (d *dispatcher) retries() []job {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []job
+	for _, q := range d.hosts {
+		if len(q.jobs) > 0 && q.jobs[0].retry != nil {
+			out = append(out, q.jobs[0])
+			q.jobs = q.jobs[1:]
+			delete(d.pending, out[len(out)-1].ID)
+		}
+	}
+	return out
+}
+
+// makes host ready if it is free and has a job, booking the head's slot
+// first; a slot in the future is waited for on a timer, not by a worker
+func (d *dispatcher) considerLocked(host string, q *hostQueue) {
+	if q.busy || q.waiting || q.ready {
+		return
+	}
+	if len(q.jobs) == 0 {
+		delete(d.hosts, host)
+		return
+	}
+	if wait := time.Until(q.jobs[0].retry.backoff()); wait > 0 {
+		//the slot is booked only once the backoff is over
+		q.waiting = true
+		time.AfterFunc(wait, func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			q.waiting = false
+			d.considerLocked(host, q)
+		})
+		return
+	}
+	if wait := d.limiter().Reserve(host, time.Now()); wait > 0 {
+		q.waiting = true
+		time.AfterFunc(wait, func() { d.wake(host, q) })
+		return
+	}
+	d.readyLocked(host, q)
+}
+
+func (d *dispatcher) wake(host string, q *hostQueue) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	q.waiting = false
+	d.readyLocked(host, q)
+}
+
+func (d *dispatcher) readyLocked(host string, q *hostQueue) {
+	q.ready = true
+	d.ready = append(d.ready, host)
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
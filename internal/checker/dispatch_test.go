@@ -0,0 +1,222 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/hostlimit"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/retry"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherHandsOutFreeHostsOnly(t *testing.T) {
+	lim := hostlimit.New(hostlimit.Config{})
+	d := newDispatcher(func() *hostlimit.Limiter { return lim })
+	ctx := context.Background()
+
+	require.True(t, d.submit(job{ID: "a1", Host: "a.test"}))
+	require.True(t, d.submit(job{ID: "a2", Host: "a.test"}))
+	require.True(t, d.submit(job{ID: "b1", Host: "b.test"}))
+	require.False(t, d.submit(job{ID: "a1", Host: "a.test"}), "already queued")
+	require.True(t, d.queued("a2"))
+
+	a1, ok := d.next(ctx, nil)
+	require.True(t, ok)
+	require.Equal(t, "a1", a1.ID)
+	b1, _ := d.next(ctx, nil)
+	require.Equal(t, "b1", b1.ID, "a.test is busy, its second job waits")
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, ok = d.next(short, nil)
+	require.False(t, ok, "nothing is free")
+
+	d.done(a1)
+	a2, ok := d.next(ctx, nil)
+	require.True(t, ok)
+	require.Equal(t, "a2", a2.ID)
+	require.False(t, d.queued("a1"))
+	d.done(a2)
+	d.done(b1)
+	require.Empty(t, d.hosts)
+
+	quit := make(chan struct{}, 1)
+	quit <- struct{}{}
+	_, ok = d.next(ctx, quit)
+	require.False(t, ok)
+}
+
+// workers must not pile up on a slow host while other hosts have work
+func TestSlowHostDoesNotStarveWorkers(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var inSlow, maxSlow int
+	var slowDone atomic.Int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inSlow++
+		maxSlow = max(maxSlow, inSlow)
+		mu.Unlock()
+		time.Sleep(300 * time.Millisecond)
+		mu.Lock()
+		inSlow--
+		mu.Unlock()
+		slowDone.Add(1)
+	}))
+	defer slow.Close()
+	var fastAt []time.Time
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fastAt = append(fastAt, time.Now())
+		mu.Unlock()
+	}))
+	defer fast.Close()
+
+	//slow targets first, so a FIFO queue would hand them out first
+	add := func(raw string) {
+		canon, host, err := core.Canonicalize(raw)
+		require.NoError(t, err)
+		_, _, err = s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), canon, host)
+		require.NoError(t, err)
+	}
+	for i := range 6 {
+		add(fmt.Sprintf("%s/%d", slow.URL, i))
+	}
+	for i := range 6 {
+		add(fmt.Sprintf("%s/%d", fast.URL, i))
+	}
+
+	c := New(s, 3, 2*time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()))
+	rctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	t0 := time.Now()
+	go func() { c.Start(rctx); close(done) }()
+	defer func() { cancel(); <-done }()
+
+	fastCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(fastAt)
+	}
+	require.Eventually(t, func() bool { return fastCount() == 6 }, 250*time.Millisecond, 5*time.Millisecond,
+		"every fast check finishes before the first slow one")
+	require.Zero(t, slowDone.Load())
+	mu.Lock()
+	require.Less(t, fastAt[5].Sub(t0), 300*time.Millisecond)
+	mu.Unlock()
+
+	require.Eventually(t, func() bool { return slowDone.Load() == 6 }, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 1, maxSlow, "one in-flight request per host")
+}
+
+// a retry waits for its backoff and its host's slot in the dispatcher, not in
+// a worker: the only worker keeps checking other hosts meanwhile
+func TestRetryDoesNotHoldWorker(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var flakyAt []time.Time
+	var fastAt time.Time
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		flakyAt = append(flakyAt, time.Now())
+		n := len(flakyAt)
+		mu.Unlock()
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer flaky.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fastAt = time.Now()
+		mu.Unlock()
+	}))
+	defer fast.Close()
+
+	add := func(raw string) store.Target {
+		canon, host, err := core.Canonicalize(raw)
+		require.NoError(t, err)
+		tg, _, err := s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), canon, host)
+		require.NoError(t, err)
+		return tg
+	}
+	tg := add(flaky.URL)
+	add(fast.URL)
+
+	//the retry's slot is 500ms out (2 rps) on top of a 100ms backoff
+	_, flakyHost, _ := core.Canonicalize(flaky.URL)
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()),
+		WithRetryPolicy(retry.Policy{MaxAttempts: 2, BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Statuses: []string{"5xx"}}),
+		WithHostLimits(hostlimit.Config{Hosts: map[string]hostlimit.Rate{flakyHost: {RPS: 2}}}))
+	rctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() { c.Start(rctx); close(done) }()
+	defer func() { cancel(); <-done }()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(flakyAt) == 2
+	}, 3*time.Second, 5*time.Millisecond)
+	mu.Lock()
+	require.False(t, fastAt.IsZero())
+	require.True(t, fastAt.Before(flakyAt[1]), "the fast host was checked during the retry's wait")
+	require.GreaterOrEqual(t, flakyAt[1].Sub(flakyAt[0]), 400*time.Millisecond, "the retry kept to the host's rate")
+	mu.Unlock()
+
+	require.Eventually(t, func() bool {
+		items, err := s.ListResults(ctx, tg.ID, nil, 1)
+		return err == nil && len(items) == 1 && len(items[0].Attempts) == 2
+	}, time.Second, 5*time.Millisecond)
+	r := lastResult(t, s, tg.ID)
+	require.Equal(t, 200, *r.StatusCode)
+}
+
+// retries still waiting at shutdown are recorded with their last attempt
+func TestRetryRecordedOnShutdown(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	canon, host, err := core.Canonicalize(srv.URL)
+	require.NoError(t, err)
+	tg, _, err := s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), canon, host)
+	require.NoError(t, err)
+
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()),
+		WithRetryPolicy(retry.Policy{MaxAttempts: 3, BaseBackoff: time.Hour, MaxBackoff: time.Hour, Statuses: []string{"5xx"}}))
+	rctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() { c.Start(rctx); close(done) }()
+	waiting := func() bool {
+		c.queue.mu.Lock()
+		defer c.queue.mu.Unlock()
+		q := c.queue.hosts[host]
+		return q != nil && q.waiting && len(q.jobs) == 1 && q.jobs[0].retry != nil
+	}
+	require.Eventually(t, waiting, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	r := lastResult(t, s, tg.ID)
+	require.Equal(t, 503, *r.StatusCode)
+	require.Len(t, r.Attempts, 1)
+	require.False(t, c.queue.queued(tg.ID))
+}
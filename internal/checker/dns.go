@@ -0,0 +1,161 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/netguard"
+)
+
+// WithDNSResolver sets the resolver asked for dns: targets that do not name
+// one; "" (the default) uses the system resolver
+func WithDNSResolver(addr string) Option {
+	return func(c *Checker) { c.SetDNSResolver(addr) }
+}
+
+func (c *Checker) DNSResolver() string {
+	s, _ := c.dnsResolver.Load().(string)
+	return s
+}
+
+// SetDNSResolver changes the default resolver, also while running
+func (c *Checker) SetDNSResolver(addr string) { c.dnsResolver.Store(addr) }
+
+// stored with dns results
+type dnsDetails struct {
+	Type     string   `json:"type"`
+	Answers  []string `json:"answers"`
+	Resolver string   `json:"resolver,omitempty"` // "" = system resolver
+}
+
+// resolves dns:name?type=..&expect=.. (see core.DNSType etc.); every expected
+// value must be among the answers
+type dnsProber struct {
+	dial     dialFunc      // resolvers named by the target, egress checked
+	resolver func() string // operator's default, dialed directly
+}
+
+func (p dnsProber) Probe(ctx context.Context, target string) Result {
+	u, err := url.Parse(target)
+	if err != nil {
+		return Result{Err: err}
+	}
+	q := u.Query()
+	typ := q.Get(core.DNSType)
+	if typ == "" {
+		typ = "A"
+	}
+	name := u.Opaque
+	if name == "" {
+		name = strings.TrimPrefix(u.Path, "/")
+	}
+
+	r, server, blocked := p.resolverFor(u.Host)
+	answers, err := lookup(ctx, r, typ, name)
+	if err != nil {
+		//the resolver reports dial errors as its own, which hides a policy block
+		if *blocked != nil {
+			return Result{Err: *blocked}
+		}
+		return Result{Err: err}
+	}
+	res := Result{Details: dnsDetails{Type: typ, Answers: answers, Resolver: server}}
+	for _, want := range q[core.DNSExpect] {
+		if !slices.ContainsFunc(answers, func(a string) bool { return dnsMatch(typ, a, want) }) {
+			res.Err = fmt.Errorf("expected %s %s, got %v", typ, want, answers)
+			break
+		}
+	}
+	return res
+}
+
+// the target's own server through the egress policy, else the default;
+// blocked is set if the policy refused a connection
+func (p dnsProber) resolverFor(server string) (r *net.Resolver, _ string, blocked *error) {
+	blocked = new(error)
+	dial := p.dial
+	if server == "" {
+		if p.resolver != nil {
+			server = p.resolver()
+		}
+		dial = (&net.Dialer{}).DialContext
+	}
+	if server == "" {
+		return net.DefaultResolver, "", blocked
+	}
+	addr := server
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if be := (*netguard.BlockedError)(nil); errors.As(err, &be) {
+				*blocked = be
+			}
+			return conn, err
+		},
+	}, server, blocked
+}
+
+// answers normalized like core.DNSValue; the name is made absolute so search
+// domains are not tried
+func lookup(ctx context.Context, r *net.Resolver, typ, name string) ([]string, error) {
+	fqdn := name + "."
+	var out []string
+	switch typ {
+	case "A", "AAAA":
+		network := map[string]string{"A": "ip4", "AAAA": "ip6"}[typ]
+		ips, err := r.LookupNetIP(ctx, network, fqdn)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			out = append(out, ip.Unmap().String())
+		}
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, fqdn)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, strings.TrimSuffix(strings.ToLower(cname), "."))
+	case "MX":
+		mxs, err := r.LookupMX(ctx, fqdn)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			out = append(out, strconv.Itoa(int(mx.Pref))+" "+strings.TrimSuffix(strings.ToLower(mx.Host), "."))
+		}
+	case "TXT":
+		txts, err := r.LookupTXT(ctx, fqdn)
+		if err != nil {
+			return nil, err
+		}
+		out = txts
+	default:
+		return nil, fmt.Errorf("unsupported record type %q", typ)
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// an MX expectation without a preference matches any preference
+func dnsMatch(typ, answer, want string) bool {
+	if answer == want {
+		return true
+	}
+	if typ == "MX" && !strings.Contains(want, " ") {
+		_, host, _ := strings.Cut(answer, " ")
+		return host == want
+	}
+	return false
+}
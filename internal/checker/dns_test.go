@@ -0,0 +1,153 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// udp dns server on loopback answering from zone (lower-case name without
+// the root dot → records); unknown names get NXDOMAIN
+func dnsServer(t *testing.T, zone map[string][]dnsmessage.Resource) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { pc.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var req dnsmessage.Message
+			if req.Unpack(buf[:n]) != nil || len(req.Questions) != 1 {
+				continue
+			}
+			q := req.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: req.ID, Response: true, Authoritative: true, RecursionAvailable: true},
+				Questions: req.Questions,
+			}
+			records, ok := zone[strings.TrimSuffix(strings.ToLower(q.Name.String()), ".")]
+			if !ok {
+				resp.RCode = dnsmessage.RCodeNameError
+			}
+			for _, r := range records {
+				//a CNAME is returned for any type, like a real server would
+				if r.Header.Type == q.Type || r.Header.Type == dnsmessage.TypeCNAME {
+					r.Header.Name, r.Header.Class = q.Name, dnsmessage.ClassINET
+					resp.Answers = append(resp.Answers, r)
+				}
+			}
+			out, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			pc.WriteTo(out, addr)
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+func testZone() map[string][]dnsmessage.Resource {
+	name := dnsmessage.MustNewName
+	return map[string][]dnsmessage.Resource{
+		"example.test": {
+			{Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeA, TTL: 60}, Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}},
+			{Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeA, TTL: 60}, Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 2}}},
+			{Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeAAAA, TTL: 60}, Body: &dnsmessage.AAAAResource{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}}},
+			{Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeMX, TTL: 60}, Body: &dnsmessage.MXResource{Pref: 10, MX: name("mail.example.test.")}},
+			{Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeTXT, TTL: 60}, Body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}},
+		},
+		"www.example.test": {
+			{Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeCNAME, TTL: 60}, Body: &dnsmessage.CNAMEResource{CNAME: name("example.test.")}},
+		},
+	}
+}
+
+func TestDNSProbe(t *testing.T) {
+	server := dnsServer(t, testZone())
+	direct := (&net.Dialer{}).DialContext
+	for _, tc := range []struct {
+		url         string
+		wantAnswers []string
+		wantErr     string
+	}{
+		{"dns:example.test", []string{"192.0.2.1", "192.0.2.2"}, ""},
+		{"dns:example.test?type=A&expect=192.0.2.2", []string{"192.0.2.1", "192.0.2.2"}, ""},
+		{"dns:example.test?type=AAAA&expect=2001:db8::1", []string{"2001:db8::1"}, ""},
+		{"dns:www.example.test?type=CNAME&expect=example.test", []string{"example.test"}, ""},
+		{"dns:example.test?type=MX&expect=mail.example.test", []string{"10 mail.example.test"}, ""},
+		{"dns:example.test?type=MX&expect=10+mail.example.test", []string{"10 mail.example.test"}, ""},
+		{"dns:example.test?type=TXT&expect=v%3Dspf1+-all", []string{"v=spf1 -all"}, ""},
+		{"dns:example.test?type=A&expect=192.0.2.9", []string{"192.0.2.1", "192.0.2.2"}, "expected A 192.0.2.9, got [192.0.2.1 192.0.2.2]"},
+		{"dns:example.test?type=MX&expect=20+mail.example.test", []string{"10 mail.example.test"}, "expected MX 20 mail.example.test"},
+		{"dns:missing.example.test", nil, "no such host"},
+	} {
+		canon, _, err := core.Canonicalize(tc.url)
+		require.NoError(t, err, tc.url)
+		p := dnsProber{dial: direct, resolver: func() string { return server }}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		r := p.Probe(ctx, canon)
+		cancel()
+		require.Nil(t, r.Status, tc.url)
+		if tc.wantErr == "" {
+			require.NoError(t, r.Err, tc.url)
+		} else {
+			require.ErrorContains(t, r.Err, tc.wantErr, tc.url)
+		}
+		if tc.wantAnswers == nil {
+			require.Nil(t, r.Details, tc.url)
+			continue
+		}
+		require.Equal(t, tc.wantAnswers, r.Details.(dnsDetails).Answers, tc.url)
+		require.Equal(t, server, r.Details.(dnsDetails).Resolver, tc.url)
+	}
+}
+
+// a resolver named in the target is dialed through the egress policy;
+// the configured default is the operator's and is not
+func TestDoCheckDNS(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+	server := dnsServer(t, testZone())
+	add := func(raw string) store.Target {
+		canon, host, err := core.Canonicalize(raw)
+		require.NoError(t, err)
+		tg, _, err := s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), canon, host)
+		require.NoError(t, err)
+		return tg
+	}
+	own, dflt := add("dns://"+server+"/example.test?type=MX"), add("dns:example.test?expect=192.0.2.1")
+
+	c := New(s, 1, time.Second, time.Hour, WithDNSResolver(server))
+	c.doCheck(ctx, job{ID: own.ID, URL: own.URL, Host: own.Host})
+	r := lastResult(t, s, own.ID)
+	require.NotNil(t, r.Error)
+	require.True(t, strings.HasPrefix(*r.Error, "blocked by egress policy"), *r.Error)
+	require.Nil(t, r.Details)
+
+	c.doCheck(ctx, job{ID: dflt.ID, URL: dflt.URL, Host: dflt.Host})
+	r = lastResult(t, s, dflt.ID)
+	require.Nil(t, r.Error)
+	require.Nil(t, r.StatusCode, "dns has no status")
+	require.NotNil(t, r.LatencyMS)
+	require.JSONEq(t, `{"type":"A","answers":["192.0.2.1","192.0.2.2"],"resolver":"`+server+`"}`, string(r.Details))
+
+	c.SetEgressPolicy(netguard.AllowAll())
+	c.doCheck(ctx, job{ID: own.ID, URL: own.URL, Host: own.Host})
+	r = lastResult(t, s, own.ID)
+	require.Nil(t, r.Error)
+	var d dnsDetails
+	require.NoError(t, json.Unmarshal(r.Details, &d))
+	require.Equal(t, []string{"10 mail.example.test"}, d.Answers)
+}
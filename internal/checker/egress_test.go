@@ -0,0 +1,73 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func lastResult(t *testing.T, s store.Store, id string) store.CheckResult {
+	items, err := s.ListResults(context.Background(), id, nil, 1)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	return items[0]
+}
+
+func TestEgressPolicyBlocksAtDialTime(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+
+	var hits int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer internal.Close()
+	_, port, _ := net.SplitHostPort(internal.Listener.Addr().String())
+
+	//trusted by its literal address, but redirects to a name resolving to loopback
+	front := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:"+port+"/admin", http.StatusFound)
+	}))
+	defer front.Close()
+
+	add := func(raw string) store.Target {
+		canon, host, err := core.Canonicalize(raw)
+		require.NoError(t, err)
+		tg, _, err := s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), canon, host)
+		require.NoError(t, err)
+		return tg
+	}
+	direct, redirected := add(internal.URL), add(front.URL)
+
+	c := New(s, 1, time.Second, time.Hour)
+	c.doCheck(ctx, job{ID: direct.ID, URL: direct.URL, Host: direct.Host})
+	r := lastResult(t, s, direct.ID)
+	require.Nil(t, r.StatusCode)
+	require.NotNil(t, r.Error)
+	require.Equal(t, "blocked by egress policy: 127.0.0.1 is loopback", *r.Error)
+
+	p, err := netguard.New(netguard.Rules{AllowHosts: []string{"127.0.0.1"}})
+	require.NoError(t, err)
+	c.SetEgressPolicy(p)
+	c.doCheck(ctx, job{ID: redirected.ID, URL: redirected.URL, Host: redirected.Host})
+	r = lastResult(t, s, redirected.ID)
+	require.NotNil(t, r.Error)
+	require.Contains(t, *r.Error, "localhost resolves to 127.0.0.1 (loopback)")
+	require.Zero(t, atomic.LoadInt32(&hits), "blocked requests never reach the server")
+
+	c.SetEgressPolicy(netguard.AllowAll())
+	c.doCheck(ctx, job{ID: redirected.ID, URL: redirected.URL, Host: redirected.Host})
+	r = lastResult(t, s, redirected.ID)
+	require.Nil(t, r.Error)
+	require.Equal(t, 200, *r.StatusCode)
+	require.EqualValues(t, 1, atomic.LoadInt32(&hits))
+}
@@ -0,0 +1,62 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	mu  sync.Mutex
+	evs []events.Event
+}
+
+func (r *recorder) Publish(ev events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.evs = append(r.evs, ev)
+}
+
+func TestPublishesResultsAndStateChanges(t *testing.T) {
+	s := testSQLite(t)
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) > 1 {
+			w.WriteHeader(http.StatusNotFound) // 4xx is not retried
+		}
+	}))
+	defer srv.Close()
+
+	canon, host, err := core.Canonicalize(srv.URL)
+	require.NoError(t, err)
+	tg, _, err := s.CreateOrGetTarget(context.Background(), store.DefaultProjectID, core.NewID("t"), canon, host)
+	require.NoError(t, err)
+
+	rec := &recorder{}
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()), WithEvents(rec))
+	j := job{ID: tg.ID, ProjectID: store.DefaultProjectID, URL: canon, Host: host, Labels: map[string]string{"env": "prod"}}
+	for range 3 {
+		c.doCheck(context.Background(), j)
+	}
+
+	//200, 404, 404: three results and a single up → down
+	require.Len(t, rec.evs, 4)
+	require.Equal(t, events.TypeResult, rec.evs[0].Type)
+	require.Equal(t, 200, *rec.evs[0].Result.StatusCode)
+	require.Equal(t, "prod", rec.evs[0].Labels["env"])
+	require.Equal(t, events.TypeResult, rec.evs[1].Type)
+	require.Equal(t, events.TypeState, rec.evs[2].Type)
+	require.Equal(t, &events.StateChange{From: "up", To: "down", Incident: "opened"}, rec.evs[2].State)
+	require.Equal(t, events.TypeResult, rec.evs[3].Type)
+}
@@ -0,0 +1,63 @@
+package checker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/hostlimit"
+)
+
+// robots.txt is read up to this size; Crawl-delay sits near the top
+const maxRobotsSize = 64 << 10
+
+// WithHostLimits paces checks per host; the default is unlimited
+func WithHostLimits(cfg hostlimit.Config) Option {
+	return func(c *Checker) { c.SetHostLimits(cfg) }
+}
+
+func (c *Checker) HostLimits() hostlimit.Config { return c.limiter.Load().Config() }
+
+// SetHostLimits replaces the per-host limits, also while running. Booked
+// slots and fetched crawl delays start over
+func (c *Checker) SetHostLimits(cfg hostlimit.Config) { c.limiter.Store(hostlimit.New(cfg)) }
+
+// fetchRobots reads the Crawl-delay of j's host in the background when the
+// limiter asks for it; until it is known the host is paced by rate alone
+func (c *Checker) fetchRobots(ctx context.Context, j job) {
+	lim := c.limiter.Load()
+	u, err := url.Parse(j.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	if !lim.WantsRobots(j.Host, time.Now()) {
+		return
+	}
+	robots := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}).String()
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, c.Timeout())
+		defer cancel()
+		req, err := newRequest(ctx, robots)
+		if err != nil {
+			return
+		}
+		//through the checker's client, so the egress policy applies
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			//no robots.txt, no delay
+			lim.SetCrawlDelay(j.Host, 0)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+		if err != nil {
+			return
+		}
+		lim.SetCrawlDelay(j.Host, hostlimit.CrawlDelay(body, "linkwatch"))
+	}()
+}
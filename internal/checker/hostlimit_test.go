@@ -0,0 +1,97 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/hostlimit"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+// a host waiting for its rate must not hold up the only worker
+func TestHostLimitDoesNotBlockOtherHosts(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	hits := map[string][]time.Time{}
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits[name] = append(hits[name], time.Now())
+			mu.Unlock()
+		})
+	}
+	slow := httptest.NewServer(handler("slow"))
+	defer slow.Close()
+	fast := httptest.NewServer(handler("fast"))
+	defer fast.Close()
+
+	add := func(raw string) {
+		canon, host, err := core.Canonicalize(raw)
+		require.NoError(t, err)
+		_, _, err = s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), canon, host)
+		require.NoError(t, err)
+	}
+	for _, p := range []string{"/a", "/b", "/c"} {
+		add(slow.URL + p)
+	}
+	add(fast.URL + "/x")
+
+	_, slowHost, _ := core.Canonicalize(slow.URL)
+	limits := hostlimit.Config{Hosts: map[string]hostlimit.Rate{slowHost: {RPS: 4}}}
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()), WithHostLimits(limits))
+	rctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() { c.Start(rctx); close(done) }()
+	defer func() { cancel(); <-done }()
+
+	count := func(name string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(hits[name])
+	}
+	require.Eventually(t, func() bool { return count("fast") == 1 }, 200*time.Millisecond, 5*time.Millisecond,
+		"the other host is checked while the limited one waits")
+	require.Eventually(t, func() bool { return count("slow") == 3 }, 2*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 1; i < len(hits["slow"]); i++ {
+		require.GreaterOrEqual(t, hits["slow"][i].Sub(hits["slow"][i-1]), 200*time.Millisecond, "4 rps")
+	}
+}
+
+func TestHostLimitReadsRobotsCrawlDelay(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nCrawl-delay: 5\n\nUser-agent: linkwatch\nCrawl-delay: 0.25\n"))
+		}
+	}))
+	defer srv.Close()
+	canon, host, err := core.Canonicalize(srv.URL + "/page")
+	require.NoError(t, err)
+	tg, _, err := s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), canon, host)
+	require.NoError(t, err)
+
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()), WithHostLimits(hostlimit.Config{RobotsCrawlDelay: true}))
+	j := job{ID: tg.ID, URL: tg.URL, Host: tg.Host}
+	c.fetchRobots(ctx, j)
+	lim := c.limiter.Load()
+	require.Eventually(t, func() bool { return lim.CrawlDelay(host) == 250*time.Millisecond }, time.Second, 5*time.Millisecond)
+
+	//fetched once per TTL, and the delay spaces the host's checks
+	require.False(t, lim.WantsRobots(host, time.Now()))
+	now := time.Now()
+	require.Zero(t, lim.Reserve(host, now))
+	require.Equal(t, 250*time.Millisecond, lim.Reserve(host, now))
+}
@@ -0,0 +1,112 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http/httptrace"
+	"strings"
+	"time"
+)
+
+// Timing is the latency breakdown of a single request (last hop if redirected)
+type Timing struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration
+	Total   time.Duration
+}
+
+// milliseconds in JSON
+func (t Timing) MarshalJSON() ([]byte, error) {
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	return json.Marshal(map[string]float64{
+		"dns_ms":     ms(t.DNS),
+		"connect_ms": ms(t.Connect),
+		"tls_ms":     ms(t.TLS),
+		"ttfb_ms":    ms(t.TTFB),
+		"total_ms":   ms(t.Total),
+	})
+}
+
+type OnceResult struct {
+	URL        string  `json:"url"`
+	StatusCode *int    `json:"status_code,omitempty"`
+	Error      *string `json:"error,omitempty"`
+	Timing     Timing  `json:"timing"`
+	Details    any     `json:"details,omitempty"`
+}
+
+// CheckOnce runs a single GET (or TCP/DNS probe) the same way the workers do,
+// without retries or a DB; it runs for the operator, so the egress policy does
+// not apply and dns: targets without a resolver use the system one
+func CheckOnce(ctx context.Context, url string, timeout time.Duration) OnceResult {
+	if strings.HasPrefix(url, "tcp://") || strings.HasPrefix(url, "dns:") {
+		return checkProbeOnce(ctx, url, timeout)
+	}
+	res := OnceResult{URL: url}
+	var dnsStart, connStart, tlsStart, wrote time.Time
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { res.Timing.DNS = time.Since(dnsStart) },
+		ConnectStart:      func(_, _ string) { connStart = time.Now() },
+		ConnectDone:       func(_, _ string, _ error) { res.Timing.Connect = time.Since(connStart) },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { res.Timing.TLS = time.Since(tlsStart) },
+		WroteRequest:      func(httptrace.WroteRequestInfo) { wrote = time.Now() },
+		GotFirstResponseByte: func() {
+			res.Timing.TTFB = time.Since(wrote)
+		},
+	}
+
+	t0 := time.Now()
+	req, err := newRequest(httptrace.WithClientTrace(ctx, trace), url)
+	if err == nil {
+		resp, doErr := newHTTPClient(timeout, nil).Do(req)
+		if doErr == nil {
+			code := resp.StatusCode
+			resp.Body.Close()
+			res.StatusCode = &code
+		}
+		err = doErr
+	}
+	res.Timing.Total = time.Since(t0)
+	if err != nil {
+		s := err.Error()
+		res.Error = &s
+	}
+	return res
+}
+
+// tcp: only connect and total are timed, TLS is part of the total;
+// dns: the whole lookup counts as DNS
+func checkProbeOnce(ctx context.Context, url string, timeout time.Duration) OnceResult {
+	res := OnceResult{URL: url}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	dialer := &net.Dialer{}
+	var p Prober = tcpProber{dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		t0 := time.Now()
+		defer func() { res.Timing.Connect = time.Since(t0) }()
+		return dialer.DialContext(ctx, network, addr)
+	}}
+	dns := strings.HasPrefix(url, "dns:")
+	if dns {
+		p = dnsProber{dial: dialer.DialContext}
+	}
+	t0 := time.Now()
+	r := p.Probe(ctx, url)
+	err := r.Err
+	res.Timing.Total = time.Since(t0)
+	res.Details = r.Details
+	if dns {
+		res.Timing.DNS = res.Timing.Total
+	}
+	if err != nil {
+		s := err.Error()
+		res.Error = &s
+	}
+	return res
+}
@@ -0,0 +1,195 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/retry"
+	"github.com/nurzh/linkwatch/internal/transport"
+)
+
+// Result of one probe attempt. HTTP reports a status; a nil Err without one
+// (TCP, DNS) means the target is up
+type Result struct {
+	Status     *int
+	Latency    time.Duration // 0: the checker times the whole Probe call
+	Err        error
+	Details    any           // stored as JSON with the check result, nil = none
+	RetryAfter time.Duration // the target asked to wait this long before retrying
+}
+
+// Prober runs one check attempt against a target URL of its scheme. The
+// checker owns scheduling, per-host locking, retries, timeouts (via ctx) and
+// persistence; a prober only talks to the target
+type Prober interface {
+	Probe(ctx context.Context, target string) Result
+}
+
+// ProberFunc adapts a function to Prober
+type ProberFunc func(ctx context.Context, target string) Result
+
+func (f ProberFunc) Probe(ctx context.Context, target string) Result { return f(ctx, target) }
+
+// WithProber registers p for URL scheme (lower case), replacing a built-in
+// one; targets still have to pass core.Canonicalize to be stored
+func WithProber(scheme string, p Prober) Option {
+	return func(c *Checker) { c.probers[strings.ToLower(scheme)] = p }
+}
+
+// prober for the target's scheme, nil if there is none
+func (c *Checker) prober(target string) Prober {
+	scheme, _, _ := strings.Cut(target, ":")
+	return c.probers[strings.ToLower(scheme)]
+}
+
+// one attempt bounded by timeout, timed unless the prober did
+func (c *Checker) probe(ctx context.Context, p Prober, target string, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	t0 := time.Now()
+	r := p.Probe(ctx, target)
+	if r.Latency <= 0 {
+		r.Latency = time.Since(t0)
+	}
+	return r
+}
+
+// GET, redirects followed by the client
+type httpProber struct {
+	// for the target being probed (transport settings)
+	client func(ctx context.Context) (*http.Client, transport.Settings, error)
+}
+
+func (p httpProber) Probe(ctx context.Context, target string) Result {
+	client, ts, err := p.client(ctx)
+	if err != nil {
+		return Result{Err: fmt.Errorf("transport: %w", err)}
+	}
+	var details any
+	if ts.Insecure() {
+		details = tlsDetails{InsecureSkipVerify: true}
+	}
+	req, err := newRequest(ctx, target)
+	if err != nil {
+		return Result{Err: err, Details: details}
+	}
+	credentialsFrom(ctx).apply(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{Err: err, Details: details}
+	}
+	resp.Body.Close()
+	code := resp.StatusCode
+	return Result{Status: &code, Details: details, RetryAfter: retry.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+}
+
+// at most this much is read looking for "expect"
+const tcpReadMax = 4 << 10
+
+// connects to tcp://host:port, optionally completes a TLS handshake, writes
+// "send" and waits for "expect" (see core.TCPSend etc.)
+type tcpProber struct {
+	dial dialFunc
+	tls  *tls.Config // base config, nil = system roots
+	// transport settings of the target being probed, nil config = base
+	tlsFor func(ctx context.Context) (*tls.Config, error)
+}
+
+func (p tcpProber) Probe(ctx context.Context, target string) Result {
+	insecure, err := p.probe(ctx, target)
+	r := Result{Err: err}
+	if insecure {
+		r.Details = tlsDetails{InsecureSkipVerify: true}
+	}
+	return r
+}
+
+// insecure: the handshake skipped certificate verification
+func (p tcpProber) probe(ctx context.Context, target string) (insecure bool, err error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false, err
+	}
+	q := u.Query()
+	conn, err := p.dial(ctx, "tcp", u.Host)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	//reads and writes end with the attempt
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+
+	if q.Get(core.TCPTLS) == "true" {
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if p.tls != nil {
+			cfg = p.tls.Clone()
+		}
+		if p.tlsFor != nil {
+			own, err := p.tlsFor(ctx)
+			if err != nil {
+				return false, fmt.Errorf("transport: %w", err)
+			}
+			if own != nil {
+				cfg = own.Clone()
+			}
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = u.Hostname()
+		}
+		insecure = cfg.InsecureSkipVerify
+		tc := tls.Client(conn, cfg)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return insecure, fmt.Errorf("tls handshake: %w", err)
+		}
+		conn = tc
+	}
+	if send := q.Get(core.TCPSend); send != "" {
+		if _, err := io.WriteString(conn, send); err != nil {
+			return insecure, fmt.Errorf("send: %w", err)
+		}
+	}
+	if expect := q.Get(core.TCPExpect); expect != "" {
+		return insecure, readExpect(conn, expect)
+	}
+	return insecure, nil
+}
+
+// reads until expect shows up, the peer closes or tcpReadMax bytes came in
+func readExpect(r io.Reader, expect string) error {
+	var got []byte
+	buf := make([]byte, 512)
+	for len(got) < tcpReadMax {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if strings.Contains(string(got), expect) {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("expected %q: %w (got %q)", expect, err, excerpt(got))
+		}
+	}
+	return fmt.Errorf("expected %q, got %q", expect, excerpt(got))
+}
+
+// keeps stored errors short
+func excerpt(b []byte) string {
+	if len(b) > 64 {
+		return string(b[:64]) + "..."
+	}
+	return string(b)
+}
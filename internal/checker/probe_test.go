@@ -0,0 +1,165 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+// tcp server on loopback running handle for every connection
+func tcpServer(t *testing.T, handle func(net.Conn)) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestTCPProbe(t *testing.T) {
+	banner := tcpServer(t, func(c net.Conn) { io.WriteString(c, "220 mail.test ESMTP\r\n") })
+	wrong := tcpServer(t, func(c net.Conn) { io.WriteString(c, "554 go away\r\n") })
+	silent := tcpServer(t, func(c net.Conn) { io.Copy(io.Discard, c) })
+	redis := tcpServer(t, func(c net.Conn) {
+		line, _ := bufio.NewReader(c).ReadString('\n')
+		if line == "PING\r\n" {
+			io.WriteString(c, "+PONG\r\n")
+		}
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closed := ln.Addr().String()
+	ln.Close()
+
+	p := tcpProber{dial: (&net.Dialer{}).DialContext}
+	for _, tc := range []struct {
+		url     string
+		wantErr string
+	}{
+		{"tcp://" + silent, ""},
+		{"tcp://" + banner + "?expect=220", ""},
+		{"tcp://" + redis + "?send=PING%0D%0A&expect=PONG", ""},
+		{"tcp://" + redis + "?send=PING&expect=PONG", "i/o timeout"},
+		{"tcp://" + wrong + "?expect=220", `expected "220", got "554 go away\r\n"`},
+		{"tcp://" + silent + "?expect=220", "i/o timeout"},
+		{"tcp://" + closed, "connection refused"},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		r := p.Probe(ctx, tc.url)
+		cancel()
+		require.Nil(t, r.Status, tc.url)
+		if tc.wantErr == "" {
+			require.NoError(t, r.Err, tc.url)
+		} else {
+			require.ErrorContains(t, r.Err, tc.wantErr, tc.url)
+		}
+	}
+}
+
+func TestTCPProbeTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	target := "tcp://" + srv.Listener.Addr().String() + "?tls=true&send=GET+%2F+HTTP%2F1.0%0D%0A%0D%0A&expect=200+OK"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	//the test certificate is not trusted by default
+	r := tcpProber{dial: (&net.Dialer{}).DialContext}.Probe(ctx, target)
+	require.ErrorContains(t, r.Err, "tls handshake")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	r = tcpProber{dial: (&net.Dialer{}).DialContext, tls: &tls.Config{RootCAs: roots}}.Probe(ctx, target)
+	require.NoError(t, r.Err)
+}
+
+// tcp targets go through doCheck and the egress policy like http ones
+func TestDoCheckTCP(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+	addr := tcpServer(t, func(c net.Conn) { io.WriteString(c, "220 ready\r\n") })
+	add := func(raw string) store.Target {
+		canon, host, err := core.Canonicalize(raw)
+		require.NoError(t, err)
+		tg, _, err := s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), canon, host)
+		require.NoError(t, err)
+		return tg
+	}
+	up, down := add("tcp://"+addr+"?expect=220"), add("tcp://"+addr+"?expect=250")
+
+	c := New(s, 1, 200*time.Millisecond, time.Hour)
+	c.doCheck(ctx, job{ID: up.ID, URL: up.URL, Host: up.Host})
+	r := lastResult(t, s, up.ID)
+	require.NotNil(t, r.Error)
+	require.True(t, strings.HasPrefix(*r.Error, "blocked by egress policy"), *r.Error)
+
+	c.SetEgressPolicy(netguard.AllowAll())
+	c.doCheck(ctx, job{ID: up.ID, URL: up.URL, Host: up.Host})
+	r = lastResult(t, s, up.ID)
+	require.Nil(t, r.Error)
+	require.Nil(t, r.StatusCode, "tcp has no status")
+	require.NotNil(t, r.LatencyMS)
+
+	c.doCheck(ctx, job{ID: down.ID, URL: down.URL, Host: down.Host})
+	r = lastResult(t, s, down.ID)
+	require.NotNil(t, r.Error)
+	require.Contains(t, *r.Error, `expected "250", got "220 ready\r\n"`)
+
+	rows, err := s.Uptime(ctx, "", time.Time{})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, 1, rows[0].Up, "a tcp result without error counts as up")
+	require.Equal(t, 0, rows[1].Up)
+}
+
+// a registered prober shares retries, latency and persistence with the built-in ones
+func TestWithProber(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+	tg, _, err := s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), "https://a.test/", "a.test")
+	require.NoError(t, err)
+
+	var calls []string
+	fake := ProberFunc(func(ctx context.Context, target string) Result {
+		calls = append(calls, target)
+		_, ok := ctx.Deadline()
+		require.True(t, ok, "attempts are bounded by the timeout")
+		code := 503
+		if len(calls) == 2 {
+			code = 200
+		}
+		return Result{Status: &code, Latency: 42 * time.Millisecond, Details: map[string]int{"attempt": len(calls)}}
+	})
+	c := New(s, 1, time.Second, time.Hour, WithProber("HTTPS", fake))
+	c.doCheck(ctx, job{ID: tg.ID, URL: tg.URL, Host: tg.Host})
+
+	require.Equal(t, []string{"https://a.test/", "https://a.test/"}, calls, "5xx is retried")
+	r := lastResult(t, s, tg.ID)
+	require.Equal(t, 200, *r.StatusCode)
+	require.Equal(t, 42, *r.LatencyMS, "the prober's own latency wins")
+	require.JSONEq(t, `{"attempt":2}`, string(r.Details))
+	require.Nil(t, c.prober("ftp://a.test/"))
+}
@@ -0,0 +1,110 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/retry"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func testSQLite(t *testing.T) *store.SQLite {
+	ctx := context.Background()
+	s, err := store.OpenSQLite(ctx, ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	m, err := store.Migrator(s)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	return s
+}
+
+func TestSetIntervalAndConcurrencyWhileRunning(t *testing.T) {
+	s := testSQLite(t)
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer srv.Close()
+
+	canon, host, err := core.Canonicalize(srv.URL)
+	require.NoError(t, err)
+	_, _, err = s.CreateOrGetTarget(context.Background(), store.DefaultProjectID, core.NewID("t"), canon, host)
+	require.NoError(t, err)
+
+	c := New(s, 2, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { c.Start(ctx); close(done) }()
+
+	//first pass runs immediately, the next one would be in an hour
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&hits) == 1 }, time.Second, 10*time.Millisecond)
+
+	c.SetInterval(50 * time.Millisecond)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&hits) >= 3 }, 2*time.Second, 10*time.Millisecond)
+
+	c.SetConcurrency(5)
+	c.SetConcurrency(1)
+	c.mu.Lock()
+	require.Equal(t, 1, c.running)
+	c.mu.Unlock()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("checker did not stop")
+	}
+	require.Equal(t, "stopped", c.State())
+}
+
+func TestSetTimeout(t *testing.T) {
+	s := testSQLite(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+	j := transportTarget(t, s, srv.URL, nil)
+
+	c := New(s, 1, 50*time.Millisecond, time.Hour, WithEgressPolicy(netguard.AllowAll()), WithRetryPolicy(retry.Policy{MaxAttempts: 1}))
+	c.doCheck(context.Background(), j)
+	require.NotNil(t, lastResult(t, s, j.ID).Error)
+
+	c.SetTimeout(0) // ignored
+	require.Equal(t, 50*time.Millisecond, c.Timeout())
+	c.SetTimeout(2 * time.Second)
+	c.doCheck(context.Background(), j)
+	r := lastResult(t, s, j.ID)
+	require.Nil(t, r.Error)
+	require.Equal(t, 200, *r.StatusCode)
+}
+
+func TestDueHonorsProjectMinInterval(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+	sixty := 60
+	require.NoError(t, s.CreateProject(ctx, store.Project{ID: "p_slow", Name: "slow", MinIntervalSeconds: &sixty, CreatedAt: time.Now()}))
+
+	c := New(s, 1, time.Second, 10*time.Second)
+	floors := c.projectFloors(ctx)
+	require.Equal(t, time.Minute, floors["p_slow"])
+	require.NotContains(t, floors, store.DefaultProjectID)
+
+	slow := store.Target{ID: "t_slow", ProjectID: "p_slow", Settings: store.TargetSettings{Interval: "10s"}}
+	fast := store.Target{ID: "t_fast", ProjectID: store.DefaultProjectID, Settings: store.TargetSettings{Interval: "10s"}}
+	now := time.Now()
+	require.True(t, c.due(slow, floors[slow.ProjectID], now))
+	require.True(t, c.due(fast, floors[fast.ProjectID], now))
+	later := now.Add(20 * time.Second)
+	require.False(t, c.due(slow, floors[slow.ProjectID], later), "project minimum wins over the target's 10s")
+	require.True(t, c.due(fast, floors[fast.ProjectID], later))
+}
@@ -11,6 +11,8 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/retry"
 	"github.com/nurzh/linkwatch/internal/store"
 	"github.com/stretchr/testify/require"
 )
@@ -54,7 +56,7 @@ func TestRetryBackoffEventually200(t *testing.T) {
 	`, id, canon, host)
 	require.NoError(t, err)
 
-	c := New(pg, 1, 2*time.Second, 1*time.Hour)
+	c := New(pg, 1, 2*time.Second, 1*time.Hour, WithEgressPolicy(netguard.AllowAll()))
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 	c.Start(ctx)
@@ -75,3 +77,82 @@ func TestRetryBackoffEventually200(t *testing.T) {
 	require.NotNil(t, code)
 	require.Equal(t, 200, *code)
 }
+
+// a failed attempt followed by a good one must not leave the old error behind
+func TestRetryRecordsEveryAttempt(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			//no response at all
+			hj, _ := w.(http.Hijacker)
+			conn, _, _ := hj.Hijack()
+			conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	tg, _, err := s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), srv.URL+"/", "a.test")
+	require.NoError(t, err)
+
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()),
+		WithRetryPolicy(retry.Policy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Errors: retry.ErrorClasses}))
+	c.doCheck(ctx, job{ID: tg.ID, URL: tg.URL, Host: tg.Host})
+
+	r := lastResult(t, s, tg.ID)
+	require.Nil(t, r.Error, "the last attempt succeeded")
+	require.Equal(t, 200, *r.StatusCode)
+	require.Len(t, r.Attempts, 2)
+	require.Equal(t, 1, r.Attempts[0].Attempt)
+	require.NotNil(t, r.Attempts[0].Error)
+	require.Nil(t, r.Attempts[0].StatusCode)
+	require.Equal(t, 2, r.Attempts[1].Attempt)
+	require.Equal(t, 200, *r.Attempts[1].StatusCode)
+}
+
+func TestRetryPolicyPerTargetAndRetryAfter(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	tg, _, err := s.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), srv.URL+"/", "a.test")
+	require.NoError(t, err)
+
+	global := retry.Default()
+	global.MaxBackoff = 20 * time.Millisecond
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()), WithRetryPolicy(global))
+
+	//Retry-After is honoured up to max_backoff
+	t0 := time.Now()
+	c.doCheck(ctx, job{ID: tg.ID, URL: tg.URL, Host: tg.Host})
+	require.Equal(t, int32(3), hits.Load())
+	require.Less(t, time.Since(t0), time.Second)
+	require.Len(t, lastResult(t, s, tg.ID).Attempts, 3)
+
+	//the target's settings win over the global policy
+	hits.Store(0)
+	c.doCheck(ctx, job{ID: tg.ID, URL: tg.URL, Host: tg.Host, Retry: &retry.Overrides{Statuses: []string{"503"}}})
+	require.Equal(t, int32(1), hits.Load(), "429 is not retried for this target")
+
+	//a cancelled check stops sleeping and still records what it has
+	hits.Store(0)
+	slow := retry.Default()
+	slow.BaseBackoff, slow.MaxBackoff, slow.RetryAfter = time.Hour, time.Hour, false
+	c.SetRetryPolicy(slow)
+	cctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	t0 = time.Now()
+	c.doCheck(cctx, job{ID: tg.ID, URL: tg.URL, Host: tg.Host})
+	require.Less(t, time.Since(t0), 5*time.Second)
+	require.Equal(t, int32(1), hits.Load())
+	r := lastResult(t, s, tg.ID)
+	require.Equal(t, 429, *r.StatusCode)
+	require.Len(t, r.Attempts, 1)
+}
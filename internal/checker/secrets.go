@@ -0,0 +1,142 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/nurzh/linkwatch/internal/secrets"
+	"github.com/nurzh/linkwatch/internal/store"
+)
+
+// WithSecrets sets the key that opens the secrets targets reference; without
+// it checks of such targets fail
+func WithSecrets(b *secrets.Box) Option {
+	return func(c *Checker) { c.secrets = b }
+}
+
+// what a target sends besides the request itself, with secrets resolved;
+// lives only for one check
+type credentials struct {
+	headers    http.Header
+	basic      bool
+	user, pass string
+	cert, key  []byte   // PEM of transport.cert_secret / key_secret
+	values     []string // resolved secrets, redacted from stored errors
+}
+
+type credentialsKey struct{}
+
+func withCredentials(ctx context.Context, cr *credentials) context.Context {
+	if cr == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, credentialsKey{}, cr)
+}
+
+// credentials of the check running with ctx, nil if the target has none
+func credentialsFrom(ctx context.Context) *credentials {
+	cr, _ := ctx.Value(credentialsKey{}).(*credentials)
+	return cr
+}
+
+// adds headers and basic auth to a request of the check
+func (cr *credentials) apply(req *http.Request) {
+	if cr == nil {
+		return
+	}
+	for k, vs := range cr.headers {
+		req.Header[k] = vs
+	}
+	if cr.basic {
+		req.SetBasicAuth(cr.user, cr.pass)
+	}
+}
+
+// removes what apply added, for redirects to another host
+func (cr *credentials) strip(req *http.Request) {
+	if cr == nil {
+		return
+	}
+	for k := range cr.headers {
+		req.Header.Del(k)
+	}
+	if cr.basic {
+		req.Header.Del("Authorization")
+	}
+}
+
+// redacts resolved secrets from msg
+func (cr *credentials) redact(msg string) string {
+	if cr == nil {
+		return msg
+	}
+	return secrets.Redact(msg, cr.values)
+}
+
+// resolves the target's headers, basic auth and certificate secrets right
+// before its check; nil when it has none
+func (c *Checker) credentials(ctx context.Context, j job) (*credentials, error) {
+	ts := c.Transport().With(j.Transport)
+	if len(j.Headers) == 0 && j.BasicAuth == nil && ts.CertSecret == "" {
+		return nil, nil
+	}
+	cr := &credentials{}
+	opened := map[string]string{}
+	lookup := func(name string) (string, error) {
+		if v, ok := opened[name]; ok {
+			return v, nil
+		}
+		if c.secrets == nil {
+			return "", secrets.ErrNoKey
+		}
+		sec, err := c.db.GetSecret(ctx, j.ProjectID, name)
+		if errors.Is(err, store.ErrNotFound) {
+			return "", fmt.Errorf("secret %q not found", name)
+		}
+		if err != nil {
+			return "", fmt.Errorf("secret %q: %w", name, err)
+		}
+		v, err := c.secrets.Open(sec.ProjectID, sec.Name, sec.Value)
+		if err != nil {
+			return "", fmt.Errorf("secret %q: %w", name, err)
+		}
+		opened[name] = string(v)
+		cr.values = append(cr.values, string(v))
+		return string(v), nil
+	}
+
+	if len(j.Headers) > 0 {
+		cr.headers = http.Header{}
+		for k, v := range j.Headers {
+			v, err := secrets.Expand(v, lookup)
+			if err != nil {
+				return nil, fmt.Errorf("headers.%s: %w", k, err)
+			}
+			cr.headers.Set(k, v)
+		}
+	}
+	if b := j.BasicAuth; b != nil {
+		var err error
+		if cr.user, err = secrets.Expand(b.Username, lookup); err != nil {
+			return nil, fmt.Errorf("basic_auth: %w", err)
+		}
+		if cr.pass, err = secrets.Expand(b.Password, lookup); err != nil {
+			return nil, fmt.Errorf("basic_auth: %w", err)
+		}
+		cr.basic = true
+	}
+	if ts.CertSecret != "" {
+		cert, err := lookup(ts.CertSecret)
+		if err != nil {
+			return nil, fmt.Errorf("transport.cert_secret: %w", err)
+		}
+		key, err := lookup(ts.KeySecret)
+		if err != nil {
+			return nil, fmt.Errorf("transport.key_secret: %w", err)
+		}
+		cr.cert, cr.key = []byte(cert), []byte(key)
+	}
+	return cr, nil
+}
@@ -0,0 +1,155 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/headers"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/retry"
+	"github.com/nurzh/linkwatch/internal/secrets"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/internal/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func putSecret(t *testing.T, s store.Store, box *secrets.Box, name, value string) {
+	require.NoError(t, s.PutSecret(context.Background(), store.Secret{ProjectID: store.DefaultProjectID, Name: name,
+		Value: box.Seal(store.DefaultProjectID, name, []byte(value)), UpdatedAt: time.Now()}))
+}
+
+func TestSecretsInHeadersAndBasicAuth(t *testing.T) {
+	s := testSQLite(t)
+	box, err := secrets.ParseKey(secrets.NewKey())
+	require.NoError(t, err)
+	putSecret(t, s, box, "token", "tok-123")
+	putSecret(t, s, box, "password", "hunter2")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		switch {
+		case r.URL.Path == "/leak":
+			//the secret ends up in the error of the next hop
+			http.Redirect(w, r, "http://127.0.0.1:1/"+r.Header.Get("X-Token"), http.StatusFound)
+		case r.Header.Get("X-Token") == "tok-123" && r.Header.Get("X-Env") == "prod" && user == "svc" && pass == "hunter2":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()), WithRetryPolicy(retry.Policy{MaxAttempts: 1}), WithSecrets(box))
+	ctx := context.Background()
+	j := transportTarget(t, s, srv.URL+"/ok", nil)
+	j.Headers = map[string]string{"X-Token": "${secret:token}", "X-Env": "prod"}
+	j.BasicAuth = &headers.BasicAuth{Username: "svc", Password: "${secret:password}"}
+	c.doCheck(ctx, j)
+	require.Equal(t, 204, *lastResult(t, s, j.ID).StatusCode)
+
+	leak := transportTarget(t, s, srv.URL+"/leak", nil)
+	leak.Headers = j.Headers
+	c.doCheck(ctx, leak)
+	msg := *lastResult(t, s, leak.ID).Error
+	require.Contains(t, msg, secrets.Redacted)
+	require.NotContains(t, msg, "tok-123")
+
+	//resolution errors fail the check before any request
+	j.Headers = map[string]string{"X-Token": "${secret:gone}"}
+	c.doCheck(ctx, j)
+	r := lastResult(t, s, j.ID)
+	require.Equal(t, `secrets: headers.X-Token: secret "gone" not found`, *r.Error)
+	require.Empty(t, r.Attempts)
+
+	c = New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()))
+	c.doCheck(ctx, leak)
+	require.Contains(t, *lastResult(t, s, leak.ID).Error, "no secrets key configured")
+}
+
+func TestSecretsNotSentToOtherHosts(t *testing.T) {
+	s := testSQLite(t)
+	box, err := secrets.ParseKey(secrets.NewKey())
+	require.NoError(t, err)
+	putSecret(t, s, box, "k", "s3cret")
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); ok || r.Header.Get("X-Api-Key") != "" {
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer other.Close()
+	var sameHost string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/away":
+			http.Redirect(w, r, other.URL, http.StatusFound)
+		case "/here":
+			http.Redirect(w, r, "/end", http.StatusFound)
+		case "/end":
+			sameHost = r.Header.Get("X-Api-Key")
+		}
+	}))
+	defer srv.Close()
+
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()), WithRetryPolicy(retry.Policy{MaxAttempts: 1}), WithSecrets(box))
+	ctx := context.Background()
+	j := transportTarget(t, s, srv.URL+"/away", nil)
+	j.Headers = map[string]string{"X-Api-Key": "${secret:k}"}
+	j.BasicAuth = &headers.BasicAuth{Username: "svc", Password: "${secret:k}"}
+	c.doCheck(ctx, j)
+	require.Equal(t, 204, *lastResult(t, s, j.ID).StatusCode)
+
+	//the target's own host keeps them
+	same := transportTarget(t, s, srv.URL+"/here", nil)
+	same.Headers = j.Headers
+	c.doCheck(ctx, same)
+	require.Equal(t, "s3cret", sameHost)
+
+	//nor to its own host over http once the target is https
+	cr, err := c.credentials(ctx, j)
+	require.NoError(t, err)
+	first := httptest.NewRequest(http.MethodGet, "https://api.test/", nil)
+	for _, next := range []string{"http://api.test/login", "https://api.test/next"} {
+		req := httptest.NewRequestWithContext(withCredentials(ctx, cr), http.MethodGet, next, nil)
+		cr.apply(req)
+		require.NoError(t, c.client.CheckRedirect(req, []*http.Request{first}))
+		if strings.HasPrefix(next, "http:") {
+			require.Empty(t, req.Header.Get("X-Api-Key"), next)
+			require.Empty(t, req.Header.Get("Authorization"), next)
+		} else {
+			require.Equal(t, "s3cret", req.Header.Get("X-Api-Key"), next)
+		}
+	}
+}
+
+func TestSecretsClientCertificate(t *testing.T) {
+	s := testSQLite(t)
+	box, err := secrets.ParseKey(secrets.NewKey())
+	require.NoError(t, err)
+	certFile, keyFile, pool := clientCert(t)
+	for name, f := range map[string]string{"client.pem": certFile, "client.key": keyFile} {
+		b, err := os.ReadFile(f)
+		require.NoError(t, err)
+		putSecret(t, s, box, name, string(b))
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()), WithRetryPolicy(retry.Policy{MaxAttempts: 1}),
+		WithTransport(transport.Settings{CAFile: caFile(t, srv)}), WithSecrets(box))
+	j := transportTarget(t, s, srv.URL, &transport.Settings{CertSecret: "client.pem", KeySecret: "client.key"})
+	c.doCheck(context.Background(), j)
+	r := lastResult(t, s, j.ID)
+	require.Nil(t, r.Error)
+	require.Equal(t, 200, *r.StatusCode)
+}
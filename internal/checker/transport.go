@@ -0,0 +1,152 @@
+package checker
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/nurzh/linkwatch/internal/transport"
+)
+
+// clients for non-default transport settings, built on first use; replaced
+// as a whole when the global settings change, so files are read again
+type transports struct {
+	global transport.Settings
+
+	mu      sync.Mutex
+	clients map[string]*transportEntry // by effective settings (JSON)
+}
+
+type transportEntry struct {
+	client *http.Client
+	tls    *tls.Config
+}
+
+// stored with the results of checks that skipped certificate verification
+type tlsDetails struct {
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+}
+
+// WithTransport sets the global proxy/TLS settings; targets can override them
+func WithTransport(s transport.Settings) Option {
+	return func(c *Checker) { c.SetTransport(s) }
+}
+
+func (c *Checker) Transport() transport.Settings { return c.transports.Load().global }
+
+// SetTransport changes the global transport settings, also while running
+func (c *Checker) SetTransport(s transport.Settings) {
+	old := c.transports.Swap(&transports{global: s, clients: map[string]*transportEntry{}})
+	if old == nil {
+		return
+	}
+	old.mu.Lock()
+	defer old.mu.Unlock()
+	for _, e := range old.clients {
+		e.client.CloseIdleConnections()
+	}
+}
+
+type transportKey struct{}
+
+// the target's own settings (may be nil) for the probes run with ctx
+func withTargetTransport(ctx context.Context, o *transport.Settings) context.Context {
+	if o == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, transportKey{}, o)
+}
+
+// transport settings of the probe running with ctx: global, then the target's
+func (c *Checker) transportFor(ctx context.Context) (*transports, transport.Settings) {
+	ts := c.transports.Load()
+	o, _ := ctx.Value(transportKey{}).(*transport.Settings)
+	return ts, ts.global.With(o)
+}
+
+// httpClient for the probe running with ctx; the shared client unless
+// settings apply
+func (c *Checker) httpClient(ctx context.Context) (*http.Client, transport.Settings, error) {
+	ts, s := c.transportFor(ctx)
+	if s.IsZero() {
+		return c.client, s, nil
+	}
+	e, err := ts.entry(s, credentialsFrom(ctx), c.dial, c.checkProxied)
+	if err != nil {
+		return nil, s, err
+	}
+	return e.client, s, nil
+}
+
+// tlsConfig for the probe running with ctx, nil without settings
+func (c *Checker) tlsConfig(ctx context.Context) (*tls.Config, error) {
+	ts, s := c.transportFor(ctx)
+	if s.IsZero() {
+		return nil, nil
+	}
+	e, err := ts.entry(s, credentialsFrom(ctx), c.dial, c.checkProxied)
+	if err != nil {
+		return nil, err
+	}
+	return e.tls, nil
+}
+
+// the proxy connects to the target, so its dial check never sees it
+func (c *Checker) checkProxied(ctx context.Context, host string) error {
+	return c.egress.Load().CheckResolved(ctx, net.DefaultResolver, host)
+}
+
+// cr supplies the certificate of s.CertSecret (may be nil otherwise);
+// proxied checks the host of every request, redirects included
+func (ts *transports) entry(s transport.Settings, cr *credentials, dial dialFunc, proxied func(context.Context, string) error) (*transportEntry, error) {
+	b, _ := json.Marshal(s)
+	key := string(b)
+	if s.CertSecret != "" && cr != nil {
+		//a rotated secret gets a new client
+		sum := sha256.Sum256(append(append([]byte{}, cr.cert...), cr.key...))
+		key += hex.EncodeToString(sum[:])
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if e, ok := ts.clients[key]; ok {
+		return e, nil
+	}
+	cfg, err := s.TLSConfig()
+	if err != nil {
+		//not cached, a fixed file is picked up by the next attempt
+		return nil, err
+	}
+	if s.CertSecret != "" {
+		if cr == nil {
+			return nil, errors.New("cert_secret: not resolved")
+		}
+		cert, err := tls.X509KeyPair(cr.cert, cr.key)
+		if err != nil {
+			return nil, fmt.Errorf("cert_secret: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	client := newHTTPClient(0, dial)
+	tr := client.Transport.(*http.Transport)
+	tr.TLSClientConfig = cfg
+	//a configured proxy is dialed through the egress policy like any target
+	if proxy := s.ProxyFunc(); proxy != nil {
+		tr.Proxy = func(req *http.Request) (*url.URL, error) {
+			if err := proxied(req.Context(), req.URL.Host); err != nil {
+				return nil, err
+			}
+			return proxy(req)
+		}
+	}
+	e := &transportEntry{client: client, tls: cfg}
+	ts.clients[key] = e
+	return e, nil
+}
@@ -0,0 +1,191 @@
+package checker
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/retry"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/internal/transport"
+	"github.com/stretchr/testify/require"
+)
+
+// self-signed client certificate as PEM files, and its pool for ClientCAs
+func clientCert(t *testing.T) (certFile, keyFile string, pool *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "linkwatch"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	kder, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kder}), 0o600))
+	return certFile, keyFile, pool
+}
+
+// the test server's certificate as a CA file
+func caFile(t *testing.T, srv *httptest.Server) string {
+	p := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(p, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600))
+	return p
+}
+
+func transportTarget(t *testing.T, s *store.SQLite, raw string, ts *transport.Settings) job {
+	canon, host, err := core.Canonicalize(raw)
+	require.NoError(t, err)
+	tg, _, err := s.CreateOrGetTarget(context.Background(), store.DefaultProjectID, core.NewID("t"), canon, host)
+	require.NoError(t, err)
+	return job{ID: tg.ID, URL: tg.URL, Host: tg.Host, Transport: ts}
+}
+
+func TestTransportCAAndClientCertificate(t *testing.T) {
+	s := testSQLite(t)
+	certFile, keyFile, pool := clientCert(t)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()), WithRetryPolicy(retry.Policy{MaxAttempts: 1}),
+		WithTransport(transport.Settings{CAFile: caFile(t, srv)}))
+	ctx := context.Background()
+
+	//trusted, but no client certificate
+	plain := transportTarget(t, s, srv.URL+"/plain", nil)
+	c.doCheck(ctx, plain)
+	r := lastResult(t, s, plain.ID)
+	require.NotNil(t, r.Error)
+	require.Nil(t, r.Details)
+
+	mtls := transportTarget(t, s, srv.URL+"/mtls", &transport.Settings{CertFile: certFile, KeyFile: keyFile})
+	c.doCheck(ctx, mtls)
+	r = lastResult(t, s, mtls.ID)
+	require.Nil(t, r.Error)
+	require.Equal(t, 200, *r.StatusCode)
+
+	//the target's SNI is verified instead of the URL's host
+	sni := transportTarget(t, s, srv.URL+"/sni", &transport.Settings{CertFile: certFile, KeyFile: keyFile, ServerName: "wrong.test"})
+	c.doCheck(ctx, sni)
+	require.Contains(t, *lastResult(t, s, sni.ID).Error, "wrong.test")
+	sni.Transport.ServerName = "example.com" //in httptest's certificate
+	c.doCheck(ctx, sni)
+	require.Nil(t, lastResult(t, s, sni.ID).Error)
+
+	//settings can be reloaded; a missing file fails the check, not the checker
+	c.SetTransport(transport.Settings{CAFile: filepath.Join(t.TempDir(), "gone.pem")})
+	c.doCheck(ctx, mtls)
+	require.Contains(t, *lastResult(t, s, mtls.ID).Error, "transport: ca_file")
+}
+
+func TestTransportInsecureAndMinVersion(t *testing.T) {
+	s := testSQLite(t)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()), WithRetryPolicy(retry.Policy{MaxAttempts: 1}))
+	ctx := context.Background()
+
+	strict := transportTarget(t, s, srv.URL+"/strict", nil)
+	c.doCheck(ctx, strict)
+	require.NotNil(t, lastResult(t, s, strict.ID).Error, "unknown authority")
+
+	yes := true
+	insecure := transportTarget(t, s, srv.URL+"/insecure", &transport.Settings{InsecureSkipVerify: &yes})
+	c.doCheck(ctx, insecure)
+	r := lastResult(t, s, insecure.ID)
+	require.Equal(t, 200, *r.StatusCode)
+	require.JSONEq(t, `{"insecure_skip_verify":true}`, string(r.Details), "recorded on the result")
+
+	tls13 := transportTarget(t, s, srv.URL+"/tls13", &transport.Settings{InsecureSkipVerify: &yes, TLSMinVersion: "1.3"})
+	c.doCheck(ctx, tls13)
+	require.Contains(t, *lastResult(t, s, tls13.ID).Error, "version")
+
+	//tcp targets with tls=true use the same settings
+	_, addr, _ := core.Canonicalize(srv.URL)
+	tcp := transportTarget(t, s, "tcp://"+addr+"?tls=true", &transport.Settings{InsecureSkipVerify: &yes})
+	c.doCheck(ctx, tcp)
+	r = lastResult(t, s, tcp.ID)
+	require.Nil(t, r.Error)
+	require.JSONEq(t, `{"insecure_skip_verify":true}`, string(r.Details))
+}
+
+func TestTransportProxy(t *testing.T) {
+	s := testSQLite(t)
+	asked := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asked <- r.URL.String() //absolute in proxy requests
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(netguard.AllowAll()), WithRetryPolicy(retry.Policy{MaxAttempts: 1}),
+		WithTransport(transport.Settings{Proxy: proxy.URL}))
+	j := transportTarget(t, s, "http://internal.invalid/health", nil)
+	c.doCheck(context.Background(), j)
+	require.Equal(t, 204, *lastResult(t, s, j.ID).StatusCode)
+	require.Equal(t, "http://internal.invalid/health", <-asked)
+
+	//the proxy is dialed through the egress policy
+	c = New(s, 1, time.Second, time.Hour, WithRetryPolicy(retry.Policy{MaxAttempts: 1}), WithTransport(transport.Settings{Proxy: proxy.URL}))
+	c.doCheck(context.Background(), j)
+	require.Contains(t, *lastResult(t, s, j.ID).Error, "blocked by egress policy")
+}
+
+func TestTransportProxyEgress(t *testing.T) {
+	s := testSQLite(t)
+	var asked []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asked = append(asked, r.URL.String())
+		if r.URL.Path == "/hop" {
+			http.Redirect(w, r, "http://10.0.0.1/", http.StatusFound)
+		}
+	}))
+	defer proxy.Close()
+	//the proxy itself may be reached, the targets it would fetch may not
+	egress, err := netguard.New(netguard.Rules{AllowCIDRs: []string{"127.0.0.1/32"}})
+	require.NoError(t, err)
+	c := New(s, 1, time.Second, time.Hour, WithEgressPolicy(egress), WithRetryPolicy(retry.Policy{MaxAttempts: 1}),
+		WithTransport(transport.Settings{Proxy: proxy.URL}))
+
+	j := transportTarget(t, s, "http://169.254.169.254/latest/meta-data/", nil)
+	c.doCheck(context.Background(), j)
+	require.Contains(t, *lastResult(t, s, j.ID).Error, "blocked by egress policy: 169.254.169.254 is cloud metadata")
+	require.Empty(t, asked)
+
+	hop := transportTarget(t, s, "http://8.8.8.8/hop", nil)
+	c.doCheck(context.Background(), hop)
+	require.Contains(t, *lastResult(t, s, hop.ID).Error, "blocked by egress policy: 10.0.0.1 is private")
+	require.Equal(t, []string{"http://8.8.8.8/hop"}, asked)
+}
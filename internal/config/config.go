@@ -0,0 +1,379 @@
+// Package config loads linkwatch settings from defaults, an optional YAML/JSON
+// file, environment variables and command-line flags (in that order).
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/breaker"
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/headers"
+	"github.com/nurzh/linkwatch/internal/hostlimit"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/retry"
+	"github.com/nurzh/linkwatch/internal/secrets"
+	"github.com/nurzh/linkwatch/internal/transport"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	DatabaseURL    string   `json:"database_url" yaml:"database_url"`
+	ListenAddr     string   `json:"listen_addr" yaml:"listen_addr"`
+	GRPCListenAddr string   `json:"grpc_listen_addr" yaml:"grpc_listen_addr"` // "" leaves the gRPC API off
+	CheckInterval  Duration `json:"check_interval" yaml:"check_interval"`
+	HTTPTimeout    Duration `json:"http_timeout" yaml:"http_timeout"`
+	MaxConcurrency int      `json:"max_concurrency" yaml:"max_concurrency"`
+	ShutdownGrace  Duration `json:"shutdown_grace" yaml:"shutdown_grace"`
+	// every /v1 request is allowed without an API key; for local development only
+	AuthDisabled bool `json:"auth_disabled" yaml:"auth_disabled"`
+	// declarative targets (inline and/or a separate file), reconciled on startup and change
+	Targets             []TargetSpec `json:"targets" yaml:"targets"`
+	TargetsFile         string       `json:"targets_file" yaml:"targets_file"`
+	TargetsSyncInterval Duration     `json:"targets_sync_interval" yaml:"targets_sync_interval"`
+	// which addresses checks may connect to; internal ranges are blocked by default
+	Egress Egress `json:"egress" yaml:"egress"`
+	// resolver (host[:port]) for dns: targets that do not name one, "" = system
+	DNSResolver string `json:"dns_resolver" yaml:"dns_resolver"`
+	// over retry.Default; targets can override it again
+	Retry retry.Overrides `json:"retry" yaml:"retry"`
+	// per-host rates and robots.txt Crawl-delay; unlimited by default
+	HostLimits hostlimit.Config `json:"host_limits" yaml:"host_limits"`
+	// skips checks of hosts that keep failing, apart from one probe per cooldown
+	Breaker breaker.Config `json:"breaker" yaml:"breaker"`
+	// proxy, CAs and client certificate of checks; targets can override each field
+	Transport transport.Settings `json:"transport" yaml:"transport"`
+	// key (base64 or hex, 32 bytes) encrypting stored secrets; SECRETS_KEY
+	// holds it directly and is deliberately not readable from the file
+	SecretsKeyFile string `json:"secrets_key_file" yaml:"secrets_key_file"`
+	SecretsKey     string `json:"-" yaml:"-"`
+}
+
+// SecretsBox opens the configured secrets key, nil without one
+func (c Config) SecretsBox() (*secrets.Box, error) {
+	switch {
+	case c.SecretsKey != "":
+		return secrets.ParseKey(c.SecretsKey)
+	case c.SecretsKeyFile != "":
+		return secrets.LoadKeyFile(c.SecretsKeyFile)
+	}
+	return nil, nil
+}
+
+// RetryPolicy is the global policy the checker applies
+func (c Config) RetryPolicy() retry.Policy {
+	return retry.Default().With(&c.Retry)
+}
+
+type Egress struct {
+	AllowPrivate bool     `json:"allow_private" yaml:"allow_private"`
+	AllowCIDRs   []string `json:"allow_cidrs" yaml:"allow_cidrs"`
+	DenyCIDRs    []string `json:"deny_cidrs" yaml:"deny_cidrs"`
+	AllowHosts   []string `json:"allow_hosts" yaml:"allow_hosts"`
+	DenyHosts    []string `json:"deny_hosts" yaml:"deny_hosts"`
+}
+
+func (e Egress) Policy() (*netguard.Policy, error) {
+	return netguard.New(netguard.Rules{
+		AllowPrivate: e.AllowPrivate,
+		AllowCIDRs:   e.AllowCIDRs,
+		DenyCIDRs:    e.DenyCIDRs,
+		AllowHosts:   e.AllowHosts,
+		DenyHosts:    e.DenyHosts,
+	})
+}
+
+type TargetSpec struct {
+	URL       string              `json:"url" yaml:"url"`
+	Project   string              `json:"project,omitempty" yaml:"project,omitempty"` // project name, "" = default
+	Labels    map[string]string   `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Interval  Duration            `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Timeout   Duration            `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Retry     *retry.Overrides    `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Transport *transport.Settings `json:"transport,omitempty" yaml:"transport,omitempty"`
+	// sent with HTTP checks; values may reference secrets as ${secret:name}
+	Headers   map[string]string  `json:"headers,omitempty" yaml:"headers,omitempty"`
+	BasicAuth *headers.BasicAuth `json:"basic_auth,omitempty" yaml:"basic_auth,omitempty"`
+}
+
+// shape of a targets file: the same "targets:" list as in the main config
+type targetsFile struct {
+	Targets []TargetSpec `json:"targets" yaml:"targets"`
+}
+
+// LoadTargets reads a YAML or JSON targets file
+func LoadTargets(path string) ([]TargetSpec, error) {
+	var f targetsFile
+	if err := decodeFile(path, &f); err != nil {
+		return nil, err
+	}
+	if err := validateTargets(f.Targets); err != nil {
+		return nil, fmt.Errorf("%s:\n%w", path, err)
+	}
+	return f.Targets, nil
+}
+
+// declared at all: an empty declaration must not archive everything
+func (c Config) HasDeclaredTargets() bool {
+	return len(c.Targets) > 0 || c.TargetsFile != ""
+}
+
+func Defaults() Config {
+	return Config{
+		ListenAddr:     ":8080",
+		CheckInterval:  Duration(15 * time.Second),
+		HTTPTimeout:    Duration(5 * time.Second),
+		MaxConcurrency: 8,
+		ShutdownGrace:  Duration(10 * time.Second),
+
+		TargetsSyncInterval: Duration(30 * time.Second),
+		Breaker:             breaker.Default(),
+	}
+}
+
+// Load = defaults + file (if path != "") + env + overrides, then Validate
+func Load(path string, getenv func(string) string, overrides func(*Config)) (Config, error) {
+	c := Defaults()
+	if path != "" {
+		if err := c.loadFile(path); err != nil {
+			return c, err
+		}
+	}
+	if getenv != nil {
+		if err := c.applyEnv(getenv); err != nil {
+			return c, err
+		}
+	}
+	if overrides != nil {
+		overrides(&c)
+	}
+	return c, c.Validate()
+}
+
+func (c *Config) loadFile(path string) error {
+	return decodeFile(path, c)
+}
+
+// .yaml/.yml or .json, unknown keys are errors
+func decodeFile(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(v); err != nil {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config %s: unsupported extension (want .yaml, .yml or .json)", path)
+	}
+	return nil
+}
+
+func (c *Config) applyEnv(getenv func(string) string) error {
+	var errs []error
+	str := func(k string, dst *string) {
+		if v := getenv(k); v != "" {
+			*dst = v
+		}
+	}
+	dur := func(k string, dst *Duration) {
+		if v := getenv(k); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid duration %q", k, v))
+				return
+			}
+			*dst = Duration(d)
+		}
+	}
+	boolean := func(k string, dst *bool) {
+		if v := getenv(k); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid boolean %q", k, v))
+				return
+			}
+			*dst = b
+		}
+	}
+	list := func(k string, dst *[]string) {
+		if v := getenv(k); v != "" {
+			*dst = nil
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*dst = append(*dst, item)
+				}
+			}
+		}
+	}
+	num := func(k string, dst *int) {
+		if v := getenv(k); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid integer %q", k, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	decimal := func(k string, dst *float64) {
+		if v := getenv(k); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid number %q", k, v))
+				return
+			}
+			*dst = f
+		}
+	}
+
+	str("DATABASE_URL", &c.DatabaseURL)
+	str("LISTEN_ADDR", &c.ListenAddr)
+	str("GRPC_LISTEN_ADDR", &c.GRPCListenAddr)
+	dur("CHECK_INTERVAL", &c.CheckInterval)
+	dur("HTTP_TIMEOUT", &c.HTTPTimeout)
+	num("MAX_CONCURRENCY", &c.MaxConcurrency)
+	dur("SHUTDOWN_GRACE", &c.ShutdownGrace)
+	str("TARGETS_FILE", &c.TargetsFile)
+	str("DNS_RESOLVER", &c.DNSResolver)
+	num("RETRY_MAX_ATTEMPTS", &c.Retry.MaxAttempts)
+	str("RETRY_BASE_BACKOFF", &c.Retry.BaseBackoff)
+	str("RETRY_MAX_BACKOFF", &c.Retry.MaxBackoff)
+	decimal("HOST_LIMIT_RPS", &c.HostLimits.Default.RPS)
+	num("HOST_LIMIT_BURST", &c.HostLimits.Default.Burst)
+	boolean("HOST_LIMIT_ROBOTS_CRAWL_DELAY", &c.HostLimits.RobotsCrawlDelay)
+	num("BREAKER_THRESHOLD", &c.Breaker.Threshold)
+	str("BREAKER_COOLDOWN", &c.Breaker.Cooldown)
+	str("TRANSPORT_PROXY", &c.Transport.Proxy)
+	str("TRANSPORT_CA_FILE", &c.Transport.CAFile)
+	str("TRANSPORT_CERT_FILE", &c.Transport.CertFile)
+	str("TRANSPORT_KEY_FILE", &c.Transport.KeyFile)
+	str("TRANSPORT_TLS_MIN_VERSION", &c.Transport.TLSMinVersion)
+	str("SECRETS_KEY", &c.SecretsKey)
+	str("SECRETS_KEY_FILE", &c.SecretsKeyFile)
+	boolean("AUTH_DISABLED", &c.AuthDisabled)
+	boolean("EGRESS_ALLOW_PRIVATE", &c.Egress.AllowPrivate)
+	list("EGRESS_ALLOW_CIDRS", &c.Egress.AllowCIDRs)
+	list("EGRESS_DENY_CIDRS", &c.Egress.DenyCIDRs)
+	list("EGRESS_ALLOW_HOSTS", &c.Egress.AllowHosts)
+	list("EGRESS_DENY_HOSTS", &c.Egress.DenyHosts)
+	return errors.Join(errs...)
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	//no scheme: pgx's key=value form
+	if strings.Contains(c.DatabaseURL, "://") &&
+		!strings.HasPrefix(c.DatabaseURL, "postgres://") &&
+		!strings.HasPrefix(c.DatabaseURL, "postgresql://") &&
+		!strings.HasPrefix(c.DatabaseURL, "sqlite://") {
+		errs = append(errs, errors.New("database_url: want postgres://, postgresql://, sqlite:// or a key=value DSN"))
+	}
+	if c.ListenAddr == "" {
+		errs = append(errs, errors.New("listen_addr: must not be empty"))
+	}
+	if c.GRPCListenAddr != "" && c.GRPCListenAddr == c.ListenAddr {
+		errs = append(errs, errors.New("grpc_listen_addr: must differ from listen_addr"))
+	}
+	if c.CheckInterval.D() < time.Second {
+		errs = append(errs, fmt.Errorf("check_interval: must be at least 1s, got %s", c.CheckInterval))
+	}
+	if c.HTTPTimeout.D() <= 0 {
+		errs = append(errs, fmt.Errorf("http_timeout: must be positive, got %s", c.HTTPTimeout))
+	}
+	if c.MaxConcurrency < 1 || c.MaxConcurrency > 1024 {
+		errs = append(errs, fmt.Errorf("max_concurrency: must be between 1 and 1024, got %d", c.MaxConcurrency))
+	}
+	if c.ShutdownGrace.D() < 0 {
+		errs = append(errs, fmt.Errorf("shutdown_grace: must not be negative, got %s", c.ShutdownGrace))
+	}
+	if c.TargetsSyncInterval.D() < time.Second {
+		errs = append(errs, fmt.Errorf("targets_sync_interval: must be at least 1s, got %s", c.TargetsSyncInterval))
+	}
+	if _, err := c.Egress.Policy(); err != nil {
+		errs = append(errs, fmt.Errorf("egress.%w", err))
+	}
+	if c.DNSResolver != "" {
+		if _, err := core.DNSServer(c.DNSResolver); err != nil {
+			errs = append(errs, fmt.Errorf("dns_resolver: %w", err))
+		}
+	}
+	if err := c.Retry.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("retry.%w", err))
+	} else if p := c.RetryPolicy(); p.BaseBackoff > p.MaxBackoff {
+		errs = append(errs, fmt.Errorf("retry: base_backoff %s exceeds max_backoff %s", p.BaseBackoff, p.MaxBackoff))
+	}
+	if err := c.HostLimits.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("host_limits.%w", err))
+	}
+	if err := c.Breaker.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("breaker.%w", err))
+	}
+	if err := c.Transport.Load(); err != nil {
+		errs = append(errs, fmt.Errorf("transport.%w", err))
+	}
+	if c.Transport.CertSecret != "" {
+		//secrets belong to a project, the global settings to none
+		errs = append(errs, errors.New("transport.cert_secret: only targets can use secrets, set cert_file"))
+	}
+	if c.SecretsKey != "" && c.SecretsKeyFile != "" {
+		errs = append(errs, errors.New("secrets_key_file: SECRETS_KEY is set too, use one"))
+	} else if _, err := c.SecretsBox(); err != nil {
+		errs = append(errs, fmt.Errorf("secrets_key: %w", err))
+	}
+	if err := validateTargets(c.Targets); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func validateTargets(ts []TargetSpec) error {
+	var errs []error
+	for i, t := range ts {
+		if strings.TrimSpace(t.URL) == "" {
+			errs = append(errs, fmt.Errorf("targets[%d].url: must not be empty", i))
+		}
+		if t.Interval.D() < 0 || t.Timeout.D() < 0 {
+			errs = append(errs, fmt.Errorf("targets[%d]: interval/timeout must not be negative", i))
+		}
+		if t.Retry != nil {
+			if err := t.Retry.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("targets[%d].retry.%w", i, err))
+			}
+		}
+		if t.Transport != nil {
+			//reads the files, so a wrong path fails the load instead of every check
+			if err := t.Transport.Load(); err != nil {
+				errs = append(errs, fmt.Errorf("targets[%d].transport.%w", i, err))
+			}
+		}
+		if err := headers.Validate(t.Headers); err != nil {
+			errs = append(errs, fmt.Errorf("targets[%d].headers: %w", i, err))
+		}
+		if t.BasicAuth != nil {
+			if err := t.BasicAuth.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("targets[%d].basic_auth.%w", i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
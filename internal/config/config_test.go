@@ -0,0 +1,279 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/breaker"
+	"github.com/nurzh/linkwatch/internal/secrets"
+	"github.com/stretchr/testify/require"
+)
+
+func env(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+func writeFile(t *testing.T, name, body string) string {
+	p := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(p, []byte(body), 0o600))
+	return p
+}
+
+func TestLoadDefaults(t *testing.T) {
+	c, err := Load("", env(nil), nil)
+	require.NoError(t, err)
+	require.Equal(t, Defaults(), c)
+}
+
+func TestLoadLayering(t *testing.T) {
+	path := writeFile(t, "lw.yaml", `
+database_url: sqlite://from-file.db
+check_interval: 30s
+max_concurrency: 4
+targets:
+  - url: https://example.org/
+`)
+	c, err := Load(path, env(map[string]string{"MAX_CONCURRENCY": "16"}), func(c *Config) {
+		c.CheckInterval = Duration(time.Minute)
+	})
+	require.NoError(t, err)
+	require.Equal(t, "sqlite://from-file.db", c.DatabaseURL)
+	require.Equal(t, 16, c.MaxConcurrency, "env beats file")
+	require.Equal(t, time.Minute, c.CheckInterval.D(), "flags beat env and file")
+	require.Equal(t, 5*time.Second, c.HTTPTimeout.D(), "default kept")
+	require.Len(t, c.Targets, 1)
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := writeFile(t, "lw.json", `{"http_timeout":"2s","listen_addr":":9090"}`)
+	c, err := Load(path, env(nil), nil)
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Second, c.HTTPTimeout.D())
+	require.Equal(t, ":9090", c.ListenAddr)
+}
+
+func TestLoadRejectsInvalid(t *testing.T) {
+	_, err := Load("", env(map[string]string{"MAX_CONCURRENCY": "abc"}), nil)
+	require.ErrorContains(t, err, `MAX_CONCURRENCY: invalid integer "abc"`)
+
+	_, err = Load("", env(map[string]string{"CHECK_INTERVAL": "soon"}), nil)
+	require.ErrorContains(t, err, "CHECK_INTERVAL")
+
+	_, err = Load("", env(map[string]string{"MAX_CONCURRENCY": "0"}), nil)
+	require.ErrorContains(t, err, "max_concurrency")
+
+	_, err = Load(writeFile(t, "lw.yaml", "max_concurency: 3\n"), env(nil), nil)
+	require.ErrorContains(t, err, "max_concurency", "typos in keys are errors")
+
+	_, err = Load(writeFile(t, "lw.yaml", "http_timeout: 5\n"), env(nil), nil)
+	require.Error(t, err)
+
+	_, err = Load(writeFile(t, "lw.toml", ""), env(nil), nil)
+	require.Error(t, err)
+
+	_, err = Load(writeFile(t, "lw.yaml", "egress:\n  deny_cidrs: [10.0.0.0/33]\n"), env(nil), nil)
+	require.ErrorContains(t, err, "egress.deny_cidrs")
+
+	_, err = Load("", env(map[string]string{"GRPC_LISTEN_ADDR": ":8080"}), nil)
+	require.ErrorContains(t, err, "grpc_listen_addr")
+
+	_, err = Load("", env(map[string]string{"DNS_RESOLVER": "10.0.0.53:dns0"}), nil)
+	require.ErrorContains(t, err, "dns_resolver")
+
+	_, err = Load("", env(map[string]string{"DATABASE_URL": "mysql://db/linkwatch"}), nil)
+	require.ErrorContains(t, err, "database_url")
+	_, err = Load("", env(map[string]string{"DATABASE_URL": "host=db user=linkwatch dbname=linkwatch"}), nil)
+	require.NoError(t, err, "pgx key=value DSN")
+}
+
+func TestLoadEgress(t *testing.T) {
+	path := writeFile(t, "lw.yaml", `
+egress:
+  allow_cidrs: [10.1.0.0/16]
+  deny_hosts: ["*.corp.internal"]
+`)
+	c, err := Load(path, env(map[string]string{"EGRESS_ALLOW_HOSTS": "status.corp.internal, db.local"}), nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.1.0.0/16"}, c.Egress.AllowCIDRs)
+	require.Equal(t, []string{"status.corp.internal", "db.local"}, c.Egress.AllowHosts)
+	p, err := c.Egress.Policy()
+	require.NoError(t, err)
+	require.ErrorContains(t, p.CheckHost("x.corp.internal"), "deny_hosts")
+	require.NoError(t, p.CheckHost("10.1.2.3"))
+	require.Error(t, p.CheckHost("10.2.0.1"))
+}
+
+func TestLoadDNSResolver(t *testing.T) {
+	c, err := Load(writeFile(t, "lw.yaml", "dns_resolver: 1.1.1.1:53\n"), env(nil), nil)
+	require.NoError(t, err)
+	require.Equal(t, "1.1.1.1:53", c.DNSResolver)
+
+	c, err = Load("", env(map[string]string{"DNS_RESOLVER": "[2606:4700:4700::1111]:5353"}), nil)
+	require.NoError(t, err)
+	require.Equal(t, "[2606:4700:4700::1111]:5353", c.DNSResolver)
+}
+
+func TestLoadRetry(t *testing.T) {
+	path := writeFile(t, "lw.yaml", `
+retry:
+  max_attempts: 5
+  statuses: ["502", "503"]
+  errors: [timeout]
+targets:
+  - url: https://example.org/
+    retry:
+      max_attempts: 1
+`)
+	c, err := Load(path, env(map[string]string{"RETRY_MAX_BACKOFF": "2s"}), nil)
+	require.NoError(t, err)
+	p := c.RetryPolicy()
+	require.Equal(t, 5, p.MaxAttempts)
+	require.Equal(t, 2*time.Second, p.MaxBackoff)
+	require.Equal(t, 200*time.Millisecond, p.BaseBackoff, "default kept")
+	require.Equal(t, []string{"502", "503"}, p.Statuses)
+	require.Equal(t, 1, c.Targets[0].Retry.MaxAttempts)
+
+	_, err = Load(writeFile(t, "lw.yaml", "retry:\n  errors: [dsn]\n"), env(nil), nil)
+	require.ErrorContains(t, err, "retry.errors")
+	_, err = Load(writeFile(t, "lw.yaml", "targets:\n  - url: https://example.org/\n    retry: {statuses: [\"5x\"]}\n"), env(nil), nil)
+	require.ErrorContains(t, err, "targets[0].retry.statuses")
+	_, err = Load("", env(map[string]string{"RETRY_BASE_BACKOFF": "1m"}), nil)
+	require.ErrorContains(t, err, "exceeds max_backoff")
+}
+
+func TestLoadHostLimits(t *testing.T) {
+	path := writeFile(t, "lw.yaml", `
+host_limits:
+  default: {rps: 1, burst: 2}
+  hosts:
+    "*.example.org": {rps: 0.2}
+  robots_crawl_delay: true
+`)
+	c, err := Load(path, env(map[string]string{"HOST_LIMIT_RPS": "0.5"}), nil)
+	require.NoError(t, err)
+	require.Equal(t, 0.5, c.HostLimits.Default.RPS)
+	require.Equal(t, 2, c.HostLimits.Default.Burst)
+	require.Equal(t, 0.2, c.HostLimits.Hosts["*.example.org"].RPS)
+	require.True(t, c.HostLimits.RobotsCrawlDelay)
+
+	_, err = Load(writeFile(t, "lw.yaml", "host_limits:\n  hosts:\n    Example.org: {rps: 1}\n"), env(nil), nil)
+	require.ErrorContains(t, err, "host_limits.hosts")
+	_, err = Load("", env(map[string]string{"HOST_LIMIT_RPS": "fast"}), nil)
+	require.ErrorContains(t, err, "HOST_LIMIT_RPS")
+	_, err = Load("", env(map[string]string{"HOST_LIMIT_BURST": "-1"}), nil)
+	require.ErrorContains(t, err, "host_limits.default.burst")
+}
+
+func TestLoadBreaker(t *testing.T) {
+	c, err := Load("", env(nil), nil)
+	require.NoError(t, err)
+	require.Equal(t, breaker.Default(), c.Breaker, "on by default")
+
+	c, err = Load(writeFile(t, "lw.yaml", "breaker:\n  cooldown: 2m\n"), env(map[string]string{"BREAKER_THRESHOLD": "0"}), nil)
+	require.NoError(t, err)
+	require.Equal(t, breaker.Config{Threshold: 0, Cooldown: "2m"}, c.Breaker)
+
+	_, err = Load("", env(map[string]string{"BREAKER_COOLDOWN": "soon"}), nil)
+	require.ErrorContains(t, err, "breaker.cooldown")
+}
+
+func TestLoadTransport(t *testing.T) {
+	dir := t.TempDir()
+	ca := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(ca, testCA(t), 0o600))
+	path := writeFile(t, "lw.yaml", `
+transport:
+  ca_file: `+ca+`
+  tls_min_version: "1.3"
+targets:
+  - url: https://internal.example.org/
+    transport:
+      server_name: internal.example.org
+      insecure_skip_verify: true
+`)
+	c, err := Load(path, env(map[string]string{"TRANSPORT_PROXY": "http://proxy.example.org:3128"}), nil)
+	require.NoError(t, err)
+	require.Equal(t, ca, c.Transport.CAFile)
+	require.Equal(t, "1.3", c.Transport.TLSMinVersion)
+	require.Equal(t, "http://proxy.example.org:3128", c.Transport.Proxy)
+	require.True(t, c.Targets[0].Transport.Insecure())
+
+	_, err = Load("", env(map[string]string{"TRANSPORT_CA_FILE": filepath.Join(dir, "missing.pem")}), nil)
+	require.ErrorContains(t, err, "transport.ca_file")
+	_, err = Load(writeFile(t, "lw.yaml", "targets:\n  - url: https://example.org/\n    transport: {cert_file: a.pem}\n"), env(nil), nil)
+	require.ErrorContains(t, err, "targets[0].transport.cert_file")
+}
+
+func TestLoadSecrets(t *testing.T) {
+	key := secrets.NewKey()
+	c, err := Load(writeFile(t, "lw.yaml", `
+targets:
+  - url: https://api.example.org/
+    headers: {Authorization: "Bearer ${secret:api-token}"}
+    basic_auth: {username: svc, password: "${secret:svc-password}"}
+    transport: {cert_secret: client.pem, key_secret: client.key}
+`), env(map[string]string{"SECRETS_KEY": key}), nil)
+	require.NoError(t, err)
+	box, err := c.SecretsBox()
+	require.NoError(t, err)
+	require.NotNil(t, box)
+	require.Equal(t, "svc", c.Targets[0].BasicAuth.Username)
+	require.Equal(t, "client.pem", c.Targets[0].Transport.CertSecret)
+
+	c, err = Load("", env(nil), nil)
+	require.NoError(t, err)
+	box, err = c.SecretsBox()
+	require.NoError(t, err)
+	require.Nil(t, box, "secrets are optional")
+
+	keyFile := writeFile(t, "key", key)
+	for _, tc := range []struct {
+		env  map[string]string
+		body string
+		want string
+	}{
+		{env: map[string]string{"SECRETS_KEY": "short"}, want: "secrets_key: want 32 bytes"},
+		{env: map[string]string{"SECRETS_KEY": key, "SECRETS_KEY_FILE": keyFile}, want: "secrets_key_file"},
+		{body: "transport: {cert_secret: a, key_secret: b}\n", want: "transport.cert_secret: only targets"},
+		{body: "targets:\n  - url: https://a.test/\n    headers: {\"Bad Name\": x}\n", want: "targets[0].headers"},
+		{body: "targets:\n  - url: https://a.test/\n    basic_auth: {password: x}\n", want: "targets[0].basic_auth.username"},
+	} {
+		path := ""
+		if tc.body != "" {
+			path = writeFile(t, "lw.yaml", tc.body)
+		}
+		_, err := Load(path, env(tc.env), nil)
+		require.ErrorContains(t, err, tc.want)
+	}
+}
+
+// self-signed certificate as PEM
+func testCA(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour), IsCA: true, BasicConstraintsValid: true}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestFlagsOnlyOverrideWhenSet(t *testing.T) {
+	path := writeFile(t, "lw.yaml", "max_concurrency: 3\n")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-config", path, "-http-timeout", "7s"}))
+
+	c, err := f.Load()
+	require.NoError(t, err)
+	require.Equal(t, 3, c.MaxConcurrency, "unset flag default must not clobber the file")
+	require.Equal(t, 7*time.Second, c.HTTPTimeout.D())
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"flag"
+	"os"
+)
+
+// Flags holds command-line overrides; only flags that were actually set
+// win over the file and the environment.
+type Flags struct {
+	fs   *flag.FlagSet
+	path string
+	c    Config
+}
+
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs}
+	d := Defaults()
+	fs.StringVar(&f.path, "config", os.Getenv("LINKWATCH_CONFIG"), "YAML or JSON config file (env LINKWATCH_CONFIG)")
+	fs.StringVar(&f.c.DatabaseURL, "database-url", "", "postgres:// or sqlite:// DSN")
+	fs.StringVar(&f.c.ListenAddr, "listen", d.ListenAddr, "HTTP listen address")
+	fs.StringVar(&f.c.GRPCListenAddr, "grpc-listen", "", "gRPC listen address (off when empty)")
+	fs.Func("check-interval", "how often to schedule checks (default "+d.CheckInterval.String()+")", f.c.CheckInterval.parse)
+	fs.Func("http-timeout", "timeout of a single check (default "+d.HTTPTimeout.String()+")", f.c.HTTPTimeout.parse)
+	fs.IntVar(&f.c.MaxConcurrency, "max-concurrency", d.MaxConcurrency, "max parallel checks")
+	fs.StringVar(&f.c.TargetsFile, "targets-file", "", "declarative targets file (YAML/JSON)")
+	fs.Func("shutdown-grace", "graceful shutdown deadline (default "+d.ShutdownGrace.String()+")", f.c.ShutdownGrace.parse)
+	return f
+}
+
+func (f *Flags) Path() string { return f.path }
+
+// Load reads the config the same way on startup and on SIGHUP
+func (f *Flags) Load() (Config, error) {
+	return Load(f.path, os.Getenv, f.apply)
+}
+
+func (f *Flags) apply(c *Config) {
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "database-url":
+			c.DatabaseURL = f.c.DatabaseURL
+		case "listen":
+			c.ListenAddr = f.c.ListenAddr
+		case "grpc-listen":
+			c.GRPCListenAddr = f.c.GRPCListenAddr
+		case "check-interval":
+			c.CheckInterval = f.c.CheckInterval
+		case "http-timeout":
+			c.HTTPTimeout = f.c.HTTPTimeout
+		case "max-concurrency":
+			c.MaxConcurrency = f.c.MaxConcurrency
+		case "shutdown-grace":
+			c.ShutdownGrace = f.c.ShutdownGrace
+		case "targets-file":
+			c.TargetsFile = f.c.TargetsFile
+		}
+	})
+}
@@ -2,19 +2,35 @@ package core
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
+// options of tcp:// targets, e.g. tcp://mail.example.com:25?expect=220
+const (
+	TCPSend   = "send"   // written after connecting (and the TLS handshake)
+	TCPExpect = "expect" // must appear in the first bytes read back
+	TCPTLS    = "tls"    // "true": handshake and verify the certificate
+)
+
 func Canonicalize(raw string) (canon string, host string, err error) {
 	u, err := url.Parse(raw)
 	if err != nil {
 		return "", "", err
 	}
+	if strings.EqualFold(u.Scheme, "dns") {
+		return canonicalizeDNS(u)
+	}
 	if u.Scheme == "" || u.Host == "" {
 		return "", "", errors.New("url must be absolute with scheme and host")
 	}
 	scheme := strings.ToLower(u.Scheme)
+	if scheme == "tcp" {
+		return canonicalizeTCP(u)
+	}
 	if scheme != "http" && scheme != "https" {
 		return "", "", errors.New("unsupported scheme")
 	}
@@ -46,3 +62,52 @@ func Canonicalize(raw string) (canon string, host string, err error) {
 
 	return u.String(), h, nil
 }
+
+// tcp://host:port[?send=..&expect=..&tls=true]; the port is required and the
+// options are sorted so equal targets compare equal
+func canonicalizeTCP(u *url.URL) (string, string, error) {
+	if u.User != nil {
+		return "", "", errors.New("tcp targets cannot have user info")
+	}
+	if u.Path != "" && u.Path != "/" {
+		return "", "", errors.New("tcp targets cannot have a path")
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil || host == "" {
+		return "", "", errors.New("tcp targets need host:port")
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", "", fmt.Errorf("invalid port %q", port)
+	}
+
+	q := u.Query()
+	out := url.Values{}
+	for k, vs := range q {
+		if len(vs) != 1 {
+			return "", "", fmt.Errorf("option %q given more than once", k)
+		}
+		v := vs[0]
+		switch k {
+		case TCPSend, TCPExpect:
+			if v == "" {
+				return "", "", fmt.Errorf("option %q is empty", k)
+			}
+		case TCPTLS:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return "", "", fmt.Errorf("option tls must be true or false, got %q", v)
+			}
+			if !b {
+				continue
+			}
+			v = "true"
+		default:
+			return "", "", fmt.Errorf("unknown option %q, allowed: send, expect, tls", k)
+		}
+		out.Set(k, v)
+	}
+
+	h := strings.ToLower(net.JoinHostPort(host, port))
+	c := url.URL{Scheme: "tcp", Host: h, RawQuery: out.Encode()}
+	return c.String(), h, nil
+}
@@ -14,6 +14,14 @@ func TestCanonicalize(t *testing.T) {
 		{"https://example.com:443/", "https://example.com/", "example.com"},
 		{"http://example.com:80/path/", "http://example.com/path", "example.com"},
 		{"https://ExAmPlE.com/a/b#frag", "https://example.com/a/b", "example.com"},
+		{"TCP://Mail.Example.com:25", "tcp://mail.example.com:25", "mail.example.com:25"},
+		{"tcp://db.test:5432/", "tcp://db.test:5432", "db.test:5432"},
+		{"tcp://[::1]:6379?tls=1&send=PING%0D%0A&expect=PONG#x", "tcp://[::1]:6379?expect=PONG&send=PING%0D%0A&tls=true", "[::1]:6379"},
+		{"tcp://a.test:25?tls=false", "tcp://a.test:25", "a.test:25"},
+		{"DNS:Example.ORG.", "dns:example.org?type=A", "example.org"},
+		{"dns:///example.org?type=mx&expect=010+Mail.Example.org.&expect=alt.example.org", "dns:example.org?expect=10+mail.example.org&expect=alt.example.org&type=MX", "example.org"},
+		{"dns://1.1.1.1:53/example.org?type=AAAA&expect=2001:DB8::1", "dns://1.1.1.1/example.org?expect=2001%3Adb8%3A%3A1&type=AAAA", "example.org"},
+		{"dns://[2001:db8::53]:5353/_dmarc.example.org?type=TXT&expect=v%3DDMARC1", "dns://[2001:db8::53]:5353/_dmarc.example.org?expect=v%3DDMARC1&type=TXT", "_dmarc.example.org"},
 	}
 	for _, tt := range tests {
 		gotURL, gotHost, err := Canonicalize(tt.in)
@@ -24,7 +32,11 @@ func TestCanonicalize(t *testing.T) {
 }
 
 func TestCanonicalizeRejects(t *testing.T) {
-	for _, bad := range []string{"", "://nope", "ftp://example.com", "example.com/path"} {
+	for _, bad := range []string{"", "://nope", "ftp://example.com", "example.com/path",
+		"tcp://a.test", "tcp://a.test:0", "tcp://a.test:70000", "tcp://:25", "tcp://a.test:25/path", "tcp://u@a.test:25",
+		"tcp://a.test:25?x=1", "tcp://a.test:25?tls=maybe", "tcp://a.test:25?send=", "tcp://a.test:25?send=a&send=b",
+		"dns:", "dns://1.1.1.1/", "dns:a..b", "dns:a b.test", "dns:a.test?type=SRV", "dns:a.test?expect=nope",
+		"dns:a.test?type=AAAA&expect=1.2.3.4", "dns:a.test?type=MX&expect=x+mx.test", "dns:a.test?class=IN", "dns://1.1.1.1:0/a.test"} {
 		_, _, err := Canonicalize(bad)
 		require.Error(t, err, bad)
 	}
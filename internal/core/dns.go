@@ -0,0 +1,155 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// options of dns: targets (RFC 4501), e.g. dns://1.1.1.1/example.org?type=MX&expect=mail.example.org
+const (
+	DNSType   = "type"   // A (default), AAAA, CNAME, MX or TXT
+	DNSExpect = "expect" // repeatable; every value must be among the answers
+)
+
+var DNSTypes = []string{"A", "AAAA", "CNAME", "MX", "TXT"}
+
+// dns:name or dns://resolver[:port]/name; the name is the target's host.
+// Without a resolver the checker's default (dns_resolver) is asked
+func canonicalizeDNS(u *url.URL) (string, string, error) {
+	if u.User != nil {
+		return "", "", errors.New("dns targets cannot have user info")
+	}
+	name := u.Opaque
+	if name == "" {
+		name = strings.TrimPrefix(u.Path, "/")
+	}
+	name, err := DNSName(name)
+	if err != nil {
+		return "", "", err
+	}
+
+	server := ""
+	if u.Host != "" {
+		if server, err = DNSServer(u.Host); err != nil {
+			return "", "", err
+		}
+	}
+
+	q := u.Query()
+	typ := "A"
+	expects := []string{}
+	for k, vs := range q {
+		switch k {
+		case DNSType:
+			if len(vs) != 1 {
+				return "", "", errors.New("option \"type\" given more than once")
+			}
+			typ = strings.ToUpper(vs[0])
+			if !slices.Contains(DNSTypes, typ) {
+				return "", "", fmt.Errorf("type must be one of %s, got %q", strings.Join(DNSTypes, ", "), vs[0])
+			}
+		case DNSExpect:
+			expects = vs
+		default:
+			return "", "", fmt.Errorf("unknown option %q, allowed: type, expect", k)
+		}
+	}
+	out := url.Values{DNSType: {typ}}
+	for _, e := range expects {
+		v, err := DNSValue(typ, e)
+		if err != nil {
+			return "", "", err
+		}
+		if !slices.Contains(out[DNSExpect], v) {
+			out[DNSExpect] = append(out[DNSExpect], v)
+		}
+	}
+	slices.Sort(out[DNSExpect])
+
+	c := url.URL{Scheme: "dns", Opaque: name, RawQuery: out.Encode()}
+	if server != "" {
+		c = url.URL{Scheme: "dns", Host: server, Path: "/" + name, RawQuery: out.Encode()}
+	}
+	return c.String(), name, nil
+}
+
+// DNSName lower-cases a host name and drops the root dot
+func DNSName(v string) (string, error) {
+	name := strings.TrimSuffix(strings.ToLower(v), ".")
+	if name == "" || len(name) > 253 {
+		return "", errors.New("dns targets need a name, e.g. dns:example.org")
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return "", fmt.Errorf("invalid name %q", v)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return "", fmt.Errorf("invalid name %q", v)
+			}
+		}
+	}
+	return name, nil
+}
+
+// DNSServer is a resolver address as host[:port]; the default port 53 is dropped
+func DNSServer(v string) (string, error) {
+	host, port, err := net.SplitHostPort(v)
+	if err != nil {
+		host, port = strings.Trim(v, "[]"), "53"
+	}
+	if host == "" {
+		return "", fmt.Errorf("invalid resolver %q", v)
+	}
+	if p, err := net.LookupPort("udp", port); err != nil || p < 1 {
+		return "", fmt.Errorf("invalid resolver port %q", port)
+	}
+	host = strings.ToLower(host)
+	if port == "53" {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]", nil
+		}
+		return host, nil
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// DNSValue normalizes an answer (or expected value) of a record type so the
+// two compare as strings: canonical IPs, lower-case names without the root
+// dot, MX as "<pref> <host>" or just "<host>"; TXT is kept as is
+func DNSValue(typ, v string) (string, error) {
+	if v == "" {
+		return "", errors.New("expect must not be empty")
+	}
+	switch typ {
+	case "A", "AAAA":
+		ip, err := netip.ParseAddr(v)
+		if err != nil || ip.Is4() != (typ == "A") || ip.Zone() != "" {
+			return "", fmt.Errorf("expect %q is not an %s address", v, map[string]string{"A": "IPv4", "AAAA": "IPv6"}[typ])
+		}
+		return ip.String(), nil
+	case "CNAME":
+		return DNSName(v)
+	case "MX":
+		pref, host, ok := strings.Cut(v, " ")
+		if !ok {
+			return DNSName(v)
+		}
+		n, err := strconv.ParseUint(pref, 10, 16)
+		if err != nil {
+			return "", fmt.Errorf("expect %q: want \"<host>\" or \"<preference> <host>\"", v)
+		}
+		h, err := DNSName(host)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(n, 10) + " " + h, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,281 @@
+// Package events is the in-process pub/sub for live check and target events.
+// The checker and target writers publish, stream endpoints subscribe; a bounded ring of recent events lets
+// reconnecting clients resume from Last-Event-ID. With Postgres, events are
+// also relayed between replicas over LISTEN/NOTIFY (see RelayPostgres).
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/store"
+)
+
+const (
+	TypeResult = "result" // a check finished
+	TypeState  = "state"  // a target went up → down (incident opened) or down → up (resolved)
+	TypeTarget = "target" // a target was created, updated or archived
+)
+
+// target actions
+const (
+	Created    = "created"
+	Updated    = "updated"
+	Archived   = "archived"
+	Unarchived = "unarchived"
+)
+
+type Event struct {
+	// decimal, increasing; derived from the publish time so ids from
+	// different replicas interleave sensibly
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Time      time.Time         `json:"time"`
+	ProjectID string            `json:"project_id"`
+	TargetID  string            `json:"target_id"`
+	URL       string            `json:"url,omitempty"`
+	Host      string            `json:"host,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+
+	Result *store.CheckResult `json:"result,omitempty"` // TypeResult
+	State  *StateChange       `json:"state,omitempty"`  // TypeState
+	Action string             `json:"action,omitempty"` // TypeTarget
+	Target *store.Target      `json:"target,omitempty"` // TypeTarget
+
+	Origin string `json:"origin,omitempty"` // publishing replica, for the relay
+}
+
+type StateChange struct {
+	From     string `json:"from"` // up, down
+	To       string `json:"to"`
+	Incident string `json:"incident"` // opened (to down), resolved (to up)
+}
+
+// Transition describes a flip between up and down
+func Transition(wasUp, up bool) *StateChange {
+	c := &StateChange{From: StateName(wasUp), To: StateName(up), Incident: "opened"}
+	if up {
+		c.Incident = "resolved"
+	}
+	return c
+}
+
+// TargetEvent is the event for a target write; credentials are redacted
+func TargetEvent(action string, t store.Target) Event {
+	t = t.Redacted()
+	return Event{Type: TypeTarget, ProjectID: t.ProjectID, TargetID: t.ID, URL: t.URL, Host: t.Host, Labels: t.Labels,
+		Action: action, Target: &t}
+}
+
+// Up: the check succeeded and, for HTTP, answered below 400
+func Up(r store.CheckResult) bool {
+	return r.Error == nil && (r.StatusCode == nil || *r.StatusCode < 400)
+}
+
+func StateName(up bool) string {
+	if up {
+		return "up"
+	}
+	return "down"
+}
+
+// Filter selects events; zero fields match everything
+type Filter struct {
+	ProjectID string
+	TargetID  string
+	Host      string
+	Labels    map[string]string // all must match
+	Types     []string          // any of them
+}
+
+func (f Filter) Match(ev Event) bool {
+	if f.ProjectID != "" && ev.ProjectID != f.ProjectID {
+		return false
+	}
+	if f.TargetID != "" && ev.TargetID != f.TargetID {
+		return false
+	}
+	if f.Host != "" && ev.Host != f.Host {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, ev.Type) {
+		return false
+	}
+	for k, v := range f.Labels {
+		if got, ok := ev.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Publisher is what event sources need
+type Publisher interface {
+	Publish(ev Event)
+}
+
+// per-subscriber buffer; a subscriber that falls this far behind is dropped
+const subBuffer = 256
+
+type Broker struct {
+	origin string
+
+	mu     sync.Mutex
+	lastID uint64
+	ring   []Event // oldest first once full, see snapshot
+	next   int
+	full   bool
+	subs   map[*Subscription]struct{}
+	relay  func(Event) // set by RelayPostgres
+}
+
+// NewBroker keeps the last history events for resumption
+func NewBroker(history int) *Broker {
+	if history <= 0 {
+		history = 1024
+	}
+	var b [6]byte
+	_, _ = rand.Read(b[:])
+	return &Broker{
+		origin: hex.EncodeToString(b[:]),
+		ring:   make([]Event, history),
+		subs:   map[*Subscription]struct{}{},
+	}
+}
+
+// Publish assigns the event an id and delivers it locally and, if relaying,
+// to the other replicas
+func (b *Broker) Publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	ev.Origin = b.origin
+	b.mu.Lock()
+	id := max(uint64(ev.Time.UnixMicro()), b.lastID+1)
+	ev.ID = strconv.FormatUint(id, 10)
+	relay := b.relay
+	b.deliverLocked(ev)
+	b.mu.Unlock()
+	if relay != nil {
+		relay(ev)
+	}
+}
+
+// deliver an event that already has an id (from another replica)
+func (b *Broker) deliver(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deliverLocked(ev)
+}
+
+func (b *Broker) deliverLocked(ev Event) {
+	if id, err := strconv.ParseUint(ev.ID, 10, 64); err == nil && id > b.lastID {
+		b.lastID = id
+	}
+	b.ring[b.next] = ev
+	b.next = (b.next + 1) % len(b.ring)
+	if b.next == 0 {
+		b.full = true
+	}
+	for s := range b.subs {
+		if !s.filter.Match(ev) {
+			continue
+		}
+		select {
+		case s.c <- ev:
+		default:
+			//too slow: drop it, the client resumes with Last-Event-ID
+			s.lagged = true
+			b.dropLocked(s)
+		}
+	}
+}
+
+func (b *Broker) dropLocked(s *Subscription) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.c)
+	}
+}
+
+type Subscription struct {
+	b      *Broker
+	filter Filter
+	c      chan Event
+	lagged bool // guarded by b.mu
+}
+
+// C is closed when the subscription ends
+func (s *Subscription) C() <-chan Event { return s.c }
+
+// Lagged reports whether the subscriber was dropped for falling behind
+func (s *Subscription) Lagged() bool {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	return s.lagged
+}
+
+func (s *Subscription) Close() {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	s.b.dropLocked(s)
+}
+
+// Subscribe returns the retained events after lastID that match f (none if
+// lastID is "") and a subscription for everything published afterwards,
+// with no gap in between
+func (b *Broker) Subscribe(f Filter, lastID string) ([]Event, *Subscription, error) {
+	after, err := parseID(lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	replay := b.replayLocked(f, lastID, after)
+	s := &Subscription{b: b, filter: f, c: make(chan Event, subBuffer)}
+	b.subs[s] = struct{}{}
+	return replay, s, nil
+}
+
+// Replay returns the retained events after lastID that match f
+func (b *Broker) Replay(f Filter, lastID string) ([]Event, error) {
+	after, err := parseID(lastID)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.replayLocked(f, lastID, after), nil
+}
+
+func parseID(id string) (uint64, error) {
+	if id == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(id, 10, 64)
+}
+
+func (b *Broker) replayLocked(f Filter, lastID string, after uint64) []Event {
+	if lastID == "" {
+		return nil
+	}
+	var replay []Event
+	for _, ev := range b.snapshotLocked() {
+		if id, _ := strconv.ParseUint(ev.ID, 10, 64); id > after && f.Match(ev) {
+			replay = append(replay, ev)
+		}
+	}
+	return replay
+}
+
+// retained events in arrival order
+func (b *Broker) snapshotLocked() []Event {
+	if !b.full {
+		return append([]Event(nil), b.ring[:b.next]...)
+	}
+	return append(append([]Event(nil), b.ring[b.next:]...), b.ring[:b.next]...)
+}
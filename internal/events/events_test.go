@@ -0,0 +1,113 @@
+package events
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func ev(project, target, host string, labels map[string]string) Event {
+	return Event{Type: TypeResult, ProjectID: project, TargetID: target, Host: host, Labels: labels}
+}
+
+func TestFilterMatch(t *testing.T) {
+	e := ev("p1", "t1", "a.test", map[string]string{"env": "prod", "team": "web"})
+	require.True(t, Filter{}.Match(e))
+	require.True(t, Filter{ProjectID: "p1", Host: "a.test", Labels: map[string]string{"env": "prod"}}.Match(e))
+	require.False(t, Filter{ProjectID: "p2"}.Match(e))
+	require.False(t, Filter{TargetID: "t2"}.Match(e))
+	require.False(t, Filter{Labels: map[string]string{"env": "dev"}}.Match(e))
+	require.False(t, Filter{Labels: map[string]string{"region": ""}}.Match(e), "missing label never matches")
+	require.True(t, Filter{Types: []string{TypeState, TypeResult}}.Match(e))
+	require.False(t, Filter{Types: []string{TypeTarget}}.Match(e))
+}
+
+func TestTransition(t *testing.T) {
+	require.Equal(t, &StateChange{From: "up", To: "down", Incident: "opened"}, Transition(true, false))
+	require.Equal(t, &StateChange{From: "down", To: "up", Incident: "resolved"}, Transition(false, true))
+
+	tg := store.Target{ID: "t1", ProjectID: "p1", URL: "https://a.test/", Host: "a.test", Labels: map[string]string{"env": "prod"}}
+	ev := TargetEvent(Created, tg)
+	require.True(t, Filter{ProjectID: "p1", TargetID: "t1", Host: "a.test", Labels: map[string]string{"env": "prod"}}.Match(ev))
+	require.Equal(t, tg, *ev.Target)
+}
+
+func TestUp(t *testing.T) {
+	code := func(n int) *int { return &n }
+	msg := "timeout"
+	require.True(t, Up(store.CheckResult{StatusCode: code(200)}))
+	require.True(t, Up(store.CheckResult{StatusCode: code(301)}))
+	require.False(t, Up(store.CheckResult{StatusCode: code(404)}))
+	require.False(t, Up(store.CheckResult{Error: &msg}))
+}
+
+func TestSubscribeAndResume(t *testing.T) {
+	b := NewBroker(4)
+	_, all, err := b.Subscribe(Filter{ProjectID: "p1"}, "")
+	require.NoError(t, err)
+
+	var ids []string
+	for i := range 6 {
+		b.Publish(ev("p1", "t"+strconv.Itoa(i), "a.test", nil))
+		got := <-all.C()
+		if len(ids) > 0 {
+			prev, _ := strconv.ParseUint(ids[len(ids)-1], 10, 64)
+			cur, _ := strconv.ParseUint(got.ID, 10, 64)
+			require.Greater(t, cur, prev, "ids increase")
+		}
+		ids = append(ids, got.ID)
+	}
+	b.Publish(ev("p2", "other", "a.test", nil))
+	select {
+	case e := <-all.C():
+		t.Fatalf("event of another project delivered: %+v", e)
+	default:
+	}
+
+	//resume: only retained events after the id, filter applied
+	replay, sub, err := b.Subscribe(Filter{ProjectID: "p1"}, ids[3])
+	require.NoError(t, err)
+	defer sub.Close()
+	require.Len(t, replay, 2)
+	require.Equal(t, ids[4], replay[0].ID)
+	require.Equal(t, ids[5], replay[1].ID)
+
+	replay, err = b.Replay(Filter{ProjectID: "p1"}, ids[4])
+	require.NoError(t, err)
+	require.Len(t, replay, 1)
+
+	//older than the ring: whatever is retained
+	replay, _, err = b.Subscribe(Filter{}, ids[0])
+	require.NoError(t, err)
+	require.Len(t, replay, 4)
+
+	_, _, err = b.Subscribe(Filter{}, "nope")
+	require.Error(t, err)
+
+	all.Close()
+	_, open := <-all.C()
+	require.False(t, open)
+	all.Close() // idempotent
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	b := NewBroker(0)
+	_, slow, err := b.Subscribe(Filter{}, "")
+	require.NoError(t, err)
+	_, fast, err := b.Subscribe(Filter{}, "")
+	require.NoError(t, err)
+
+	for range subBuffer + 1 {
+		b.Publish(ev("p1", "t1", "a.test", nil))
+		<-fast.C()
+	}
+	n := 0
+	for range slow.C() {
+		n++
+	}
+	require.Equal(t, subBuffer, n, "buffered events are still delivered, then the channel closes")
+	require.True(t, slow.Lagged())
+	require.False(t, fast.Lagged())
+}
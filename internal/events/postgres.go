@@ -0,0 +1,103 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Channel is the LISTEN/NOTIFY channel shared by all replicas
+const Channel = "linkwatch_events"
+
+// NOTIFY payloads must stay below 8000 bytes
+const maxPayload = 7900
+
+// RelayPostgres makes b send its events to the other replicas and publish
+// theirs locally. It blocks until ctx is done, reconnecting the listener
+// after errors; events missed while disconnected are not recovered.
+func RelayPostgres(ctx context.Context, pool *pgxpool.Pool, b *Broker) {
+	//Publish must not wait for the database; a full queue drops events
+	out := make(chan Event, subBuffer)
+	b.mu.Lock()
+	b.relay = func(ev Event) {
+		select {
+		case out <- ev:
+		default:
+			log.Printf("events: relay queue full, %s event for %s not sent to other replicas", ev.Type, ev.TargetID)
+		}
+	}
+	b.mu.Unlock()
+	go notify(ctx, pool, out)
+	defer func() {
+		b.mu.Lock()
+		b.relay = nil
+		b.mu.Unlock()
+	}()
+
+	backoff := time.Second
+	for ctx.Err() == nil {
+		err := listen(ctx, pool, b)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("events: listen: %v (retrying in %s)", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+func notify(ctx context.Context, pool *pgxpool.Pool, out <-chan Event) {
+	for {
+		var ev Event
+		select {
+		case <-ctx.Done():
+			return
+		case ev = <-out:
+		}
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			continue
+		}
+		if len(payload) > maxPayload {
+			log.Printf("events: %s event for %s too large to relay (%d bytes)", ev.Type, ev.TargetID, len(payload))
+			continue
+		}
+		nctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		if _, err := pool.Exec(nctx, `SELECT pg_notify($1, $2)`, Channel, string(payload)); err != nil {
+			log.Printf("events: notify: %v", err)
+		}
+		cancel()
+	}
+}
+
+func listen(ctx context.Context, pool *pgxpool.Pool, b *Broker) error {
+	pc, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	//LISTEN state must not go back to the pool
+	conn := pc.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, `LISTEN `+Channel); err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var ev Event
+		if err := json.Unmarshal([]byte(n.Payload), &ev); err != nil || ev.Origin == b.origin {
+			continue
+		}
+		b.deliver(ev)
+	}
+}
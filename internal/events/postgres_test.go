@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+// two brokers on one database behave like two replicas
+func TestRelayPostgres(t *testing.T) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	a, b := NewBroker(0), NewBroker(0)
+	go RelayPostgres(ctx, pool, a)
+	go RelayPostgres(ctx, pool, b)
+
+	_, subA, err := a.Subscribe(Filter{}, "")
+	require.NoError(t, err)
+	_, subB, err := b.Subscribe(Filter{}, "")
+	require.NoError(t, err)
+
+	//LISTEN is asynchronous; publish until the other side hears it
+	deadline := time.After(10 * time.Second)
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case got := <-subB.C():
+			require.Equal(t, "t_relay", got.TargetID)
+			require.NotEmpty(t, got.ID)
+			//a's own event came back locally only, never twice
+			n := len(subA.C())
+			time.Sleep(200 * time.Millisecond)
+			require.Equal(t, n, len(subA.C()))
+			return
+		case <-tick.C:
+			a.Publish(ev("p1", "t_relay", "a.test", nil))
+		case <-deadline:
+			t.Fatal("event not relayed")
+		}
+	}
+}
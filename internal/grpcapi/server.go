@@ -0,0 +1,170 @@
+// Package grpcapi serves the gRPC API (pkg/linkwatchpb) on its own port. It
+// shares the store, internal/targets, auth and the event broker with the
+// REST API, so both behave the same.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/pkg/linkwatchpb"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// scope each method needs
+var scopes = map[string]string{
+	linkwatchpb.LinkwatchService_ListTargets_FullMethodName:  auth.ScopeTargetsRead,
+	linkwatchpb.LinkwatchService_CreateTarget_FullMethodName: auth.ScopeTargetsWrite,
+	linkwatchpb.LinkwatchService_ListResults_FullMethodName:  auth.ScopeResultsRead,
+	linkwatchpb.LinkwatchService_WatchResults_FullMethodName: auth.ScopeResultsRead,
+}
+
+type Server struct {
+	linkwatchpb.UnimplementedLinkwatchServiceServer
+
+	store   store.Store // nil: methods that need it fail with UNAVAILABLE
+	checker *checker.Checker
+	auth    *auth.Authenticator
+	events  *events.Broker // nil: WatchResults fails with UNAVAILABLE
+
+	closing   chan struct{} // closed by CloseStreams
+	closeOnce sync.Once
+}
+
+type Option func(*Server)
+
+// WithEvents enables WatchResults
+func WithEvents(b *events.Broker) Option {
+	return func(s *Server) { s.events = b }
+}
+
+func New(st store.Store, chk *checker.Checker, authn *auth.Authenticator, opts ...Option) *Server {
+	s := &Server{store: st, checker: chk, auth: authn, closing: make(chan struct{})}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// GRPC returns a grpc.Server with the service and its auth interceptors
+func (s *Server) GRPC(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryAuth),
+		grpc.ChainStreamInterceptor(s.streamAuth),
+		//idle watchers are pinged like the SSE keepalive
+		grpc.KeepaliveParams(keepalive.ServerParameters{Time: 15 * time.Second, Timeout: 10 * time.Second}),
+	}, opts...)
+	g := grpc.NewServer(opts...)
+	linkwatchpb.RegisterLinkwatchServiceServer(g, s)
+	return g
+}
+
+// CloseStreams ends WatchResults calls so GracefulStop does not wait for them
+func (s *Server) CloseStreams() {
+	s.closeOnce.Do(func() { close(s.closing) })
+}
+
+// authenticate resolves the key from "x-api-key" or "authorization: Bearer"
+// metadata and checks the method's scope; with auth disabled "x-project"
+// picks the project, like X-Project over HTTP
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	get := func(k string) string {
+		if v := md.Get(k); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	raw, err := auth.RawKey(get("x-api-key"), get("authorization"))
+	if err != nil && !s.auth.Disabled {
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	ctx, err = s.auth.Authenticate(ctx, raw, get("x-project"))
+	switch {
+	case errors.Is(err, auth.ErrUnavailable), errors.Is(err, auth.ErrNoStore):
+		return ctx, status.Error(codes.Unavailable, err.Error())
+	case err != nil:
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	scope, ok := scopes[method]
+	if !ok || !auth.HasScope(ctx, scope) {
+		return ctx, status.Error(codes.PermissionDenied, "API key lacks the required scope "+scope)
+	}
+	return ctx, nil
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, authedStream{ss, ctx})
+}
+
+type authedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a authedStream) Context() context.Context { return a.ctx }
+
+// fails with UNAVAILABLE when there is no database
+func (s *Server) needStore() error {
+	if s.store == nil {
+		return status.Error(codes.Unavailable, "database not configured")
+	}
+	return nil
+}
+
+// invalid is INVALID_ARGUMENT with a BadRequest detail, the gRPC form of the
+// REST validation problem
+func invalid(field, msg string) error {
+	st := status.New(codes.InvalidArgument, field+" "+msg)
+	if d, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+		{Field: field, Description: msg},
+	}}); err == nil {
+		st = d
+	}
+	return st.Err()
+}
+
+// status for an error from the store or internal/targets; unexpected errors
+// are logged and hidden like the REST 500s
+func toStatus(method string, err error, notFound string) error {
+	var fe *store.FieldError
+	switch {
+	case errors.As(err, &fe):
+		return invalid(fe.Field, fe.Message)
+	case errors.Is(err, store.ErrIdemConflict):
+		return status.Error(codes.AlreadyExists, "idempotency_key was already used with a different url")
+	case errors.Is(err, store.ErrQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, store.ErrNotFound):
+		return status.Error(codes.NotFound, notFound)
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return status.FromContextError(err).Err()
+	}
+	log.Printf("grpc %s: %v", method, err)
+	return status.Error(codes.Internal, "internal error, see the server log")
+}
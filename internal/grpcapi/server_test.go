@@ -0,0 +1,240 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/pkg/linkwatchpb"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func testStore(t *testing.T) store.Store {
+	ctx := context.Background()
+	s, err := store.OpenSQLite(ctx, ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	m, err := store.Migrator(s)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	return s
+}
+
+type testEnv struct {
+	st     store.Store
+	srv    *Server
+	broker *events.Broker
+	client linkwatchpb.LinkwatchServiceClient
+}
+
+// serves over an in-memory listener
+func newEnv(t *testing.T, authDisabled bool) *testEnv {
+	st := testStore(t)
+	chk := checker.New(st, 1, time.Second, time.Minute, checker.WithEgressPolicy(netguard.Default()))
+	broker := events.NewBroker(0)
+	srv := New(st, chk, &auth.Authenticator{Store: st, Disabled: authDisabled}, WithEvents(broker))
+	g := srv.GRPC()
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = g.Serve(lis) }()
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &testEnv{st: st, srv: srv, broker: broker, client: linkwatchpb.NewLinkwatchServiceClient(conn)}
+}
+
+func requireCode(t *testing.T, err error, code codes.Code) *status.Status {
+	t.Helper()
+	st, ok := status.FromError(err)
+	require.True(t, ok, "not a status: %v", err)
+	require.Equal(t, code, st.Code(), st.Message())
+	return st
+}
+
+func TestAuth(t *testing.T) {
+	e := newEnv(t, false)
+	ctx := context.Background()
+
+	_, err := e.client.ListTargets(ctx, &linkwatchpb.ListTargetsRequest{})
+	requireCode(t, err, codes.Unauthenticated)
+	_, err = e.client.ListTargets(metadata.AppendToOutgoingContext(ctx, "authorization", "Basic abc"), &linkwatchpb.ListTargetsRequest{})
+	requireCode(t, err, codes.Unauthenticated)
+	_, err = e.client.ListTargets(metadata.AppendToOutgoingContext(ctx, "x-api-key", "lw_nope"), &linkwatchpb.ListTargetsRequest{})
+	requireCode(t, err, codes.Unauthenticated)
+
+	raw, k, err := auth.NewKey(store.DefaultProjectID, "reader", []string{auth.ScopeTargetsRead})
+	require.NoError(t, err)
+	require.NoError(t, e.st.CreateAPIKey(ctx, k))
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+raw)
+	_, err = e.client.ListTargets(authed, &linkwatchpb.ListTargetsRequest{})
+	require.NoError(t, err)
+	_, err = e.client.CreateTarget(authed, &linkwatchpb.CreateTargetRequest{Url: "https://a.test/"})
+	st := requireCode(t, err, codes.PermissionDenied)
+	require.Contains(t, st.Message(), auth.ScopeTargetsWrite)
+
+	//streams are authenticated too
+	w, err := e.client.WatchResults(authed, &linkwatchpb.WatchResultsRequest{})
+	require.NoError(t, err)
+	_, err = w.Recv()
+	requireCode(t, err, codes.PermissionDenied)
+}
+
+func TestCreateAndListTargets(t *testing.T) {
+	e := newEnv(t, true)
+	ctx := context.Background()
+
+	_, err := e.client.CreateTarget(ctx, &linkwatchpb.CreateTargetRequest{Url: "ftp://a.test/"})
+	st := requireCode(t, err, codes.InvalidArgument)
+	require.Len(t, st.Details(), 1)
+	require.Equal(t, "url", st.Details()[0].(*errdetails.BadRequest).FieldViolations[0].Field)
+	_, err = e.client.CreateTarget(ctx, &linkwatchpb.CreateTargetRequest{Url: "http://127.0.0.1/"})
+	requireCode(t, err, codes.InvalidArgument)
+
+	_, sub, err := e.broker.Subscribe(events.Filter{}, "")
+	require.NoError(t, err)
+	first, err := e.client.CreateTarget(ctx, &linkwatchpb.CreateTargetRequest{Url: "HTTPS://A.test:443/x/#frag", IdempotencyKey: "k1"})
+	require.NoError(t, err)
+	require.True(t, first.Created)
+	require.Equal(t, "https://a.test/x", first.Target.Url, "canonicalized like the REST API")
+	require.Equal(t, store.DefaultProjectID, first.Target.ProjectId)
+	ev := <-sub.C()
+	require.Equal(t, events.Created, ev.Action)
+	require.Equal(t, first.Target.Id, ev.TargetID)
+
+	//idempotency key: same url → same target, other url → ALREADY_EXISTS
+	again, err := e.client.CreateTarget(ctx, &linkwatchpb.CreateTargetRequest{Url: "https://a.test/x", IdempotencyKey: "k1"})
+	require.NoError(t, err)
+	require.False(t, again.Created)
+	require.Equal(t, first.Target.Id, again.Target.Id)
+	_, err = e.client.CreateTarget(ctx, &linkwatchpb.CreateTargetRequest{Url: "https://b.test/", IdempotencyKey: "k1"})
+	requireCode(t, err, codes.AlreadyExists)
+
+	for _, u := range []string{"https://b.test/", "https://c.test/"} {
+		_, err := e.client.CreateTarget(ctx, &linkwatchpb.CreateTargetRequest{Url: u})
+		require.NoError(t, err)
+	}
+	page, err := e.client.ListTargets(ctx, &linkwatchpb.ListTargetsRequest{PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Targets, 2)
+	require.NotEmpty(t, page.NextPageToken)
+	page, err = e.client.ListTargets(ctx, &linkwatchpb.ListTargetsRequest{PageSize: 2, PageToken: page.NextPageToken})
+	require.NoError(t, err)
+	require.Len(t, page.Targets, 1)
+	require.Empty(t, page.NextPageToken)
+
+	page, err = e.client.ListTargets(ctx, &linkwatchpb.ListTargetsRequest{Host: "B.TEST"})
+	require.NoError(t, err)
+	require.Len(t, page.Targets, 1)
+
+	//other projects see nothing
+	other := metadata.AppendToOutgoingContext(ctx, "x-project", "p_other")
+	page, err = e.client.ListTargets(other, &linkwatchpb.ListTargetsRequest{})
+	require.NoError(t, err)
+	require.Empty(t, page.Targets)
+
+	_, err = e.client.ListTargets(ctx, &linkwatchpb.ListTargetsRequest{PageSize: 101})
+	requireCode(t, err, codes.InvalidArgument)
+	_, err = e.client.ListTargets(ctx, &linkwatchpb.ListTargetsRequest{PageToken: "garbage"})
+	requireCode(t, err, codes.InvalidArgument)
+	_, err = e.client.ListTargets(ctx, &linkwatchpb.ListTargetsRequest{Host: "https://a.test"})
+	requireCode(t, err, codes.InvalidArgument)
+}
+
+func TestListResults(t *testing.T) {
+	e := newEnv(t, true)
+	ctx := context.Background()
+	tg, _, err := e.st.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), "https://a.test/", "a.test")
+	require.NoError(t, err)
+	base := time.Now().UTC().Truncate(time.Second)
+	for i := range 3 {
+		code := 200 + i
+		require.NoError(t, e.st.AppendCheckResult(ctx, store.CheckResult{TargetID: tg.ID, CheckedAt: base.Add(time.Duration(i) * time.Minute), StatusCode: &code}))
+	}
+	msg := "timeout"
+	require.NoError(t, e.st.AppendCheckResult(ctx, store.CheckResult{TargetID: tg.ID, CheckedAt: base.Add(time.Hour), Error: &msg,
+		Details:  json.RawMessage(`{"type":"A","answers":["192.0.2.1"]}`),
+		Attempts: []store.Attempt{{Attempt: 1, Error: &msg}, {Attempt: 2, Error: &msg}}}))
+
+	resp, err := e.client.ListResults(ctx, &linkwatchpb.ListResultsRequest{TargetId: tg.ID, PageSize: 3})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 3)
+	require.Nil(t, resp.Results[0].StatusCode, "newest first")
+	require.Equal(t, "timeout", resp.Results[0].GetError())
+	require.Equal(t, "A", resp.Results[0].GetDetails().AsMap()["type"])
+	require.Len(t, resp.Results[0].Attempts, 2)
+	require.Equal(t, int32(2), resp.Results[0].Attempts[1].Attempt)
+	require.Nil(t, resp.Results[1].Details)
+	require.Equal(t, int32(202), resp.Results[1].GetStatusCode())
+
+	resp, err = e.client.ListResults(ctx, &linkwatchpb.ListResultsRequest{TargetId: tg.ID, Since: timestamppb.New(base.Add(90 * time.Second))})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 2)
+
+	_, err = e.client.ListResults(ctx, &linkwatchpb.ListResultsRequest{})
+	requireCode(t, err, codes.InvalidArgument)
+	_, err = e.client.ListResults(ctx, &linkwatchpb.ListResultsRequest{TargetId: "t_missing"})
+	requireCode(t, err, codes.NotFound)
+	_, err = e.client.ListResults(metadata.AppendToOutgoingContext(ctx, "x-project", "p_other"), &linkwatchpb.ListResultsRequest{TargetId: tg.ID})
+	requireCode(t, err, codes.NotFound)
+}
+
+func TestWatchResults(t *testing.T) {
+	e := newEnv(t, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	tg, _, err := e.st.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), "https://a.test/", "a.test")
+	require.NoError(t, err)
+
+	code := 200
+	result := func(target string) events.Event {
+		return events.Event{Type: events.TypeResult, ProjectID: store.DefaultProjectID, TargetID: target, Host: "a.test",
+			Result: &store.CheckResult{TargetID: target, CheckedAt: time.Now(), StatusCode: &code}}
+	}
+	//retained before the watch starts: only reachable through last_event_id
+	e.broker.Publish(result(tg.ID))
+
+	w, err := e.client.WatchResults(ctx, &linkwatchpb.WatchResultsRequest{TargetId: tg.ID, LastEventId: "1"})
+	require.NoError(t, err)
+	got, err := w.Recv()
+	require.NoError(t, err)
+	require.Equal(t, tg.ID, got.Result.TargetId)
+	require.Equal(t, int32(200), got.Result.GetStatusCode())
+
+	e.broker.Publish(result("t_other"))
+	e.broker.Publish(events.TargetEvent(events.Updated, tg))
+	e.broker.Publish(result(tg.ID))
+	next, err := w.Recv()
+	require.NoError(t, err)
+	require.Equal(t, tg.ID, next.Result.TargetId, "other targets and non-result events are skipped")
+	require.NotEqual(t, got.EventId, next.EventId)
+
+	e.srv.CloseStreams()
+	_, err = w.Recv()
+	requireCode(t, err, codes.Unavailable)
+
+	w, err = e.client.WatchResults(ctx, &linkwatchpb.WatchResultsRequest{TargetId: "t_missing"})
+	require.NoError(t, err)
+	_, err = w.Recv()
+	requireCode(t, err, codes.NotFound)
+}
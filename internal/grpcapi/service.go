@@ -0,0 +1,264 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/internal/targets"
+	"github.com/nurzh/linkwatch/pkg/linkwatchpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// page_size 0 is def, otherwise it must be in [1, max] like the REST limit
+func pageSize(n int32, def, max int) (int, error) {
+	if n == 0 {
+		return def, nil
+	}
+	if n < 1 || int(n) > max {
+		return 0, invalid("page_size", "must be between 1 and "+strconv.Itoa(max)+", got "+strconv.Itoa(int(n)))
+	}
+	return int(n), nil
+}
+
+func (s *Server) ListTargets(ctx context.Context, req *linkwatchpb.ListTargetsRequest) (*linkwatchpb.ListTargetsResponse, error) {
+	if err := s.needStore(); err != nil {
+		return nil, err
+	}
+	var host *string
+	if req.GetHost() != "" {
+		h, err := api.ParseHost(req.GetHost())
+		if err != nil {
+			return nil, invalid("host", err.Error())
+		}
+		host = &h
+	}
+	limit, err := pageSize(req.GetPageSize(), 20, 100)
+	if err != nil {
+		return nil, err
+	}
+	var after *api.Cursor
+	if req.GetPageToken() != "" {
+		c, err := api.DecodeCursor(req.GetPageToken())
+		if err != nil {
+			return nil, invalid("page_token", "malformed token, pass next_page_token back unchanged")
+		}
+		after = &c
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	items, next, err := s.store.ListTargets(ctx, auth.ProjectID(ctx), host, after, limit)
+	if err != nil {
+		return nil, toStatus("ListTargets", err, "")
+	}
+	resp := &linkwatchpb.ListTargetsResponse{}
+	for _, t := range items {
+		resp.Targets = append(resp.Targets, targetPB(t))
+	}
+	if next != nil {
+		resp.NextPageToken = api.EncodeCursor(*next)
+	}
+	return resp, nil
+}
+
+// same rules as POST /v1/targets; idempotency keys are shared with it
+func (s *Server) CreateTarget(ctx context.Context, req *linkwatchpb.CreateTargetRequest) (*linkwatchpb.CreateTargetResponse, error) {
+	if err := s.needStore(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	t, created, err := targets.Create(ctx, s.store, s.checker.EgressPolicy(), auth.ProjectID(ctx), req.GetUrl(), req.GetIdempotencyKey())
+	if err != nil {
+		return nil, toStatus("CreateTarget", err, "unknown project")
+	}
+	if created && s.events != nil {
+		s.events.Publish(events.TargetEvent(events.Created, t))
+	}
+	return &linkwatchpb.CreateTargetResponse{Target: targetPB(t), Created: created}, nil
+}
+
+func (s *Server) ListResults(ctx context.Context, req *linkwatchpb.ListResultsRequest) (*linkwatchpb.ListResultsResponse, error) {
+	if err := s.needStore(); err != nil {
+		return nil, err
+	}
+	if req.GetTargetId() == "" {
+		return nil, invalid("target_id", "is required")
+	}
+	limit, err := pageSize(req.GetPageSize(), 50, 200)
+	if err != nil {
+		return nil, err
+	}
+	var since *time.Time
+	if req.GetSince() != nil {
+		if err := req.GetSince().CheckValid(); err != nil {
+			return nil, invalid("since", err.Error())
+		}
+		t := req.GetSince().AsTime()
+		since = &t
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if _, err := targets.Get(ctx, s.store, auth.ProjectID(ctx), req.GetTargetId()); err != nil {
+		return nil, toStatus("ListResults", err, "target not found")
+	}
+	items, err := s.store.ListResults(ctx, req.GetTargetId(), since, limit)
+	if err != nil {
+		return nil, toStatus("ListResults", err, "")
+	}
+	resp := &linkwatchpb.ListResultsResponse{}
+	for _, r := range items {
+		resp.Results = append(resp.Results, resultPB(r))
+	}
+	return resp, nil
+}
+
+// streams result events from the broker, like GET /v1/stream without the
+// state and target events
+func (s *Server) WatchResults(req *linkwatchpb.WatchResultsRequest, stream linkwatchpb.LinkwatchService_WatchResultsServer) error {
+	if s.events == nil {
+		return status.Error(codes.Unavailable, "live events are not enabled")
+	}
+	ctx := stream.Context()
+	f := events.Filter{ProjectID: auth.ProjectID(ctx), TargetID: req.GetTargetId(), Labels: req.GetLabels(), Types: []string{events.TypeResult}}
+	if req.GetHost() != "" {
+		h, err := api.ParseHost(req.GetHost())
+		if err != nil {
+			return invalid("host", err.Error())
+		}
+		f.Host = h
+	}
+	if _, err := strconv.ParseUint(req.GetLastEventId(), 10, 64); req.GetLastEventId() != "" && err != nil {
+		return invalid("last_event_id", "must be an event id")
+	}
+	if f.TargetID != "" {
+		if err := s.needStore(); err != nil {
+			return err
+		}
+		tctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		_, err := targets.Get(tctx, s.store, f.ProjectID, f.TargetID)
+		cancel()
+		if err != nil {
+			return toStatus("WatchResults", err, "target not found")
+		}
+	}
+
+	replay, sub, err := s.events.Subscribe(f, req.GetLastEventId())
+	if err != nil {
+		return toStatus("WatchResults", err, "")
+	}
+	defer sub.Close()
+	send := func(ev events.Event) error {
+		if ev.Result == nil {
+			return nil
+		}
+		return stream.Send(&linkwatchpb.WatchResultsResponse{EventId: ev.ID, Result: resultPB(*ev.Result)})
+	}
+	for _, ev := range replay {
+		if err := send(ev); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-s.closing:
+			return status.Error(codes.Unavailable, "server shutting down, resume with last_event_id")
+		case ev, ok := <-sub.C():
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "client too slow, resume with last_event_id")
+			}
+			if err := send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func targetPB(t store.Target) *linkwatchpb.Target {
+	t = t.Redacted()
+	pb := &linkwatchpb.Target{
+		Id:        t.ID,
+		ProjectId: t.ProjectID,
+		Url:       t.URL,
+		Host:      t.Host,
+		CreatedAt: timestamppb.New(t.CreatedAt),
+		Labels:    t.Labels,
+		Source:    t.Source,
+		Interval:  t.Settings.Interval,
+		Timeout:   t.Settings.Timeout,
+	}
+	if t.ArchivedAt != nil {
+		pb.ArchivedAt = timestamppb.New(*t.ArchivedAt)
+	}
+	if r := t.Settings.Retry; r != nil {
+		pb.Retry = &linkwatchpb.RetrySettings{
+			MaxAttempts: int32(r.MaxAttempts),
+			BaseBackoff: r.BaseBackoff,
+			MaxBackoff:  r.MaxBackoff,
+			Jitter:      r.Jitter,
+			Statuses:    r.Statuses,
+			Errors:      r.Errors,
+			RetryAfter:  r.RetryAfter,
+		}
+	}
+	if tr := t.Settings.Transport; tr != nil {
+		pb.Transport = &linkwatchpb.TransportSettings{
+			Proxy:              tr.Proxy,
+			CaFile:             tr.CAFile,
+			CertFile:           tr.CertFile,
+			KeyFile:            tr.KeyFile,
+			TlsMinVersion:      tr.TLSMinVersion,
+			ServerName:         tr.ServerName,
+			InsecureSkipVerify: tr.InsecureSkipVerify,
+			CertSecret:         tr.CertSecret,
+			KeySecret:          tr.KeySecret,
+		}
+	}
+	pb.Headers = t.Settings.Headers
+	if b := t.Settings.BasicAuth; b != nil {
+		pb.BasicAuth = &linkwatchpb.BasicAuth{Username: b.Username, Password: b.Password}
+	}
+	return pb
+}
+
+func resultPB(r store.CheckResult) *linkwatchpb.CheckResult {
+	pb := &linkwatchpb.CheckResult{TargetId: r.TargetID, CheckedAt: timestamppb.New(r.CheckedAt), Error: r.Error}
+	if r.StatusCode != nil {
+		n := int32(*r.StatusCode)
+		pb.StatusCode = &n
+	}
+	if r.LatencyMS != nil {
+		n := int32(*r.LatencyMS)
+		pb.LatencyMs = &n
+	}
+	var details map[string]any
+	if json.Unmarshal(r.Details, &details) == nil {
+		pb.Details, _ = structpb.NewStruct(details)
+	}
+	for _, a := range r.Attempts {
+		apb := &linkwatchpb.Attempt{Attempt: int32(a.Attempt), Error: a.Error}
+		if a.StatusCode != nil {
+			n := int32(*a.StatusCode)
+			apb.StatusCode = &n
+		}
+		if a.LatencyMS != nil {
+			n := int32(*a.LatencyMS)
+			apb.LatencyMs = &n
+		}
+		pb.Attempts = append(pb.Attempts, apb)
+	}
+	return pb
+}
@@ -0,0 +1,80 @@
+// Package headers holds what HTTP checks of a target send besides the request
+// itself: extra headers and basic auth. Values may reference secrets as
+// ${secret:name} (see internal/secrets); config and store share the shapes.
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/nurzh/linkwatch/internal/secrets"
+)
+
+type BasicAuth struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"` // normally ${secret:name}
+}
+
+func (b BasicAuth) Validate() error {
+	if b.Username == "" {
+		return errors.New("username: is required")
+	}
+	if err := secrets.ValidateRefs(b.Username); err != nil {
+		return fmt.Errorf("username: %w", err)
+	}
+	if err := secrets.ValidateRefs(b.Password); err != nil {
+		return fmt.Errorf("password: %w", err)
+	}
+	return nil
+}
+
+// Redacted hides a literal password; a reference stays
+func (b BasicAuth) Redacted() BasicAuth {
+	if b.Password != "" && len(secrets.Refs(b.Password)) == 0 {
+		b.Password = secrets.Redacted
+	}
+	return b
+}
+
+// Validate checks header names and the secrets values reference
+func Validate(h map[string]string) error {
+	for k, v := range h {
+		if k == "" || strings.ContainsFunc(k, func(c rune) bool {
+			return c <= ' ' || c >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c)
+		}) {
+			return fmt.Errorf("invalid header name %q", k)
+		}
+		if strings.EqualFold(k, "Host") {
+			return errors.New("Host cannot be set, use transport.server_name or the URL")
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("%s: must not contain line breaks", k)
+		}
+		if err := secrets.ValidateRefs(v); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// headers whose literal values Redacted hides
+var sensitive = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "X-Auth-Token"}
+
+// Redacted copies h for API responses: references stay, literal values of
+// credential headers are replaced
+func Redacted(h map[string]string) map[string]string {
+	if len(h) == 0 {
+		return h
+	}
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		if len(secrets.Refs(v)) == 0 && slices.Contains(sensitive, http.CanonicalHeaderKey(k)) {
+			v = secrets.Redacted
+		}
+		out[k] = v
+	}
+	return out
+}
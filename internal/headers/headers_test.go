@@ -0,0 +1,33 @@
+package headers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(map[string]string{"Authorization": "Bearer ${secret:api-token}", "X-Env": "prod"}))
+	for h, want := range map[string]string{
+		"Bad Name": "invalid header name",
+		"X:Y":      "invalid header name",
+		"host":     "Host",
+	} {
+		require.ErrorContains(t, Validate(map[string]string{h: "x"}), want)
+	}
+	require.ErrorContains(t, Validate(map[string]string{"X": "a\r\nB: c"}), "line breaks")
+	require.ErrorContains(t, Validate(map[string]string{"X": "${secret:a b}"}), "X: invalid secret name")
+
+	require.NoError(t, BasicAuth{Username: "svc", Password: "${secret:pw}"}.Validate())
+	require.ErrorContains(t, BasicAuth{Password: "x"}.Validate(), "username")
+	require.ErrorContains(t, BasicAuth{Username: "svc", Password: "${secret:}"}.Validate(), "password")
+}
+
+func TestRedacted(t *testing.T) {
+	h := map[string]string{"authorization": "Bearer abc", "X-Token": "${secret:t}", "Cookie": "sid=${secret:sid}", "X-Env": "prod"}
+	require.Equal(t, map[string]string{"authorization": "[redacted]", "X-Token": "${secret:t}", "Cookie": "sid=${secret:sid}", "X-Env": "prod"}, Redacted(h))
+	require.Equal(t, "Bearer abc", h["authorization"], "the original is unchanged")
+
+	require.Equal(t, BasicAuth{Username: "svc", Password: "[redacted]"}, BasicAuth{Username: "svc", Password: "hunter2"}.Redacted())
+	require.Equal(t, BasicAuth{Username: "svc", Password: "${secret:pw}"}, BasicAuth{Username: "svc", Password: "${secret:pw}"}.Redacted())
+}
@@ -0,0 +1,226 @@
+// Package hostlimit paces checks per host: a rate with a burst for every host
+// (a default plus overrides) and, optionally, the Crawl-delay of the host's
+// robots.txt. It only hands out start times; the checker decides what to do
+// until then.
+package hostlimit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// a robots.txt Crawl-delay above this is capped, so one file cannot stop checks
+const MaxCrawlDelay = time.Minute
+
+// how long a fetched robots.txt is trusted
+const RobotsTTL = time.Hour
+
+// Rate of requests to one host; RPS 0 is unlimited
+type Rate struct {
+	RPS   float64 `json:"rps" yaml:"rps"`                         // sustained requests per second
+	Burst int     `json:"burst,omitempty" yaml:"burst,omitempty"` // requests allowed back to back, 0 = 1
+}
+
+// Config is the config file shape ("host_limits:")
+type Config struct {
+	Default Rate `json:"default" yaml:"default"`
+	// by target host ("example.org", "example.org:8443"); "*.example.org" covers subdomains
+	Hosts            map[string]Rate `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	RobotsCrawlDelay bool            `json:"robots_crawl_delay" yaml:"robots_crawl_delay"`
+}
+
+func (c Config) Validate() error {
+	check := func(name string, r Rate) error {
+		if r.RPS < 0 || math.IsInf(r.RPS, 0) || math.IsNaN(r.RPS) {
+			return fmt.Errorf("%s.rps: must not be negative, got %g", name, r.RPS)
+		}
+		if r.Burst < 0 {
+			return fmt.Errorf("%s.burst: must not be negative, got %d", name, r.Burst)
+		}
+		return nil
+	}
+	if err := check("default", c.Default); err != nil {
+		return err
+	}
+	for h, r := range c.Hosts {
+		if h == "" || h != strings.ToLower(h) || strings.Contains(h, "/") {
+			return fmt.Errorf("hosts: want a lower-case host[:port] or *.domain, got %q", h)
+		}
+		if err := check("hosts."+h, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exact host, then the host without port, then the longest *.domain
+func (c Config) rateFor(host string) Rate {
+	if r, ok := c.Hosts[host]; ok {
+		return r
+	}
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+		if r, ok := c.Hosts[name]; ok {
+			return r
+		}
+	}
+	for i := strings.IndexByte(name, '.'); i >= 0; i = strings.IndexByte(name, '.') {
+		name = name[i+1:]
+		if r, ok := c.Hosts["*."+name]; ok {
+			return r
+		}
+	}
+	return c.Default
+}
+
+type Limiter struct {
+	cfg Config
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	tat        time.Time     // theoretical arrival time of the rate (GCRA)
+	lastSlot   time.Time     // start handed out last, for the crawl delay
+	crawlDelay time.Duration // from robots.txt
+	robotsAt   time.Time     // last fetch started, zero = never
+}
+
+func New(cfg Config) *Limiter {
+	return &Limiter{cfg: cfg, hosts: map[string]*hostState{}}
+}
+
+func (l *Limiter) Config() Config { return l.cfg }
+
+func (l *Limiter) state(host string) *hostState {
+	st, ok := l.hosts[host]
+	if !ok {
+		st = &hostState{}
+		l.hosts[host] = st
+	}
+	return st
+}
+
+// Reserve books the next start for a request to host and returns how long
+// after now it is; 0 means go ahead. Every call books its own slot, so
+// callers must not call it again for the same request
+func (l *Limiter) Reserve(host string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st := l.state(host)
+	r := l.cfg.rateFor(host)
+	var interval time.Duration
+	slot := now
+	if r.RPS > 0 {
+		interval = time.Duration(float64(time.Second) / r.RPS)
+		tolerance := time.Duration(max(r.Burst, 1)-1) * interval
+		st.tat = maxTime(st.tat, now)
+		slot = maxTime(slot, st.tat.Add(-tolerance))
+	}
+	if st.crawlDelay > 0 && !st.lastSlot.IsZero() {
+		slot = maxTime(slot, st.lastSlot.Add(st.crawlDelay))
+	}
+	if interval > 0 {
+		st.tat = maxTime(st.tat, slot).Add(interval)
+	}
+	st.lastSlot = slot
+	return slot.Sub(now)
+}
+
+// WantsRobots reports whether host's robots.txt should be fetched now: true
+// at most once per RobotsTTL, so only one caller fetches it
+func (l *Limiter) WantsRobots(host string, now time.Time) bool {
+	if !l.cfg.RobotsCrawlDelay {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st := l.state(host)
+	if !st.robotsAt.IsZero() && now.Sub(st.robotsAt) < RobotsTTL {
+		return false
+	}
+	st.robotsAt = now
+	return true
+}
+
+// SetCrawlDelay records the Crawl-delay of host's robots.txt, 0 if none
+func (l *Limiter) SetCrawlDelay(host string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.state(host).crawlDelay = min(max(d, 0), MaxCrawlDelay)
+}
+
+func (l *Limiter) CrawlDelay(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if st, ok := l.hosts[host]; ok {
+		return st.crawlDelay
+	}
+	return 0
+}
+
+// CrawlDelay reads robots.txt: the Crawl-delay of the group naming agent,
+// else that of the "*" group, else 0
+func CrawlDelay(robots []byte, agent string) time.Duration {
+	agent = strings.ToLower(agent)
+	var (
+		own, star       time.Duration
+		hasOwn, hasStar bool
+		inAgents        bool // consecutive User-agent lines form one group
+		forOwn, forStar bool
+	)
+	sc := bufio.NewScanner(bytes.NewReader(robots))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, val = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(val)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				forOwn, forStar = false, false
+			}
+			inAgents = true
+			ua := strings.ToLower(val)
+			forOwn = forOwn || (ua != "*" && ua != "" && strings.Contains(agent, ua))
+			forStar = forStar || ua == "*"
+		case "crawl-delay":
+			inAgents = false
+			secs, err := strconv.ParseFloat(val, 64)
+			if err != nil || !(secs >= 0) || math.IsInf(secs, 0) {
+				continue
+			}
+			d := time.Duration(min(secs, MaxCrawlDelay.Seconds()) * float64(time.Second))
+			if forOwn && !hasOwn {
+				own, hasOwn = d, true
+			}
+			if forStar && !hasStar {
+				star, hasStar = d, true
+			}
+		default:
+			inAgents = false
+		}
+	}
+	if hasOwn {
+		return own
+	}
+	return star
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
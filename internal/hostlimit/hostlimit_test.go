@@ -0,0 +1,113 @@
+package hostlimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReserveBurstAndRate(t *testing.T) {
+	l := New(Config{Default: Rate{RPS: 2, Burst: 3}})
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for range 3 {
+		require.Zero(t, l.Reserve("a.test", now), "within the burst")
+	}
+	require.Equal(t, 500*time.Millisecond, l.Reserve("a.test", now))
+	require.Equal(t, time.Second, l.Reserve("a.test", now), "every call books its own slot")
+	require.Zero(t, l.Reserve("b.test", now), "hosts are paced apart")
+
+	//idle time refills the burst
+	later := now.Add(time.Minute)
+	for range 3 {
+		require.Zero(t, l.Reserve("a.test", later))
+	}
+	require.Positive(t, l.Reserve("a.test", later))
+}
+
+func TestReserveUnlimited(t *testing.T) {
+	l := New(Config{})
+	now := time.Now()
+	for range 100 {
+		require.Zero(t, l.Reserve("a.test", now))
+	}
+}
+
+func TestReserveCrawlDelay(t *testing.T) {
+	l := New(Config{RobotsCrawlDelay: true})
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.True(t, l.WantsRobots("a.test", now))
+	require.False(t, l.WantsRobots("a.test", now.Add(time.Minute)), "one fetch per TTL")
+	require.True(t, l.WantsRobots("a.test", now.Add(RobotsTTL)))
+
+	l.SetCrawlDelay("a.test", 2*time.Second)
+	require.Equal(t, 2*time.Second, l.CrawlDelay("a.test"))
+	require.Zero(t, l.Reserve("a.test", now))
+	require.Equal(t, 2*time.Second, l.Reserve("a.test", now))
+	require.Equal(t, 4*time.Second, l.Reserve("a.test", now))
+	require.Zero(t, l.Reserve("a.test", now.Add(time.Minute)))
+
+	l.SetCrawlDelay("a.test", time.Hour)
+	require.Equal(t, MaxCrawlDelay, l.CrawlDelay("a.test"), "capped")
+
+	require.False(t, New(Config{}).WantsRobots("a.test", now), "off by default")
+}
+
+func TestRateFor(t *testing.T) {
+	cfg := Config{
+		Default: Rate{RPS: 1},
+		Hosts: map[string]Rate{
+			"a.test":        {RPS: 2},
+			"a.test:8443":   {RPS: 3},
+			"*.b.test":      {RPS: 4},
+			"*.deep.b.test": {RPS: 5},
+		},
+	}
+	for host, want := range map[string]float64{
+		"a.test":        2,
+		"a.test:8443":   3,
+		"a.test:9000":   2,
+		"x.b.test":      4,
+		"x.deep.b.test": 5,
+		"b.test":        1,
+		"c.test":        1,
+		"x.b.test:8080": 4,
+	} {
+		require.Equal(t, want, cfg.rateFor(host).RPS, host)
+	}
+}
+
+func TestCrawlDelay(t *testing.T) {
+	robots := []byte(`# comment
+User-agent: *
+Crawl-delay: 10
+
+User-agent: googlebot
+User-agent: linkwatch
+Disallow: /private
+Crawl-delay: 1.5 # seconds
+`)
+	require.Equal(t, 1500*time.Millisecond, CrawlDelay(robots, "linkwatch"))
+	require.Equal(t, 1500*time.Millisecond, CrawlDelay(robots, "Linkwatch/1.0"))
+	require.Equal(t, 10*time.Second, CrawlDelay(robots, "otherbot"))
+	require.Equal(t, MaxCrawlDelay, CrawlDelay([]byte("User-agent: *\nCrawl-delay: 86400\n"), "linkwatch"))
+	require.Zero(t, CrawlDelay([]byte("User-agent: *\nCrawl-delay: soon\n"), "linkwatch"))
+	require.Zero(t, CrawlDelay([]byte("User-agent: *\nCrawl-delay: NaN\n"), "linkwatch"))
+	require.Zero(t, CrawlDelay([]byte("User-agent: other\nCrawl-delay: 5\n"), "linkwatch"))
+	require.Zero(t, CrawlDelay(nil, "linkwatch"))
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Config{}.Validate())
+	require.NoError(t, Config{Default: Rate{RPS: 0.5, Burst: 2}, Hosts: map[string]Rate{"*.a.test": {RPS: 1}}}.Validate())
+	for cfg, want := range map[*Config]string{
+		{Default: Rate{RPS: -1}}:                       "default.rps",
+		{Default: Rate{Burst: -1}}:                     "default.burst",
+		{Hosts: map[string]Rate{"A.test": {RPS: 1}}}:   "hosts",
+		{Hosts: map[string]Rate{"a.test/x": {RPS: 1}}}: "hosts",
+		{Hosts: map[string]Rate{"a.test": {RPS: -2}}}:  "hosts.a.test.rps",
+	} {
+		require.ErrorContains(t, cfg.Validate(), want)
+	}
+}
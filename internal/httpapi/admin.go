@@ -0,0 +1,256 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type createAPIKeyReq struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ProjectID string   `json:"project_id,omitempty"` // other projects need projects:admin
+}
+
+// plaintext key is only ever returned here
+type createAPIKeyResp struct {
+	store.APIKey
+	Key string `json:"key"`
+}
+
+func invalidProject(w http.ResponseWriter, r *http.Request, err error) {
+	var fe *store.FieldError
+	if errors.As(err, &fe) {
+		api.Invalid(w, r, api.FieldError{Field: fe.Field, Message: fe.Message})
+		return
+	}
+	api.Invalid(w, r, api.FieldError{Field: "body", Message: err.Error()})
+}
+
+// project an admin request acts on: its own, or any other with projects:admin
+func adminProject(r *http.Request, requested string) (string, bool) {
+	own := auth.ProjectID(r.Context())
+	if requested == "" || requested == own {
+		return own, true
+	}
+	return requested, auth.HasScope(r.Context(), auth.ScopeProjectsAdmin)
+}
+
+// keys go to the caller's project unless project_id names another one
+func (s *Server) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !s.needStore(w, r) {
+		return
+	}
+	var body createAPIKeyReq
+	if !api.DecodeJSON(w, r, &body) {
+		return
+	}
+	project, ok := adminProject(r, body.ProjectID)
+	if !ok {
+		api.WriteProblem(w, r, api.Problem{Type: api.TypeForbidden, Status: http.StatusForbidden,
+			Detail: "creating keys for another project requires " + auth.ScopeProjectsAdmin, RequiredScope: auth.ScopeProjectsAdmin})
+		return
+	}
+	if slices.Contains(body.Scopes, auth.ScopeProjectsAdmin) && !auth.HasScope(r.Context(), auth.ScopeProjectsAdmin) {
+		api.WriteProblem(w, r, api.Problem{Type: api.TypeForbidden, Status: http.StatusForbidden,
+			Detail: "only " + auth.ScopeProjectsAdmin + " keys can grant " + auth.ScopeProjectsAdmin, RequiredScope: auth.ScopeProjectsAdmin})
+		return
+	}
+	if strings.TrimSpace(body.Name) == "" {
+		api.Invalid(w, r, api.FieldError{Field: "name", Message: "is required"})
+		return
+	}
+	if err := auth.ValidateScopes(body.Scopes); err != nil {
+		api.Invalid(w, r, api.FieldError{Field: "scopes", Message: err.Error()})
+		return
+	}
+	key, k, err := auth.NewKey(project, body.Name, body.Scopes)
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	if _, err := s.store.GetProject(ctx, project); errors.Is(err, store.ErrNotFound) {
+		api.Error(w, r, http.StatusNotFound, "project not found")
+		return
+	}
+	if err := s.store.CreateAPIKey(ctx, k); err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, createAPIKeyResp{APIKey: k, Key: key})
+}
+
+// keys of the caller's project
+func (s *Server) listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if !s.needStore(w, r) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	items, err := s.store.ListAPIKeys(ctx, auth.ProjectID(r.Context()))
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// revoke a key of the caller's project (any project with projects:admin)
+func (s *Server) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !s.needStore(w, r) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	id := chi.URLParam(r, "id")
+	scope := auth.ProjectID(r.Context())
+	if auth.HasScope(r.Context(), auth.ScopeProjectsAdmin) {
+		scope = ""
+	}
+	keys, err := s.store.ListAPIKeys(ctx, scope)
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	if !slices.ContainsFunc(keys, func(k store.APIKey) bool { return k.ID == id }) {
+		api.Error(w, r, http.StatusNotFound, "api key not found or already revoked")
+		return
+	}
+	err = s.store.RevokeAPIKey(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		api.Error(w, r, http.StatusNotFound, "api key not found or already revoked")
+		return
+	}
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type createProjectReq struct {
+	Name                    string `json:"name"`
+	MaxTargets              *int   `json:"max_targets"`
+	MinCheckIntervalSeconds *int   `json:"min_check_interval_seconds"`
+}
+
+// PATCH body: absent fields are kept, null clears a quota
+type patchProjectReq struct {
+	Name                    *string `json:"name"`
+	MaxTargets              optInt  `json:"max_targets"`
+	MinCheckIntervalSeconds optInt  `json:"min_check_interval_seconds"`
+}
+
+type optInt struct {
+	Set bool
+	V   *int
+}
+
+func (o *optInt) UnmarshalJSON(b []byte) error {
+	o.Set = true
+	return json.Unmarshal(b, &o.V)
+}
+
+func (s *Server) createProject(w http.ResponseWriter, r *http.Request) {
+	if !s.needStore(w, r) {
+		return
+	}
+	var body createProjectReq
+	if !api.DecodeJSON(w, r, &body) {
+		return
+	}
+	p := store.Project{
+		ID: core.NewID("p"), Name: body.Name, CreatedAt: time.Now().UTC(),
+		MaxTargets: body.MaxTargets, MinIntervalSeconds: body.MinCheckIntervalSeconds,
+	}
+	if err := p.Validate(); err != nil {
+		invalidProject(w, r, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	if _, err := s.store.GetProjectByName(ctx, p.Name); err == nil {
+		api.Error(w, r, http.StatusConflict, "project name already taken")
+		return
+	}
+	if err := s.store.CreateProject(ctx, p); err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, p)
+}
+
+func (s *Server) listProjects(w http.ResponseWriter, r *http.Request) {
+	if !s.needStore(w, r) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	items, err := s.store.ListProjects(ctx)
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (s *Server) updateProject(w http.ResponseWriter, r *http.Request) {
+	if !s.needStore(w, r) {
+		return
+	}
+	var body patchProjectReq
+	if !api.DecodeJSON(w, r, &body) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	p, err := s.store.GetProject(ctx, chi.URLParam(r, "id"))
+	if errors.Is(err, store.ErrNotFound) {
+		api.Error(w, r, http.StatusNotFound, "project not found")
+		return
+	}
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	if body.Name != nil && *body.Name != p.Name {
+		if _, err := s.store.GetProjectByName(ctx, *body.Name); err == nil {
+			api.Error(w, r, http.StatusConflict, "project name already taken")
+			return
+		}
+		p.Name = *body.Name
+	}
+	if body.MaxTargets.Set {
+		p.MaxTargets = body.MaxTargets.V
+	}
+	if body.MinCheckIntervalSeconds.Set {
+		p.MinIntervalSeconds = body.MinCheckIntervalSeconds.V
+	}
+	if err := p.Validate(); err != nil {
+		invalidProject(w, r, err)
+		return
+	}
+	if err := s.store.UpdateProject(ctx, p); err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// circuit state of the hosts that failed recently; in memory, per replica
+func (s *Server) listBreakers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"items": s.checker.Breakers()})
+}
@@ -0,0 +1,160 @@
+package httpapi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/breaker"
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/retry"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+type keyList struct {
+	Items []store.APIKey `json:"items"`
+}
+
+func TestAPIKeys(t *testing.T) {
+	e := newEnv(t)
+
+	rec := e.do(http.MethodPost, "/v1/admin/api-keys", `{"name":"ci","scopes":["targets:read"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	created := decode[createAPIKeyResp](t, rec)
+	require.NotEmpty(t, created.Key)
+	require.Equal(t, store.DefaultProjectID, created.ProjectID)
+
+	rec = e.do(http.MethodGet, "/v1/admin/api-keys", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	keys := decode[keyList](t, rec).Items
+	require.Len(t, keys, 1)
+	require.Equal(t, created.ID, keys[0].ID)
+	require.NotContains(t, rec.Body.String(), created.Key, "secrets are never listed")
+
+	cases := []struct {
+		name, body string
+		field      string
+	}{
+		{"no name", `{"scopes":["admin"]}`, "name"},
+		{"no scopes", `{"name":"x","scopes":[]}`, "scopes"},
+		{"unknown scope", `{"name":"x","scopes":["root"]}`, "scopes"},
+		{"unknown field", `{"name":"x","scopes":["admin"],"expires":"1d"}`, "expires"},
+	}
+	for _, tc := range cases {
+		p := requireProblem(t, e.do(http.MethodPost, "/v1/admin/api-keys", tc.body), http.StatusBadRequest, api.TypeValidation)
+		require.Equal(t, tc.field, p.Errors[0].Field, tc.name)
+	}
+	requireProblem(t, e.do(http.MethodPost, "/v1/admin/api-keys", `{"name":"x","scopes":["admin"],"project_id":"p_missing"}`),
+		http.StatusNotFound, "about:blank")
+
+	//another project cannot see or revoke the key
+	team := e.project("team").ID
+	rec = e.do(http.MethodGet, "/v1/admin/api-keys", nil, "X-Project", team)
+	require.Empty(t, decode[keyList](t, rec).Items)
+
+	require.Equal(t, http.StatusNoContent, e.do(http.MethodDelete, "/v1/admin/api-keys/"+created.ID, nil).Code)
+	requireProblem(t, e.do(http.MethodDelete, "/v1/admin/api-keys/"+created.ID, nil), http.StatusNotFound, "about:blank")
+}
+
+// a plain admin key manages its own project only
+func TestAPIKeysAdminScope(t *testing.T) {
+	st := testStore(t)
+	e := &testEnv{t: t, st: st, srv: New(st, checker.New(st, 1, time.Second, time.Minute), &auth.Authenticator{Store: st})}
+	admin, row, err := auth.NewKey(store.DefaultProjectID, "ops", []string{auth.ScopeAdmin})
+	require.NoError(t, err)
+	require.NoError(t, st.CreateAPIKey(context.Background(), row))
+	team := e.project("team").ID
+	bearer := []string{"Authorization", "Bearer " + admin}
+
+	p := requireProblem(t, e.do(http.MethodPost, "/v1/admin/api-keys", `{"name":"x","scopes":["admin"],"project_id":"`+team+`"}`, bearer...),
+		http.StatusForbidden, api.TypeForbidden)
+	require.Equal(t, auth.ScopeProjectsAdmin, p.RequiredScope)
+	requireProblem(t, e.do(http.MethodPost, "/v1/admin/api-keys", `{"name":"x","scopes":["projects:admin"]}`, bearer...),
+		http.StatusForbidden, api.TypeForbidden)
+	requireProblem(t, e.do(http.MethodGet, "/v1/admin/projects", nil, bearer...), http.StatusForbidden, api.TypeForbidden)
+	requireProblem(t, e.do(http.MethodGet, "/v1/admin/breakers", nil, bearer...), http.StatusForbidden, api.TypeForbidden)
+
+	rec := e.do(http.MethodPost, "/v1/admin/api-keys", `{"name":"ro","scopes":["targets:read"]}`, bearer...)
+	require.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestProjects(t *testing.T) {
+	e := newEnv(t)
+
+	rec := e.do(http.MethodPost, "/v1/admin/projects", `{"name":"web","max_targets":2}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	web := decode[store.Project](t, rec)
+	require.Equal(t, 2, *web.MaxTargets)
+	require.Nil(t, web.MinIntervalSeconds)
+
+	requireProblem(t, e.do(http.MethodPost, "/v1/admin/projects", `{"name":"web"}`), http.StatusConflict, "about:blank")
+	p := requireProblem(t, e.do(http.MethodPost, "/v1/admin/projects", `{"name":"x","max_targets":-1}`), http.StatusBadRequest, api.TypeValidation)
+	require.Equal(t, "max_targets", p.Errors[0].Field)
+	p = requireProblem(t, e.do(http.MethodPost, "/v1/admin/projects", `{"max_targets":1}`), http.StatusBadRequest, api.TypeValidation)
+	require.Equal(t, "name", p.Errors[0].Field)
+
+	rec = e.do(http.MethodGet, "/v1/admin/projects", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	list := decode[struct {
+		Items []store.Project `json:"items"`
+	}](t, rec)
+	require.Len(t, list.Items, 2) // default + web
+
+	//absent fields are kept, null removes a quota
+	rec = e.do(http.MethodPatch, "/v1/admin/projects/"+web.ID, `{"min_check_interval_seconds":60}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	web = decode[store.Project](t, rec)
+	require.Equal(t, 2, *web.MaxTargets)
+	require.Equal(t, 60, *web.MinIntervalSeconds)
+	rec = e.do(http.MethodPatch, "/v1/admin/projects/"+web.ID, `{"max_targets":null,"name":"www"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	web = decode[store.Project](t, rec)
+	require.Nil(t, web.MaxTargets)
+	require.Equal(t, "www", web.Name)
+
+	requireProblem(t, e.do(http.MethodPatch, "/v1/admin/projects/"+web.ID, `{"name":"default"}`), http.StatusConflict, "about:blank")
+	requireProblem(t, e.do(http.MethodPatch, "/v1/admin/projects/"+web.ID, `{"min_check_interval_seconds":0}`), http.StatusBadRequest, api.TypeValidation)
+	requireProblem(t, e.do(http.MethodPatch, "/v1/admin/projects/p_missing", `{}`), http.StatusNotFound, "about:blank")
+}
+
+func TestListBreakers(t *testing.T) {
+	st := testStore(t)
+	ctx := context.Background()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close() //refused from now on
+	_, _, err = st.CreateOrGetTarget(ctx, store.DefaultProjectID, core.NewID("t"), "http://"+addr+"/", addr)
+	require.NoError(t, err)
+
+	chk := checker.New(st, 1, time.Second, time.Minute, checker.WithEgressPolicy(netguard.AllowAll()),
+		checker.WithRetryPolicy(retry.Policy{MaxAttempts: 1}), checker.WithBreaker(breaker.Config{Threshold: 1, Cooldown: "1h"}))
+	e := &testEnv{t: t, st: st, srv: New(st, chk, &auth.Authenticator{Store: st, Disabled: true})}
+
+	rec := e.do(http.MethodGet, "/v1/admin/breakers", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"items":[]}`, rec.Body.String())
+
+	rctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() { chk.Start(rctx); close(done) }()
+	defer func() { cancel(); <-done }()
+	var items []breaker.HostState
+	require.Eventually(t, func() bool {
+		items = decode[struct {
+			Items []breaker.HostState `json:"items"`
+		}](t, e.do(http.MethodGet, "/v1/admin/breakers", nil)).Items
+		return len(items) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, addr, items[0].Host)
+	require.Equal(t, breaker.Open, items[0].State)
+	require.Equal(t, 1, items[0].Failures)
+	require.NotNil(t, items[0].ProbeAt)
+}
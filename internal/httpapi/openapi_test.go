@@ -0,0 +1,82 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/stretchr/testify/require"
+)
+
+type specDoc struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+func loadSpec(t *testing.T) specDoc {
+	t.Helper()
+	var doc specDoc
+	require.NoError(t, json.Unmarshal(api.OpenAPISpec, &doc))
+	require.True(t, strings.HasPrefix(doc.OpenAPI, "3.1"), "openapi version %q", doc.OpenAPI)
+	return doc
+}
+
+// every chi route has a spec operation and every spec operation a route
+func TestSpecCoversRoutes(t *testing.T) {
+	doc := loadSpec(t)
+	r := New(nil, checker.New(nil, 1, time.Second, time.Minute), &auth.Authenticator{Disabled: true}).Router()
+
+	routed := map[string]bool{}
+	err := chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.ReplaceAll(route, "/*/", "/") // chi's marker for inline groups
+		routed[strings.ToLower(method)+" "+route] = true
+		return nil
+	})
+	require.NoError(t, err)
+
+	var missing, stale []string
+	for op := range routed {
+		method, path, _ := strings.Cut(op, " ")
+		if _, ok := doc.Paths[path][method]; !ok {
+			missing = append(missing, op)
+		}
+	}
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method == "parameters" || method == "summary" || method == "description" {
+				continue
+			}
+			if !routed[method+" "+path] {
+				stale = append(stale, method+" "+path)
+			}
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	require.Empty(t, missing, "routes without an operation in internal/api/openapi.json")
+	require.Empty(t, stale, "spec operations without a route")
+}
+
+func TestSpecAndDocsServed(t *testing.T) {
+	r := New(nil, checker.New(nil, 1, time.Second, time.Minute), &auth.Authenticator{Disabled: true})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, string(api.OpenAPISpec), rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	require.Contains(t, rec.Body.String(), "openapi.json")
+}
@@ -0,0 +1,125 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/secrets"
+	"github.com/nurzh/linkwatch/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// the value is write-only: no response ever contains it
+type putSecretReq struct {
+	Value string `json:"value"`
+}
+
+// answers 503 and reports false without a store or secrets key
+func (s *Server) needSecrets(w http.ResponseWriter, r *http.Request) bool {
+	if !s.needStore(w, r) {
+		return false
+	}
+	if s.secrets == nil {
+		api.Error(w, r, http.StatusServiceUnavailable, "secrets key not configured (SECRETS_KEY or secrets_key_file)")
+		return false
+	}
+	return true
+}
+
+// creates or replaces a secret of the caller's project
+func (s *Server) putSecret(w http.ResponseWriter, r *http.Request) {
+	if !s.needSecrets(w, r) {
+		return
+	}
+	name := chi.URLParam(r, "name")
+	if err := secrets.ValidateName(name); err != nil {
+		api.Invalid(w, r, api.FieldError{Field: "name", Message: err.Error()})
+		return
+	}
+	var body putSecretReq
+	if !api.DecodeJSON(w, r, &body) {
+		return
+	}
+	if body.Value == "" {
+		api.Invalid(w, r, api.FieldError{Field: "value", Message: "is required"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	project := auth.ProjectID(r.Context())
+	now := time.Now().UTC().Truncate(time.Microsecond) // as stored
+	sec := store.Secret{ProjectID: project, Name: name, CreatedAt: now, UpdatedAt: now}
+	status := http.StatusCreated
+	old, err := s.store.GetSecret(ctx, project, name)
+	switch {
+	case err == nil:
+		sec.CreatedAt, status = old.CreatedAt, http.StatusOK
+	case !errors.Is(err, store.ErrNotFound):
+		api.Internal(w, r, err)
+		return
+	}
+	sec.Value = s.secrets.Seal(project, name, []byte(body.Value))
+	if err := s.store.PutSecret(ctx, sec); err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	writeJSON(w, status, sec)
+}
+
+// names and timestamps of the caller's project's secrets
+func (s *Server) listSecrets(w http.ResponseWriter, r *http.Request) {
+	if !s.needStore(w, r) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	items, err := s.store.ListSecrets(ctx, auth.ProjectID(r.Context()))
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// deletes a secret no active target references
+func (s *Server) deleteSecret(w http.ResponseWriter, r *http.Request) {
+	if !s.needStore(w, r) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	project, name := auth.ProjectID(r.Context()), chi.URLParam(r, "name")
+	//only declarative targets have settings
+	ts, err := s.store.ListTargetsBySource(ctx, store.SourceFile)
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	var users []string
+	for _, t := range ts {
+		if t.ProjectID == project && t.ArchivedAt == nil && slices.Contains(t.Settings.SecretRefs(), name) {
+			users = append(users, t.URL)
+		}
+	}
+	if len(users) > 0 {
+		api.Error(w, r, http.StatusConflict, "secret is used by targets: "+strings.Join(users, ", "))
+		return
+	}
+	err = s.store.DeleteSecret(ctx, project, name)
+	if errors.Is(err, store.ErrNotFound) {
+		api.Error(w, r, http.StatusNotFound, "secret not found")
+		return
+	}
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,101 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/headers"
+	"github.com/nurzh/linkwatch/internal/secrets"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecrets(t *testing.T) {
+	st := testStore(t)
+	box, err := secrets.ParseKey(secrets.NewKey())
+	require.NoError(t, err)
+	e := &testEnv{t: t, st: st, srv: New(st, checker.New(st, 1, time.Second, time.Minute), &auth.Authenticator{Store: st, Disabled: true}, WithSecrets(box))}
+	ctx := context.Background()
+
+	rec := e.do(http.MethodPut, "/v1/admin/secrets/api-token", `{"value":"tok-123"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.NotContains(t, rec.Body.String(), "tok-123")
+	created := decode[store.Secret](t, rec)
+	require.Equal(t, "api-token", created.Name)
+
+	//sealed at rest
+	row, err := st.GetSecret(ctx, store.DefaultProjectID, "api-token")
+	require.NoError(t, err)
+	require.NotContains(t, string(row.Value), "tok-123")
+	v, err := box.Open(store.DefaultProjectID, "api-token", row.Value)
+	require.NoError(t, err)
+	require.Equal(t, "tok-123", string(v))
+
+	rec = e.do(http.MethodPut, "/v1/admin/secrets/api-token", `{"value":"tok-456"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, created.CreatedAt, decode[store.Secret](t, rec).CreatedAt)
+
+	rec = e.do(http.MethodGet, "/v1/admin/secrets", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotContains(t, rec.Body.String(), "tok-")
+	require.Len(t, decode[struct{ Items []store.Secret }](t, rec).Items, 1)
+
+	p := requireProblem(t, e.do(http.MethodPut, "/v1/admin/secrets/a%20b", `{"value":"x"}`), http.StatusBadRequest, api.TypeValidation)
+	require.Equal(t, "name", p.Errors[0].Field)
+	p = requireProblem(t, e.do(http.MethodPut, "/v1/admin/secrets/x", `{"value":""}`), http.StatusBadRequest, api.TypeValidation)
+	require.Equal(t, "value", p.Errors[0].Field)
+
+	//a declarative target using it blocks the delete and shows only references
+	canon, host, err := core.Canonicalize("https://api.example.org/")
+	require.NoError(t, err)
+	tg := store.Target{ID: core.NewID("t"), ProjectID: store.DefaultProjectID, URL: canon, Host: host, CreatedAt: time.Now(), Source: store.SourceFile,
+		Settings: store.TargetSettings{
+			Headers:   map[string]string{"Authorization": "Bearer ${secret:api-token}"},
+			BasicAuth: &headers.BasicAuth{Username: "svc", Password: "pasted-in"},
+		}}
+	require.NoError(t, st.InsertTarget(ctx, tg))
+	requireProblem(t, e.do(http.MethodDelete, "/v1/admin/secrets/api-token", nil), http.StatusConflict, "about:blank")
+	rec = e.do(http.MethodGet, "/v1/targets", nil)
+	require.Contains(t, rec.Body.String(), `Bearer ${secret:api-token}`)
+	require.NotContains(t, rec.Body.String(), "pasted-in")
+	require.Contains(t, rec.Body.String(), secrets.Redacted)
+
+	now := time.Now()
+	tg.ArchivedAt = &now
+	require.NoError(t, st.UpdateTarget(ctx, tg))
+	require.Equal(t, http.StatusNoContent, e.do(http.MethodDelete, "/v1/admin/secrets/api-token", nil).Code)
+	requireProblem(t, e.do(http.MethodDelete, "/v1/admin/secrets/api-token", nil), http.StatusNotFound, "about:blank")
+
+	//writes need the key
+	e.srv = New(st, checker.New(st, 1, time.Second, time.Minute), &auth.Authenticator{Store: st, Disabled: true})
+	requireProblem(t, e.do(http.MethodPut, "/v1/admin/secrets/x", `{"value":"x"}`), http.StatusServiceUnavailable, "about:blank")
+}
+
+// secrets belong to the key's project and need admin
+func TestSecretsScope(t *testing.T) {
+	st := testStore(t)
+	box, err := secrets.ParseKey(secrets.NewKey())
+	require.NoError(t, err)
+	e := &testEnv{t: t, st: st, srv: New(st, checker.New(st, 1, time.Second, time.Minute), &auth.Authenticator{Store: st}, WithSecrets(box))}
+	ctx := context.Background()
+	team := e.project("team").ID
+	key := func(project string, scopes ...string) []string {
+		k, row, err := auth.NewKey(project, "k", scopes)
+		require.NoError(t, err)
+		require.NoError(t, st.CreateAPIKey(ctx, row))
+		return []string{"Authorization", "Bearer " + k}
+	}
+	writer, teamAdmin, admin := key(store.DefaultProjectID, auth.ScopeTargetsWrite), key(team, auth.ScopeAdmin), key(store.DefaultProjectID, auth.ScopeAdmin)
+
+	requireProblem(t, e.do(http.MethodPut, "/v1/admin/secrets/x", `{"value":"x"}`, writer...), http.StatusForbidden, api.TypeForbidden)
+	require.Equal(t, http.StatusCreated, e.do(http.MethodPut, "/v1/admin/secrets/x", `{"value":"x"}`, admin...).Code)
+	rec := e.do(http.MethodGet, "/v1/admin/secrets", nil, teamAdmin...)
+	require.JSONEq(t, `{"items":[]}`, rec.Body.String())
+	requireProblem(t, e.do(http.MethodDelete, "/v1/admin/secrets/x", nil, teamAdmin...), http.StatusNotFound, "about:blank")
+}
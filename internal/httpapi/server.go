@@ -0,0 +1,139 @@
+// Package httpapi is the HTTP API: routes, handlers and their dependencies.
+// Shared request/response helpers live in internal/api.
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/secrets"
+	"github.com/nurzh/linkwatch/internal/store"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type Server struct {
+	store   store.Store // nil: endpoints that need it answer 503
+	checker *checker.Checker
+	auth    *auth.Authenticator
+	events  *events.Broker // nil: stream and websocket endpoints answer 503
+	secrets *secrets.Box   // nil: secret endpoints answer 503
+	router  chi.Router
+
+	closing   chan struct{} // closed by CloseStreams
+	closeOnce sync.Once
+}
+
+type Option func(*Server)
+
+// WithEvents enables the live event endpoints
+func WithEvents(b *events.Broker) Option {
+	return func(s *Server) { s.events = b }
+}
+
+// WithSecrets enables the secret endpoints, sealing values with b
+func WithSecrets(b *secrets.Box) Option {
+	return func(s *Server) { s.secrets = b }
+}
+
+type health struct {
+	Liveness string `json:"liveness"`
+	DB       string `json:"db"`
+	Checker  string `json:"checker"`
+}
+
+func New(st store.Store, chk *checker.Checker, authn *auth.Authenticator, opts ...Option) *Server {
+	s := &Server{store: st, checker: chk, auth: authn, closing: make(chan struct{})}
+	for _, o := range opts {
+		o(s)
+	}
+	s.router = s.routes()
+	return s
+}
+
+// CloseStreams ends long-lived responses so http.Server.Shutdown does not
+// wait for them (use with RegisterOnShutdown)
+func (s *Server) CloseStreams() {
+	s.closeOnce.Do(func() { close(s.closing) })
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) { s.router.ServeHTTP(w, r) }
+
+// Router exposes the routes, e.g. for chi.Walk
+func (s *Server) Router() chi.Router { return s.router }
+
+// every route of the HTTP API; the OpenAPI spec must describe each of them
+func (s *Server) routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID, middleware.RealIP, middleware.Logger, api.Recoverer)
+	r.NotFound(api.NotFoundHandler)
+	r.MethodNotAllowed(api.MethodNotAllowedHandler)
+
+	r.Get("/healthz", s.health)
+	r.Get("/openapi.json", api.OpenAPIHandler)
+	r.Get("/docs", api.DocsHandler)
+
+	v1 := r.With(s.auth.Middleware)
+	v1.With(s.auth.Require(auth.ScopeTargetsRead)).Get("/v1/targets", s.listTargets)
+	v1.With(s.auth.Require(auth.ScopeTargetsWrite)).Post("/v1/targets", s.createTarget)
+	v1.With(s.auth.Require(auth.ScopeResultsRead)).Get("/v1/targets/{id}/results", s.listResults)
+	v1.With(s.auth.Require(auth.ScopeResultsRead)).Get("/v1/targets/{id}/stream", s.stream)
+	v1.With(s.auth.Require(auth.ScopeResultsRead)).Get("/v1/stream", s.stream)
+	v1.With(s.auth.Require(auth.ScopeResultsRead)).Get("/v1/ws", s.ws)
+
+	admin := v1.With(s.auth.Require(auth.ScopeAdmin))
+	admin.Post("/v1/admin/api-keys", s.createAPIKey)
+	admin.Get("/v1/admin/api-keys", s.listAPIKeys)
+	admin.Delete("/v1/admin/api-keys/{id}", s.revokeAPIKey)
+	admin.Get("/v1/admin/secrets", s.listSecrets)
+	admin.Put("/v1/admin/secrets/{name}", s.putSecret)
+	admin.Delete("/v1/admin/secrets/{name}", s.deleteSecret)
+
+	projects := v1.With(s.auth.Require(auth.ScopeProjectsAdmin))
+	projects.Post("/v1/admin/projects", s.createProject)
+	projects.Get("/v1/admin/projects", s.listProjects)
+	projects.Patch("/v1/admin/projects/{id}", s.updateProject)
+	//hosts are shared by all projects
+	projects.Get("/v1/admin/breakers", s.listBreakers)
+	return r
+}
+
+// answers 503 and reports false when there is no database
+func (s *Server) needStore(w http.ResponseWriter, r *http.Request) bool {
+	if s.store == nil {
+		api.Error(w, r, http.StatusServiceUnavailable, "database not configured")
+		return false
+	}
+	return true
+}
+
+// liveness probe returning 200 OK once the server is ready
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	resp := health{Liveness: "ok", DB: "down", Checker: s.checker.State()}
+	if s.store != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
+		defer cancel()
+		if err := s.store.Ping(ctx); err == nil {
+			resp.DB = "ok"
+		}
+	}
+	status := http.StatusOK
+	if resp.DB != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,153 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	t   *testing.T
+	st  store.Store
+	srv *Server
+}
+
+func testStore(t *testing.T) store.Store {
+	ctx := context.Background()
+	s, err := store.OpenSQLite(ctx, ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	m, err := store.Migrator(s)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	return s
+}
+
+// auth disabled: every request has every scope, X-Project picks the project
+func newEnv(t *testing.T) *testEnv {
+	st := testStore(t)
+	chk := checker.New(st, 1, time.Second, time.Minute, checker.WithEgressPolicy(netguard.Default()))
+	return &testEnv{t: t, st: st, srv: New(st, chk, &auth.Authenticator{Store: st, Disabled: true})}
+}
+
+// do sends body (marshalled unless it is a string) with headers as k, v pairs
+func (e *testEnv) do(method, path string, body any, headers ...string) *httptest.ResponseRecorder {
+	e.t.Helper()
+	var rd io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		rd = strings.NewReader(b)
+	default:
+		buf, err := json.Marshal(b)
+		require.NoError(e.t, err)
+		rd = strings.NewReader(string(buf))
+	}
+	req := httptest.NewRequest(method, path, rd)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	e.srv.ServeHTTP(rec, req)
+	return rec
+}
+
+// project created directly in the store, with optional changes
+func (e *testEnv) project(name string, opts ...func(*store.Project)) store.Project {
+	e.t.Helper()
+	p := store.Project{ID: "p_" + name, Name: name, CreatedAt: time.Now().UTC()}
+	for _, o := range opts {
+		o(&p)
+	}
+	if existing, err := e.st.GetProject(context.Background(), p.ID); err == nil {
+		return existing
+	}
+	require.NoError(e.t, e.st.CreateProject(context.Background(), p))
+	return p
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&v), rec.Body.String())
+	return v
+}
+
+// status and problem type of an error response
+func requireProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, typ string) api.Problem {
+	t.Helper()
+	require.Equal(t, status, rec.Code, rec.Body.String())
+	require.Equal(t, api.ProblemContentType, rec.Header().Get("Content-Type"))
+	p := decode[api.Problem](t, rec)
+	require.Equal(t, typ, p.Type)
+	return p
+}
+
+func TestHealth(t *testing.T) {
+	e := newEnv(t)
+	rec := e.do(http.MethodGet, "/healthz", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, health{Liveness: "ok", DB: "ok", Checker: e.srv.checker.State()}, decode[health](t, rec))
+
+	noDB := New(nil, checker.New(nil, 1, time.Second, time.Minute), &auth.Authenticator{Disabled: true})
+	rec = httptest.NewRecorder()
+	noDB.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, "down", decode[health](t, rec).DB)
+
+	rec = httptest.NewRecorder()
+	noDB.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/targets", nil))
+	requireProblem(t, rec, http.StatusServiceUnavailable, "about:blank")
+}
+
+func TestUnknownRoutes(t *testing.T) {
+	e := newEnv(t)
+	requireProblem(t, e.do(http.MethodGet, "/v2/targets", nil), http.StatusNotFound, "about:blank")
+	requireProblem(t, e.do(http.MethodPut, "/v1/targets", nil), http.StatusMethodNotAllowed, "about:blank")
+}
+
+func TestAuthRequired(t *testing.T) {
+	st := testStore(t)
+	srv := New(st, checker.New(st, 1, time.Second, time.Minute), &auth.Authenticator{Store: st})
+	ctx := context.Background()
+
+	reader, row, err := auth.NewKey(store.DefaultProjectID, "reader", []string{auth.ScopeTargetsRead})
+	require.NoError(t, err)
+	require.NoError(t, st.CreateAPIKey(ctx, row))
+
+	cases := []struct {
+		method, path, key string
+		status            int
+	}{
+		{http.MethodGet, "/healthz", "", http.StatusOK},
+		{http.MethodGet, "/openapi.json", "", http.StatusOK},
+		{http.MethodGet, "/v1/targets", "", http.StatusUnauthorized},
+		{http.MethodGet, "/v1/targets", reader, http.StatusOK},
+		{http.MethodPost, "/v1/targets", reader, http.StatusForbidden},
+		{http.MethodGet, "/v1/targets/t_x/results", reader, http.StatusForbidden},
+		{http.MethodGet, "/v1/admin/api-keys", reader, http.StatusForbidden},
+		{http.MethodGet, "/v1/admin/projects", reader, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{"url":"https://a.test/"}`))
+		if tc.key != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.key)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		require.Equal(t, tc.status, rec.Code, "%s %s", tc.method, tc.path)
+	}
+}
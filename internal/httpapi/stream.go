@@ -0,0 +1,155 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/internal/targets"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	heartbeatEvery = 15 * time.Second
+	streamWrite    = 10 * time.Second // a client that cannot take a write in this long is dropped
+)
+
+// event filter shared by the SSE and WebSocket endpoints: the caller's
+// project, optionally narrowed to a target (checked to be visible), host and labels
+func (s *Server) eventFilter(w http.ResponseWriter, r *http.Request, q *api.Query, targetID string) (events.Filter, bool) {
+	f := events.Filter{ProjectID: auth.ProjectID(r.Context()), TargetID: targetID, Types: eventTypes(r.Context())}
+	if h := q.Host(); h != nil {
+		f.Host = *h
+	}
+	f.Labels = q.Labels()
+	if q.Invalid(w, r) {
+		return f, false
+	}
+	if targetID == "" {
+		return f, true
+	}
+	if !s.needStore(w, r) {
+		return f, false
+	}
+	_, err := s.projectTarget(r.Context(), f.ProjectID, targetID)
+	if errors.Is(err, store.ErrNotFound) {
+		api.Error(w, r, http.StatusNotFound, "target not found")
+		return f, false
+	}
+	if err != nil {
+		api.Internal(w, r, err)
+		return f, false
+	}
+	return f, true
+}
+
+// event types the caller may see; target events need targets:read
+func eventTypes(ctx context.Context) []string {
+	types := []string{events.TypeResult, events.TypeState}
+	if auth.HasScope(ctx, auth.ScopeTargetsRead) {
+		types = append(types, events.TypeTarget)
+	}
+	return types
+}
+
+// targets.Get with the handlers' store timeout
+func (s *Server) projectTarget(ctx context.Context, project, id string) (store.Target, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	return targets.Get(ctx, s.store, project, id)
+}
+
+// Server-Sent Events for /v1/stream and /v1/targets/{id}/stream
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	if s.events == nil {
+		api.Error(w, r, http.StatusServiceUnavailable, "live events are not enabled")
+		return
+	}
+	q := api.NewQuery(r)
+	targetID := chi.URLParam(r, "id")
+	if targetID == "" {
+		targetID = q.String("target_id")
+	}
+	//EventSource sends Last-Event-ID on reconnect; the parameter is for first connects
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = q.String("last_event_id")
+	}
+	if _, err := strconv.ParseUint(lastID, 10, 64); lastID != "" && err != nil {
+		api.Invalid(w, r, api.FieldError{Field: "last_event_id", Message: "must be an event id"})
+		return
+	}
+	f, ok := s.eventFilter(w, r, q, targetID)
+	if !ok {
+		return
+	}
+
+	replay, sub, err := s.events.Subscribe(f, lastID)
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx
+	w.WriteHeader(http.StatusOK)
+
+	write := func(format string, args ...any) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(streamWrite))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	send := func(ev events.Event) bool {
+		ev.Origin = ""
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return true
+		}
+		return write("id: %s\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	}
+
+	if !write("retry: 3000\n\n") {
+		return
+	}
+	for _, ev := range replay {
+		if !send(ev) {
+			return
+		}
+	}
+	heartbeat := time.NewTicker(heartbeatEvery)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		case <-heartbeat.C:
+			if !write(": keepalive\n\n") {
+				return
+			}
+		case ev, ok := <-sub.C():
+			if !ok {
+				//dropped for lagging; the client reconnects with Last-Event-ID
+				return
+			}
+			if !send(ev) {
+				return
+			}
+		}
+	}
+}
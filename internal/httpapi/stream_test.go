@@ -0,0 +1,132 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+type sseEvent struct {
+	id, typ string
+	data    events.Event
+}
+
+// reads one SSE message at a time, skipping comments and retry hints
+type sseReader struct {
+	t  *testing.T
+	sc *bufio.Scanner
+}
+
+func (r *sseReader) next() sseEvent {
+	r.t.Helper()
+	var ev sseEvent
+	for r.sc.Scan() {
+		line := r.sc.Text()
+		switch {
+		case line == "":
+			if ev.typ != "" {
+				return ev
+			}
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.typ = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(r.t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev.data))
+		}
+	}
+	r.t.Fatalf("stream ended: %v", r.sc.Err())
+	return ev
+}
+
+func openStream(t *testing.T, ctx context.Context, url string, headers ...string) (*http.Response, *sseReader) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp, &sseReader{t: t, sc: bufio.NewScanner(resp.Body)}
+}
+
+func TestStream(t *testing.T) {
+	st := testStore(t)
+	broker := events.NewBroker(0)
+	srv := New(st, checker.New(st, 1, time.Second, time.Minute), &auth.Authenticator{Store: st, Disabled: true}, WithEvents(broker))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tg, _, err := st.CreateOrGetTarget(ctx, store.DefaultProjectID, "t_a", "https://a.test/", "a.test")
+	require.NoError(t, err)
+
+	//headers are only sent once the handler has subscribed
+	resp, stream := openStream(t, ctx, ts.URL+"/v1/stream?label=env=prod")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	prod := map[string]string{"env": "prod"}
+	code := 200
+	broker.Publish(events.Event{Type: events.TypeResult, ProjectID: store.DefaultProjectID, TargetID: "t_b", Host: "b.test"}) // no label
+	broker.Publish(events.Event{Type: events.TypeResult, ProjectID: "p_other", TargetID: "t_x", Labels: prod})                // other project
+	broker.Publish(events.Event{Type: events.TypeResult, ProjectID: store.DefaultProjectID, TargetID: tg.ID, Host: "a.test", Labels: prod,
+		Result: &store.CheckResult{TargetID: tg.ID, StatusCode: &code}})
+	broker.Publish(events.Event{Type: events.TypeState, ProjectID: store.DefaultProjectID, TargetID: tg.ID, Host: "a.test", Labels: prod,
+		State: &events.StateChange{From: "up", To: "down"}})
+
+	first := stream.next()
+	require.Equal(t, events.TypeResult, first.typ)
+	require.Equal(t, tg.ID, first.data.TargetID)
+	require.Equal(t, 200, *first.data.Result.StatusCode)
+	require.Equal(t, first.id, first.data.ID)
+	require.Empty(t, first.data.Origin)
+	second := stream.next()
+	require.Equal(t, events.TypeState, second.typ)
+	require.Equal(t, "down", second.data.State.To)
+
+	//resume after the first event: the state change is replayed
+	_, resumed := openStream(t, ctx, ts.URL+"/v1/targets/"+tg.ID+"/stream", "Last-Event-ID", first.id)
+	require.Equal(t, second.id, resumed.next().id)
+
+	//the per-target stream only carries that target
+	broker.Publish(events.Event{Type: events.TypeResult, ProjectID: store.DefaultProjectID, TargetID: "t_b"})
+	broker.Publish(events.Event{Type: events.TypeResult, ProjectID: store.DefaultProjectID, TargetID: tg.ID})
+	require.Equal(t, tg.ID, resumed.next().data.TargetID)
+
+	//shutdown ends open streams
+	srv.CloseStreams()
+	for stream.sc.Scan() { //returns once the server ends the response
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	e := newEnv(t)
+	//no broker configured
+	requireProblem(t, e.do(http.MethodGet, "/v1/stream", nil), http.StatusServiceUnavailable, "about:blank")
+
+	st := e.st
+	e.srv = New(st, checker.New(st, 1, time.Second, time.Minute), &auth.Authenticator{Store: st, Disabled: true}, WithEvents(events.NewBroker(0)))
+	_, _, err := st.CreateOrGetTarget(context.Background(), store.DefaultProjectID, "t_a", "https://a.test/", "a.test")
+	require.NoError(t, err)
+
+	requireProblem(t, e.do(http.MethodGet, "/v1/targets/t_missing/stream", nil), http.StatusNotFound, "about:blank")
+	requireProblem(t, e.do(http.MethodGet, "/v1/targets/t_a/stream", nil, "X-Project", e.project("team").ID), http.StatusNotFound, "about:blank")
+	requireProblem(t, e.do(http.MethodGet, "/v1/stream?label=env", nil), http.StatusBadRequest, api.TypeValidation)
+	requireProblem(t, e.do(http.MethodGet, "/v1/stream", nil, "Last-Event-ID", "abc"), http.StatusBadRequest, api.TypeValidation)
+}
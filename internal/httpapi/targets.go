@@ -0,0 +1,143 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/internal/targets"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type createTargetReq struct {
+	URL string `json:"url"`
+}
+
+// list targets with cursor pagination, stable (created_at, id) ordering
+func (s *Server) listTargets(w http.ResponseWriter, r *http.Request) {
+	if !s.needStore(w, r) {
+		return
+	}
+
+	//query
+	q := api.NewQuery(r)
+	host := q.Host()
+	limit := q.Limit(20, 100)
+	after := q.Cursor()
+	if q.Invalid(w, r) {
+		return
+	}
+
+	ctx, cancel := api.CtxTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	items, next, err := s.store.ListTargets(ctx, auth.ProjectID(r.Context()), host, after, limit)
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+
+	for i := range items {
+		items[i] = items[i].Redacted()
+	}
+	resp := map[string]any{
+		"items": items,
+	}
+	if next != nil {
+		resp["next_page_token"] = api.EncodeCursor(*next)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// recent check results for a target, newest first
+func (s *Server) listResults(w http.ResponseWriter, r *http.Request) {
+	if !s.needStore(w, r) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+
+	//query
+	q := api.NewQuery(r)
+	limit := q.Limit(50, 200)
+	since := q.Since()
+	if q.Invalid(w, r) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	//results are scoped through their target; other projects' targets do not exist
+	t, err := s.store.GetTarget(ctx, id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && t.ProjectID != auth.ProjectID(r.Context())) {
+		api.Error(w, r, http.StatusNotFound, "target not found")
+		return
+	}
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	items, err := s.store.ListResults(ctx, id, since, limit)
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// validate and canonicalize the URL; supports Idempotency-Key
+func (s *Server) createTarget(w http.ResponseWriter, r *http.Request) {
+	if !s.needStore(w, r) {
+		return
+	}
+
+	var body createTargetReq
+	if !api.DecodeJSON(w, r, &body) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	t, created, err := targets.Create(ctx, s.store, s.checker.EgressPolicy(), auth.ProjectID(r.Context()), body.URL, r.Header.Get("Idempotency-Key"))
+	if err != nil {
+		createTargetError(w, r, err)
+		return
+	}
+	//an existing declarative target may carry credentials
+	t = t.Redacted()
+	if created {
+		s.publishTarget(events.Created, t)
+		writeJSON(w, http.StatusCreated, t)
+	} else {
+		writeJSON(w, http.StatusOK, t)
+	}
+}
+
+func (s *Server) publishTarget(action string, t store.Target) {
+	if s.events != nil {
+		s.events.Publish(events.TargetEvent(action, t))
+	}
+}
+
+func createTargetError(w http.ResponseWriter, r *http.Request, err error) {
+	var fe *store.FieldError
+	switch {
+	case errors.As(err, &fe):
+		api.Invalid(w, r, api.FieldError{Field: fe.Field, Message: fe.Message})
+	case errors.Is(err, store.ErrIdemConflict):
+		api.WriteProblem(w, r, api.Problem{Type: api.TypeIdempotencyConflict, Status: http.StatusConflict,
+			Detail: "Idempotency-Key was already used with a different url"})
+	case errors.Is(err, store.ErrQuotaExceeded):
+		api.WriteProblem(w, r, api.Problem{Type: api.TypeQuotaExceeded, Status: http.StatusForbidden, Detail: err.Error()})
+	case errors.Is(err, store.ErrNotFound):
+		api.Error(w, r, http.StatusNotFound, "unknown project")
+	default:
+		api.Internal(w, r, err)
+	}
+}
@@ -0,0 +1,192 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+type targetPage struct {
+	Items         []store.Target `json:"items"`
+	NextPageToken string         `json:"next_page_token"`
+}
+
+func TestCreateTarget(t *testing.T) {
+	e := newEnv(t)
+
+	rec := e.do(http.MethodPost, "/v1/targets", map[string]string{"url": "HTTPS://Example.ORG:443/a/"})
+	require.Equal(t, http.StatusCreated, rec.Code)
+	first := decode[store.Target](t, rec)
+	require.Equal(t, "https://example.org/a", first.URL)
+	require.Equal(t, "example.org", first.Host)
+	require.Equal(t, store.DefaultProjectID, first.ProjectID)
+	require.Equal(t, store.SourceAPI, first.Source)
+
+	//same canonical url again: existing target, 200
+	rec = e.do(http.MethodPost, "/v1/targets", map[string]string{"url": "https://example.org/a"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, first.ID, decode[store.Target](t, rec).ID)
+
+	cases := []struct {
+		name, body string
+		field      string
+	}{
+		{"missing url", `{}`, "url"},
+		{"bad scheme", `{"url":"ftp://example.org/"}`, "url"},
+		{"relative", `{"url":"/path"}`, "url"},
+		{"unknown field", `{"url":"https://example.org/","labels":{}}`, "labels"},
+		{"not json", `url=https://example.org/`, "body"},
+		{"loopback", `{"url":"http://127.0.0.1:8080/"}`, "url"},
+		{"metadata", `{"url":"http://169.254.169.254/latest/"}`, "url"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := requireProblem(t, e.do(http.MethodPost, "/v1/targets", tc.body), http.StatusBadRequest, api.TypeValidation)
+			require.Equal(t, tc.field, p.Errors[0].Field)
+		})
+	}
+}
+
+func TestCreateTargetIdempotency(t *testing.T) {
+	e := newEnv(t)
+	post := func(key, u string) *httptest.ResponseRecorder {
+		return e.do(http.MethodPost, "/v1/targets", map[string]string{"url": u}, "Idempotency-Key", key)
+	}
+
+	r := post("k1", "https://a.test/")
+	require.Equal(t, http.StatusCreated, r.Code)
+	a := decode[store.Target](t, r)
+
+	//replay, also with a differently spelled but equal url
+	for _, u := range []string{"https://a.test/", "HTTPS://A.TEST:443/"} {
+		r = post("k1", u)
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Equal(t, a.ID, decode[store.Target](t, r).ID)
+	}
+
+	//same key, different url
+	requireProblem(t, post("k1", "https://b.test/"), http.StatusConflict, api.TypeIdempotencyConflict)
+
+	//new key for an already registered url returns that target
+	r = post("k2", "https://a.test/")
+	require.Equal(t, http.StatusCreated, r.Code)
+	require.Equal(t, a.ID, decode[store.Target](t, r).ID)
+
+	//keys are per project
+	rec := e.do(http.MethodPost, "/v1/targets", map[string]string{"url": "https://b.test/"}, "Idempotency-Key", "k1", "X-Project", e.project("team").ID)
+	require.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestCreateTargetQuota(t *testing.T) {
+	e := newEnv(t)
+	p := e.project("small", func(p *store.Project) { one := 1; p.MaxTargets = &one })
+
+	rec := e.do(http.MethodPost, "/v1/targets", `{"url":"https://a.test/"}`, "X-Project", p.ID)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	rec = e.do(http.MethodPost, "/v1/targets", `{"url":"https://b.test/"}`, "X-Project", p.ID)
+	requireProblem(t, rec, http.StatusForbidden, api.TypeQuotaExceeded)
+	rec = e.do(http.MethodPost, "/v1/targets", `{"url":"https://b.test/"}`, "X-Project", p.ID, "Idempotency-Key", "k")
+	requireProblem(t, rec, http.StatusForbidden, api.TypeQuotaExceeded)
+
+	//the existing url still resolves
+	rec = e.do(http.MethodPost, "/v1/targets", `{"url":"https://a.test/"}`, "X-Project", p.ID)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	requireProblem(t, e.do(http.MethodPost, "/v1/targets", `{"url":"https://a.test/"}`, "X-Project", "p_missing"), http.StatusNotFound, "about:blank")
+}
+
+func TestListTargetsPagination(t *testing.T) {
+	e := newEnv(t)
+	var want []string
+	for i := range 7 {
+		host := "example.org"
+		if i%2 == 1 {
+			host = "other.test"
+		}
+		rec := e.do(http.MethodPost, "/v1/targets", map[string]string{"url": fmt.Sprintf("https://%s/%d", host, i)})
+		require.Equal(t, http.StatusCreated, rec.Code)
+		want = append(want, decode[store.Target](t, rec).ID)
+	}
+	//another project's targets never show up
+	rec := e.do(http.MethodPost, "/v1/targets", `{"url":"https://example.org/0"}`, "X-Project", e.project("team").ID)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var got []string
+	token, pages := "", 0
+	for {
+		q := url.Values{"limit": {"3"}}
+		if token != "" {
+			q.Set("page_token", token)
+		}
+		rec := e.do(http.MethodGet, "/v1/targets?"+q.Encode(), nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		page := decode[targetPage](t, rec)
+		pages++
+		for _, it := range page.Items {
+			got = append(got, it.ID)
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		require.Len(t, page.Items, 3)
+		token = page.NextPageToken
+	}
+	require.Equal(t, want, got)
+	require.Equal(t, 3, pages)
+
+	rec = e.do(http.MethodGet, "/v1/targets?host=OTHER.test", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	page := decode[targetPage](t, rec)
+	require.Len(t, page.Items, 3)
+	require.Empty(t, page.NextPageToken)
+
+	for _, q := range []string{"limit=0", "limit=101", "limit=x", "page_token=nope", "host=http://a.test"} {
+		requireProblem(t, e.do(http.MethodGet, "/v1/targets?"+q, nil), http.StatusBadRequest, api.TypeValidation)
+	}
+}
+
+func TestListResults(t *testing.T) {
+	e := newEnv(t)
+	ctx := context.Background()
+	rec := e.do(http.MethodPost, "/v1/targets", `{"url":"https://a.test/"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	tg := decode[store.Target](t, rec)
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	for i := range 5 {
+		code := 200 + i
+		require.NoError(t, e.st.AppendCheckResult(ctx, store.CheckResult{
+			TargetID: tg.ID, CheckedAt: base.Add(time.Duration(i) * time.Minute), StatusCode: &code,
+		}))
+	}
+
+	type results struct {
+		Items []store.CheckResult `json:"items"`
+	}
+	rec = e.do(http.MethodGet, "/v1/targets/"+tg.ID+"/results", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	items := decode[results](t, rec).Items
+	require.Len(t, items, 5)
+	require.Equal(t, 204, *items[0].StatusCode, "newest first")
+
+	since := url.QueryEscape(base.Add(3 * time.Minute).Format(time.RFC3339))
+	rec = e.do(http.MethodGet, "/v1/targets/"+tg.ID+"/results?limit=1&since="+since, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	items = decode[results](t, rec).Items
+	require.Len(t, items, 1)
+	require.Equal(t, 204, *items[0].StatusCode)
+
+	requireProblem(t, e.do(http.MethodGet, "/v1/targets/"+tg.ID+"/results?limit=201", nil), http.StatusBadRequest, api.TypeValidation)
+	requireProblem(t, e.do(http.MethodGet, "/v1/targets/"+tg.ID+"/results?since=1h", nil), http.StatusBadRequest, api.TypeValidation)
+	requireProblem(t, e.do(http.MethodGet, "/v1/targets/t_missing/results", nil), http.StatusNotFound, "about:blank")
+	//other projects' targets do not exist
+	requireProblem(t, e.do(http.MethodGet, "/v1/targets/"+tg.ID+"/results", nil, "X-Project", e.project("team").ID), http.StatusNotFound, "about:blank")
+}
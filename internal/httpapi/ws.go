@@ -0,0 +1,289 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/store"
+
+	"github.com/coder/websocket"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	wsMaxSubscriptions = 32
+	wsPongWait         = 10 * time.Second
+)
+
+// client → server
+type wsRequest struct {
+	Type        string            `json:"type"` // subscribe, unsubscribe, ping
+	ID          string            `json:"id"`   // subscription id, chosen by the client
+	TargetID    string            `json:"target_id"`
+	Host        string            `json:"host"`
+	Labels      map[string]string `json:"labels"`
+	Events      []string          `json:"events"` // result, state, target; default all the key may see
+	LastEventID string            `json:"last_event_id"`
+
+	invalid string // why the message could not be decoded
+}
+
+// server → client
+type wsMessage struct {
+	Type          string        `json:"type"` // subscribed, unsubscribed, event, error, pong
+	ID            string        `json:"id,omitempty"`
+	Subscriptions []string      `json:"subscriptions,omitempty"` // event: every matching subscription
+	Event         *events.Event `json:"event,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+type wsSubscription struct {
+	filter events.Filter
+	//ids sent as replay; the same events may still be queued live
+	replayed map[string]bool
+}
+
+// one connection: a single broker subscription for the caller's project,
+// matched here against the client's subscriptions. The broker buffer is the
+// connection's send buffer; a client that lets it fill up is disconnected.
+type wsConn struct {
+	s       *Server
+	conn    *websocket.Conn
+	reqID   string // of the upgrade request, for the log
+	project string
+	types   []string // allowed event types
+	subs    map[string]*wsSubscription
+}
+
+// WebSocket endpoint: JSON subscribe/unsubscribe messages, events pushed as they happen
+func (s *Server) ws(w http.ResponseWriter, r *http.Request) {
+	if s.events == nil {
+		api.Error(w, r, http.StatusServiceUnavailable, "live events are not enabled")
+		return
+	}
+	//Accept would answer in plain text
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		api.Error(w, r, http.StatusUpgradeRequired, "WebSocket upgrade required")
+		return
+	}
+	_, sub, err := s.events.Subscribe(events.Filter{ProjectID: auth.ProjectID(r.Context())}, "")
+	if err != nil {
+		api.Internal(w, r, err)
+		return
+	}
+	defer sub.Close()
+	conn, err := websocket.Accept(w, r, nil) // rejects cross-origin browsers
+	if err != nil {
+		return // Accept answered
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(api.MaxBodyBytes)
+
+	c := &wsConn{s: s, conn: conn, reqID: middleware.GetReqID(r.Context()), project: auth.ProjectID(r.Context()), types: eventTypes(r.Context()),
+		subs: map[string]*wsSubscription{}}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	//the reader only decodes; every write happens in the loop below
+	reqs := make(chan wsRequest)
+	go func() {
+		defer cancel()
+		for {
+			typ, data, err := conn.Read(ctx)
+			if err != nil {
+				return
+			}
+			req := decodeWS(typ, data)
+			select {
+			case reqs <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(heartbeatEvery)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.closing:
+			conn.Close(websocket.StatusGoingAway, "server shutting down")
+			return
+		case <-heartbeat.C:
+			pctx, pcancel := context.WithTimeout(ctx, wsPongWait)
+			err := conn.Ping(pctx)
+			pcancel()
+			if err != nil {
+				return
+			}
+		case req := <-reqs:
+			if !c.handle(ctx, req) {
+				return
+			}
+		case ev, ok := <-sub.C():
+			if !ok {
+				conn.Close(websocket.StatusTryAgainLater, "too slow, resubscribe with last_event_id")
+				return
+			}
+			if !c.event(ctx, ev) {
+				return
+			}
+		}
+	}
+}
+
+// strict like api.DecodeJSON: one object, no unknown fields
+func decodeWS(typ websocket.MessageType, data []byte) wsRequest {
+	if typ != websocket.MessageText {
+		return wsRequest{invalid: "messages must be text"}
+	}
+	var req wsRequest
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return wsRequest{invalid: "invalid JSON: " + err.Error()}
+	}
+	if dec.More() {
+		return wsRequest{invalid: "invalid JSON: a single object expected"}
+	}
+	return req
+}
+
+func (c *wsConn) write(ctx context.Context, m wsMessage) bool {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return true
+	}
+	wctx, cancel := context.WithTimeout(ctx, streamWrite)
+	defer cancel()
+	return c.conn.Write(wctx, websocket.MessageText, data) == nil
+}
+
+func (c *wsConn) fail(ctx context.Context, id, msg string) bool {
+	return c.write(ctx, wsMessage{Type: "error", ID: id, Error: msg})
+}
+
+// a live event, once, listing every subscription it matches
+func (c *wsConn) event(ctx context.Context, ev events.Event) bool {
+	var ids []string
+	for id, sub := range c.subs {
+		if sub.replayed[ev.ID] {
+			delete(sub.replayed, ev.ID)
+			continue
+		}
+		if sub.filter.Match(ev) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return true
+	}
+	sort.Strings(ids)
+	ev.Origin = ""
+	return c.write(ctx, wsMessage{Type: "event", Subscriptions: ids, Event: &ev})
+}
+
+// false ends the connection
+func (c *wsConn) handle(ctx context.Context, req wsRequest) bool {
+	if req.invalid != "" {
+		return c.fail(ctx, "", req.invalid)
+	}
+	switch req.Type {
+	case "ping":
+		return c.write(ctx, wsMessage{Type: "pong"})
+	case "unsubscribe":
+		if _, ok := c.subs[req.ID]; !ok {
+			return c.fail(ctx, req.ID, "unknown subscription")
+		}
+		delete(c.subs, req.ID)
+		return c.write(ctx, wsMessage{Type: "unsubscribed", ID: req.ID})
+	case "subscribe":
+		return c.subscribe(ctx, req)
+	default:
+		return c.fail(ctx, req.ID, `message must be a JSON object with type "subscribe", "unsubscribe" or "ping"`)
+	}
+}
+
+func (c *wsConn) subscribe(ctx context.Context, req wsRequest) bool {
+	f, msg := c.filter(ctx, req)
+	if msg == "" {
+		if _, ok := c.subs[req.ID]; !ok && len(c.subs) >= wsMaxSubscriptions {
+			msg = "at most " + strconv.Itoa(wsMaxSubscriptions) + " subscriptions per connection"
+		}
+	}
+	var replay []events.Event
+	if msg == "" {
+		var err error
+		if replay, err = c.s.events.Replay(f, req.LastEventID); err != nil {
+			msg = "last_event_id must be an event id"
+		}
+	}
+	if msg != "" {
+		return c.fail(ctx, req.ID, msg)
+	}
+
+	sub := &wsSubscription{filter: f, replayed: map[string]bool{}}
+	c.subs[req.ID] = sub // resubscribing with an id replaces it
+	if !c.write(ctx, wsMessage{Type: "subscribed", ID: req.ID}) {
+		return false
+	}
+	for _, ev := range replay {
+		sub.replayed[ev.ID] = true
+		ev.Origin = ""
+		if !c.write(ctx, wsMessage{Type: "event", Subscriptions: []string{req.ID}, Event: &ev}) {
+			return false
+		}
+	}
+	return true
+}
+
+// validates a subscribe request; the message is "" when it is fine
+func (c *wsConn) filter(ctx context.Context, req wsRequest) (events.Filter, string) {
+	f := events.Filter{ProjectID: c.project, TargetID: req.TargetID, Labels: req.Labels, Types: c.types}
+	if req.ID == "" || len(req.ID) > 64 {
+		return f, "id must be 1 to 64 characters"
+	}
+	if req.Host != "" {
+		h, err := api.ParseHost(req.Host)
+		if err != nil {
+			return f, "host " + err.Error()
+		}
+		f.Host = h
+	}
+	if len(req.Events) > 0 {
+		for _, t := range req.Events {
+			if !slices.Contains(c.types, t) {
+				return f, "events: " + strconv.Quote(t) + " is unknown or needs a scope the key lacks"
+			}
+		}
+		f.Types = req.Events
+	}
+	if req.TargetID != "" {
+		if c.s.store == nil {
+			return f, "database not configured"
+		}
+		_, err := c.s.projectTarget(ctx, c.project, req.TargetID)
+		if errors.Is(err, store.ErrNotFound) {
+			return f, "target not found"
+		}
+		if err != nil {
+			log.Printf("request %s ws subscribe: %v", c.reqID, err)
+			return f, "internal error, see the server log for this request id"
+		}
+	}
+	return f, ""
+}
@@ -0,0 +1,152 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+type wsClient struct {
+	t    *testing.T
+	ctx  context.Context
+	conn *websocket.Conn
+}
+
+func dialWS(t *testing.T, ctx context.Context, url string) *wsClient {
+	t.Helper()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(url, "http")+"/v1/ws", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.CloseNow() })
+	return &wsClient{t: t, ctx: ctx, conn: conn}
+}
+
+func (c *wsClient) send(v any) {
+	c.t.Helper()
+	require.NoError(c.t, wsjson.Write(c.ctx, c.conn, v))
+}
+
+func (c *wsClient) next() wsMessage {
+	c.t.Helper()
+	var m wsMessage
+	require.NoError(c.t, wsjson.Read(c.ctx, c.conn, &m))
+	return m
+}
+
+func TestWebSocket(t *testing.T) {
+	st := testStore(t)
+	broker := events.NewBroker(0)
+	chk := checker.New(st, 1, time.Second, time.Minute, checker.WithEgressPolicy(netguard.Default()))
+	srv := New(st, chk, &auth.Authenticator{Store: st, Disabled: true}, WithEvents(broker))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tg, _, err := st.CreateOrGetTarget(ctx, store.DefaultProjectID, "t_a", "https://a.test/", "a.test")
+	require.NoError(t, err)
+	prod := map[string]string{"env": "prod"}
+
+	c := dialWS(t, ctx, ts.URL)
+	c.send(map[string]any{"type": "subscribe", "id": "prod", "labels": prod})
+	require.Equal(t, wsMessage{Type: "subscribed", ID: "prod"}, c.next())
+	c.send(map[string]any{"type": "subscribe", "id": "a", "target_id": tg.ID, "events": []string{"state"}})
+	require.Equal(t, wsMessage{Type: "subscribed", ID: "a"}, c.next())
+
+	broker.Publish(events.Event{Type: events.TypeResult, ProjectID: store.DefaultProjectID, TargetID: "t_b"})               // no match
+	broker.Publish(events.Event{Type: events.TypeResult, ProjectID: "p_other", TargetID: tg.ID, Labels: prod})              // other project
+	broker.Publish(events.Event{Type: events.TypeResult, ProjectID: store.DefaultProjectID, TargetID: tg.ID, Labels: prod}) // prod only
+	broker.Publish(events.Event{Type: events.TypeState, ProjectID: store.DefaultProjectID, TargetID: tg.ID, Labels: prod,
+		State: events.Transition(true, false)})
+
+	m := c.next()
+	require.Equal(t, "event", m.Type)
+	require.Equal(t, []string{"prod"}, m.Subscriptions)
+	require.Equal(t, events.TypeResult, m.Event.Type)
+	require.Empty(t, m.Event.Origin)
+	first := m.Event.ID
+	m = c.next()
+	require.Equal(t, []string{"a", "prod"}, m.Subscriptions, "sent once for every matching subscription")
+	require.Equal(t, "opened", m.Event.State.Incident)
+
+	//targets created through the API are events too
+	c.send(map[string]any{"type": "unsubscribe", "id": "a"})
+	require.Equal(t, wsMessage{Type: "unsubscribed", ID: "a"}, c.next())
+	c.send(map[string]any{"type": "subscribe", "id": "targets", "events": []string{"target"}})
+	require.Equal(t, wsMessage{Type: "subscribed", ID: "targets"}, c.next())
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/targets", strings.NewReader(`{"url":"https://new.test/"}`)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+	m = c.next()
+	require.Equal(t, []string{"targets"}, m.Subscriptions)
+	require.Equal(t, events.Created, m.Event.Action)
+	require.Equal(t, "https://new.test/", m.Event.Target.URL)
+
+	//resume: a new connection gets what it missed after last_event_id, then live events
+	c2 := dialWS(t, ctx, ts.URL)
+	c2.send(map[string]any{"type": "subscribe", "id": "prod", "labels": prod, "last_event_id": first})
+	require.Equal(t, "subscribed", c2.next().Type)
+	m = c2.next()
+	require.Equal(t, events.TypeState, m.Event.Type)
+	broker.Publish(events.Event{Type: events.TypeResult, ProjectID: store.DefaultProjectID, TargetID: tg.ID, Labels: prod})
+	require.Equal(t, events.TypeResult, c2.next().Event.Type)
+	require.Equal(t, []string{"prod"}, c.next().Subscriptions)
+
+	c.send(map[string]any{"type": "ping"})
+	require.Equal(t, wsMessage{Type: "pong"}, c.next())
+
+	//shutdown closes connections
+	srv.CloseStreams()
+	_, _, err = c.conn.Read(ctx)
+	require.Equal(t, websocket.StatusGoingAway, websocket.CloseStatus(err))
+}
+
+func TestWebSocketErrors(t *testing.T) {
+	e := newEnv(t)
+	//no broker configured
+	requireProblem(t, e.do(http.MethodGet, "/v1/ws", nil), http.StatusServiceUnavailable, "about:blank")
+
+	st := e.st
+	srv := New(st, checker.New(st, 1, time.Second, time.Minute), &auth.Authenticator{Store: st, Disabled: true}, WithEvents(events.NewBroker(0)))
+	e.srv = srv
+	requireProblem(t, e.do(http.MethodGet, "/v1/ws", nil), http.StatusUpgradeRequired, "about:blank")
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, _, err := st.CreateOrGetTarget(ctx, e.project("team").ID, "t_team", "https://a.test/", "a.test")
+	require.NoError(t, err)
+
+	c := dialWS(t, ctx, ts.URL)
+	for _, tc := range []struct {
+		msg  string
+		want string
+	}{
+		{`{"type":"subscribe"}`, "id must be"},
+		{`{"type":"subscribe","id":"x","target_id":"t_team"}`, "target not found"},
+		{`{"type":"subscribe","id":"x","events":["nope"]}`, `"nope" is unknown`},
+		{`{"type":"subscribe","id":"x","host":"https://a.test"}`, "host must be a host name without scheme"},
+		{`{"type":"subscribe","id":"x","last_event_id":"abc"}`, "last_event_id"},
+		{`{"type":"unsubscribe","id":"x"}`, "unknown subscription"},
+		{`{"type":"subscribe","id":"x","extra":1}`, "unknown field"},
+		{`not json`, "invalid JSON"},
+		{`{"type":"bogus"}`, "must be a JSON object"},
+	} {
+		require.NoError(t, c.conn.Write(ctx, websocket.MessageText, []byte(tc.msg)))
+		m := c.next()
+		require.Equal(t, "error", m.Type, tc.msg)
+		require.Contains(t, m.Error, tc.want, tc.msg)
+	}
+}
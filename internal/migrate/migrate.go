@@ -0,0 +1,193 @@
+// Package migrate applies the embedded schema migrations and records them
+// in a schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Driver is the backend specific part (implemented in package store)
+type Driver interface {
+	// Lock serializes runners across processes; the returned func releases it
+	Lock(ctx context.Context) (func(), error)
+	EnsureVersionTable(ctx context.Context) error
+	AppliedVersions(ctx context.Context) (map[int]time.Time, error)
+	// Apply runs sql and records (up) or removes (down) the version in one tx
+	Apply(ctx context.Context, version int, name, sql string, up bool) error
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+type Runner struct {
+	d          Driver
+	migrations []Migration
+}
+
+func New(d Driver, src fs.FS) (*Runner, error) {
+	ms, err := Load(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{d: d, migrations: ms}, nil
+}
+
+// Load reads <version>_<name>.(up|down).sql files, sorted by version
+func Load(src fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(src, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || path.Ext(name) != ".sql" {
+			continue
+		}
+		base := strings.TrimSuffix(name, ".sql")
+		dir := path.Ext(base)
+		if dir != ".up" && dir != ".down" {
+			return nil, fmt.Errorf("migration %s: want .up.sql or .down.sql", name)
+		}
+		base = strings.TrimSuffix(base, dir)
+		num, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: want <version>_<name>", name)
+		}
+		v, err := strconv.Atoi(num)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("migration %s: bad version %q", name, num)
+		}
+		b, err := fs.ReadFile(src, name)
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[v]
+		if m == nil {
+			m = &Migration{Version: v, Name: label}
+			byVersion[v] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", v, m.Name, label)
+		}
+		if dir == ".up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Up applies every pending migration and returns the ones it ran
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	unlock, err := r.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := r.d.AppliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ran []Migration
+	for _, m := range r.migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := r.d.Apply(ctx, m.Version, m.Name, m.Up, true); err != nil {
+			return ran, fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Down rolls back the latest steps applied migrations
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, nil
+	}
+	unlock, err := r.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := r.d.AppliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ran []Migration
+	for i := len(r.migrations) - 1; i >= 0 && len(ran) < steps; i-- {
+		m := r.migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return ran, fmt.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+		if err := r.d.Apply(ctx, m.Version, m.Name, m.Down, false); err != nil {
+			return ran, fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	if err := r.d.EnsureVersionTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := r.d.AppliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		s := Status{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			s.AppliedAt = &at
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (r *Runner) prepare(ctx context.Context) (func(), error) {
+	unlock, err := r.d.Lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.d.EnsureVersionTable(ctx); err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
@@ -0,0 +1,78 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDriver struct {
+	applied map[int]time.Time
+	ran     []string
+}
+
+func (f *fakeDriver) Lock(ctx context.Context) (func(), error)     { return func() {}, nil }
+func (f *fakeDriver) EnsureVersionTable(ctx context.Context) error { return nil }
+func (f *fakeDriver) AppliedVersions(ctx context.Context) (map[int]time.Time, error) {
+	out := map[int]time.Time{}
+	for k, v := range f.applied {
+		out[k] = v
+	}
+	return out, nil
+}
+func (f *fakeDriver) Apply(ctx context.Context, version int, name, sql string, up bool) error {
+	f.ran = append(f.ran, sql)
+	if up {
+		f.applied[version] = time.Now()
+	} else {
+		delete(f.applied, version)
+	}
+	return nil
+}
+
+func TestRunnerUpDownStatus(t *testing.T) {
+	src := fstest.MapFS{
+		"001_init.up.sql":      {Data: []byte("up1")},
+		"001_init.down.sql":    {Data: []byte("down1")},
+		"002_indexes.up.sql":   {Data: []byte("up2")},
+		"002_indexes.down.sql": {Data: []byte("down2")},
+		"README.md":            {Data: []byte("ignored")},
+	}
+	d := &fakeDriver{applied: map[int]time.Time{}}
+	r, err := New(d, src)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	ran, err := r.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, ran, 2)
+	require.Equal(t, []string{"up1", "up2"}, d.ran)
+
+	//second up is a no-op
+	ran, err = r.Up(ctx)
+	require.NoError(t, err)
+	require.Empty(t, ran)
+
+	ran, err = r.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, ran, 1)
+	require.Equal(t, 2, ran[0].Version)
+
+	st, err := r.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, st, 2)
+	require.NotNil(t, st[0].AppliedAt)
+	require.Nil(t, st[1].AppliedAt)
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	for _, name := range []string{"init.up.sql", "001_init.sql", "x_init.up.sql"} {
+		_, err := Load(fstest.MapFS{name: {Data: []byte("SELECT 1")}})
+		require.Error(t, err, name)
+	}
+	_, err := Load(fstest.MapFS{"001_init.down.sql": {Data: []byte("x")}})
+	require.Error(t, err, "down without up")
+}
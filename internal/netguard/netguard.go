@@ -0,0 +1,262 @@
+// Package netguard is the checker's egress policy: it decides which
+// addresses a check may connect to. It is enforced on the resolved IP at
+// dial time, so DNS rebinding and redirects to internal hosts are caught
+// as well as literal IPs in the target URL.
+package netguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// Rules is the user-facing configuration of a Policy
+type Rules struct {
+	AllowPrivate bool     // turn the built-in blocklist off
+	AllowCIDRs   []string // reachable even if in a blocked range
+	DenyCIDRs    []string // never reachable, wins over everything
+	AllowHosts   []string // "db.internal" or "*.internal"; any IP they resolve to is allowed
+	DenyHosts    []string
+}
+
+type Policy struct {
+	allowCIDRs, denyCIDRs []netip.Prefix
+	allowHosts, denyHosts []string
+	allowPrivate          bool
+}
+
+type blockedRange struct {
+	prefix netip.Prefix
+	name   string
+}
+
+// checked in order, so the metadata endpoints are reported as such
+var defaultBlocked = func() []blockedRange {
+	var out []blockedRange
+	add := func(name string, cidrs ...string) {
+		for _, c := range cidrs {
+			out = append(out, blockedRange{netip.MustParsePrefix(c), name})
+		}
+	}
+	add("cloud metadata", "169.254.169.254/32", "169.254.170.2/32", "100.100.100.200/32", "fd00:ec2::254/128")
+	add("unspecified", "0.0.0.0/8", "::/128")
+	add("loopback", "127.0.0.0/8", "::1/128")
+	add("private", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")
+	add("link-local", "169.254.0.0/16", "fe80::/10")
+	add("shared address space", "100.64.0.0/10")
+	add("multicast", "224.0.0.0/4", "ff00::/8")
+	add("reserved", "240.0.0.0/4")
+	return out
+}()
+
+// NAT64 addresses embed an IPv4 address in the last 4 bytes
+var nat64 = netip.MustParsePrefix("64:ff9b::/96")
+
+// New validates the rules
+func New(r Rules) (*Policy, error) {
+	p := &Policy{allowPrivate: r.AllowPrivate}
+	var err error
+	if p.allowCIDRs, err = parseCIDRs("allow_cidrs", r.AllowCIDRs); err != nil {
+		return nil, err
+	}
+	if p.denyCIDRs, err = parseCIDRs("deny_cidrs", r.DenyCIDRs); err != nil {
+		return nil, err
+	}
+	if p.allowHosts, err = parseHosts("allow_hosts", r.AllowHosts); err != nil {
+		return nil, err
+	}
+	if p.denyHosts, err = parseHosts("deny_hosts", r.DenyHosts); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Default blocks the built-in ranges and nothing else
+func Default() *Policy { return &Policy{} }
+
+// AllowAll permits every address (tests, trusted deployments)
+func AllowAll() *Policy { return &Policy{allowPrivate: true} }
+
+func parseCIDRs(field string, in []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(in))
+	for _, s := range in {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			a, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid address %q", field, s)
+			}
+			out = append(out, netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen()))
+			continue
+		}
+		pfx, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid CIDR %q", field, s)
+		}
+		out = append(out, pfx.Masked())
+	}
+	return out, nil
+}
+
+func parseHosts(field string, in []string) ([]string, error) {
+	out := make([]string, 0, len(in))
+	for _, h := range in {
+		h = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(h), "."))
+		if h == "" || h == "*" || strings.Contains(strings.TrimPrefix(h, "*."), "*") {
+			return nil, fmt.Errorf("%s: invalid host pattern %q (want host or *.domain)", field, h)
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+func matchHost(patterns []string, host string) bool {
+	for _, p := range patterns {
+		if suffix, ok := strings.CutPrefix(p, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == p {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockedError is returned from dials the policy refuses
+type BlockedError struct {
+	Host   string
+	IP     netip.Addr // invalid if the host name itself is denied
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	if !e.IP.IsValid() {
+		return fmt.Sprintf("blocked by egress policy: host %s is %s", e.Host, e.Reason)
+	}
+	if e.Host == "" || e.Host == e.IP.String() {
+		return fmt.Sprintf("blocked by egress policy: %s is %s", e.IP, e.Reason)
+	}
+	return fmt.Sprintf("blocked by egress policy: %s resolves to %s (%s)", e.Host, e.IP, e.Reason)
+}
+
+// trusted = explicitly allowed host, its addresses are not checked
+func (p *Policy) checkHostname(host string) (trusted bool, err error) {
+	h := strings.ToLower(strings.TrimSuffix(host, "."))
+	if matchHost(p.denyHosts, h) {
+		return false, &BlockedError{Host: host, Reason: "in egress deny_hosts"}
+	}
+	return matchHost(p.allowHosts, h), nil
+}
+
+// CheckIP reports why ip may not be dialed, nil if it may
+func (p *Policy) CheckIP(host string, ip netip.Addr) error {
+	ip = ip.Unmap().WithZone("") // zoned addresses never match a prefix
+	addrs := []netip.Addr{ip}
+	if nat64.Contains(ip) {
+		b := ip.As16()
+		addrs = append(addrs, netip.AddrFrom4([4]byte(b[12:])))
+	}
+	for _, a := range addrs {
+		for _, pfx := range p.denyCIDRs {
+			if pfx.Contains(a) {
+				return &BlockedError{Host: host, IP: ip, Reason: "in egress deny_cidrs"}
+			}
+		}
+	}
+	for _, a := range addrs {
+		for _, pfx := range p.allowCIDRs {
+			if pfx.Contains(a) {
+				return nil
+			}
+		}
+	}
+	if p.allowPrivate {
+		return nil
+	}
+	for _, a := range addrs {
+		for _, r := range defaultBlocked {
+			if r.prefix.Contains(a) {
+				return &BlockedError{Host: host, IP: ip, Reason: r.name}
+			}
+		}
+	}
+	return nil
+}
+
+// CheckHost is the early check for a target's host: deny_hosts and literal
+// IPs are rejected; names are only fully checked at dial time
+func (p *Policy) CheckHost(host string) error {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	trusted, err := p.checkHostname(host)
+	if err != nil || trusted {
+		return err
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return p.CheckIP(host, ip)
+	}
+	return nil
+}
+
+// CheckResolved resolves host (optionally host:port) and checks every address.
+// For connections another party makes, e.g. a proxy, where the dial check
+// only sees the proxy; a name that does not resolve is refused too
+func (p *Policy) CheckResolved(ctx context.Context, r *net.Resolver, host string) error {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	trusted, err := p.checkHostname(host)
+	if err != nil || trusted {
+		return err
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return p.CheckIP(host, ip)
+	}
+	if p.allowPrivate && len(p.denyCIDRs) == 0 {
+		//no address is blocked
+		return nil
+	}
+	ips, err := r.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return &BlockedError{Host: host, Reason: "not resolvable (" + err.Error() + ")"}
+	}
+	for _, ip := range ips {
+		if err := p.CheckIP(host, ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DialContext wraps base so every connection is checked against the policy
+// after name resolution (for http.Transport.DialContext)
+func (p *Policy) DialContext(base *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		trusted, err := p.checkHostname(host)
+		if err != nil {
+			return nil, err
+		}
+		d := *base
+		if !trusted {
+			d.Control = func(_, address string, _ syscall.RawConn) error {
+				ap, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return err
+				}
+				return p.CheckIP(host, ap.Addr())
+			}
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+}
@@ -0,0 +1,123 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultBlocksInternalRanges(t *testing.T) {
+	p := Default()
+	for ip, reason := range map[string]string{
+		"127.0.0.1":        "loopback",
+		"::1":              "loopback",
+		"169.254.169.254":  "cloud metadata",
+		"169.254.1.1":      "link-local",
+		"10.1.2.3":         "private",
+		"172.20.0.1":       "private",
+		"192.168.1.10":     "private",
+		"fd12::1":          "private",
+		"fe80::1%eth0":     "link-local",
+		"::ffff:127.0.0.1": "loopback",
+		"64:ff9b::a00:1":   "private",
+		"0.0.0.0":          "unspecified",
+		"100.100.100.200":  "cloud metadata",
+		"fd00:ec2::254":    "cloud metadata",
+		"239.255.255.250":  "multicast",
+	} {
+		err := p.CheckIP("", netip.MustParseAddr(ip))
+		var be *BlockedError
+		require.True(t, errors.As(err, &be), ip)
+		require.Equal(t, reason, be.Reason, ip)
+	}
+	for _, ip := range []string{"93.184.216.34", "2606:2800:220:1::1", "8.8.8.8"} {
+		require.NoError(t, p.CheckIP("", netip.MustParseAddr(ip)), ip)
+	}
+}
+
+func TestRulesPrecedence(t *testing.T) {
+	p, err := New(Rules{
+		AllowCIDRs: []string{"10.0.5.0/24", "127.0.0.1"},
+		DenyCIDRs:  []string{"10.0.5.7", "93.184.216.0/24"},
+		AllowHosts: []string{"*.corp.internal"},
+		DenyHosts:  []string{"evil.test"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, p.CheckIP("", netip.MustParseAddr("10.0.5.1")))
+	require.NoError(t, p.CheckIP("", netip.MustParseAddr("127.0.0.1")))
+	require.Error(t, p.CheckIP("", netip.MustParseAddr("10.0.5.7")), "deny wins over allow")
+	require.Error(t, p.CheckIP("", netip.MustParseAddr("10.0.6.1")))
+	require.ErrorContains(t, p.CheckIP("", netip.MustParseAddr("93.184.216.34")), "deny_cidrs")
+
+	require.ErrorContains(t, p.CheckHost("EVIL.test."), "deny_hosts")
+	require.NoError(t, p.CheckHost("db.corp.internal"))
+	require.Error(t, p.CheckHost("[::1]:8080"))
+	require.NoError(t, p.CheckHost("example.org"), "names are checked when dialing")
+
+	all, err := New(Rules{AllowPrivate: true, DenyCIDRs: []string{"169.254.169.254"}})
+	require.NoError(t, err)
+	require.NoError(t, all.CheckIP("", netip.MustParseAddr("10.0.0.1")))
+	require.Error(t, all.CheckIP("", netip.MustParseAddr("169.254.169.254")))
+
+	_, err = New(Rules{AllowCIDRs: []string{"10.0.0.0/33"}})
+	require.ErrorContains(t, err, "allow_cidrs")
+	_, err = New(Rules{DenyHosts: []string{"a.*.test"}})
+	require.ErrorContains(t, err, "deny_hosts")
+}
+
+func TestCheckResolved(t *testing.T) {
+	ctx := context.Background()
+	var be *BlockedError
+	err := Default().CheckResolved(ctx, net.DefaultResolver, "localhost:8080")
+	require.True(t, errors.As(err, &be), "got %v", err)
+	require.Equal(t, "loopback", be.Reason)
+	require.Error(t, Default().CheckResolved(ctx, net.DefaultResolver, "[fd00:ec2::254]:80"))
+	require.ErrorContains(t, Default().CheckResolved(ctx, net.DefaultResolver, "nothing.invalid"), "not resolvable")
+
+	require.NoError(t, AllowAll().CheckResolved(ctx, net.DefaultResolver, "nothing.invalid"))
+	trusted, err := New(Rules{AllowHosts: []string{"localhost"}})
+	require.NoError(t, err)
+	require.NoError(t, trusted.CheckResolved(ctx, net.DefaultResolver, "localhost"))
+}
+
+func TestDialContextChecksResolvedAddress(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	base := &net.Dialer{}
+
+	//"localhost" is only known to be loopback after resolution
+	_, err = Default().DialContext(base)(ctx, "tcp", net.JoinHostPort("localhost", port))
+	var be *BlockedError
+	require.True(t, errors.As(err, &be), "got %v", err)
+	require.Equal(t, "loopback", be.Reason)
+	require.Contains(t, be.Error(), "localhost resolves to")
+
+	c, err := AllowAll().DialContext(base)(ctx, "tcp", ln.Addr().String())
+	require.NoError(t, err)
+	c.Close()
+
+	trusted, err := New(Rules{AllowHosts: []string{"localhost"}})
+	require.NoError(t, err)
+	c, err = trusted.DialContext(base)(ctx, "tcp", net.JoinHostPort("localhost", port))
+	require.NoError(t, err)
+	c.Close()
+}
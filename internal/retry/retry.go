@@ -0,0 +1,238 @@
+// Package retry decides whether and when a failed check attempt is repeated.
+// The global policy (config "retry:") and per-target settings are both
+// Overrides applied over Default.
+package retry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// error classes for Policy.Errors
+const (
+	ErrTimeout    = "timeout"    // the attempt or a read/dial ran out of time
+	ErrConnection = "connection" // refused, reset, unreachable
+	ErrDNS        = "dns"        // resolving the target failed
+	ErrTLS        = "tls"        // handshake or certificate
+	ErrOther      = "other"      // anything else, e.g. an unmet expect
+)
+
+var ErrorClasses = []string{ErrTimeout, ErrConnection, ErrDNS, ErrTLS, ErrOther}
+
+type Policy struct {
+	MaxAttempts int           // 1 = no retries
+	BaseBackoff time.Duration // before the 2nd attempt, doubled for every later one
+	MaxBackoff  time.Duration // cap, also for Retry-After
+	Jitter      float64       // 0-1, up to this fraction of a delay is taken off at random
+	Statuses    []string      // retried status codes: "503" or a class like "5xx"
+	Errors      []string      // retried error classes
+	RetryAfter  bool          // a Retry-After header replaces the backoff
+}
+
+// Default keeps the checker's historic behaviour (3 attempts, 200ms then
+// 400ms, 5xx and network errors) and adds 429 with Retry-After. Certificate
+// and handshake failures do not go away by retrying, so tls is not included
+func Default() Policy {
+	return Policy{
+		MaxAttempts: 3,
+		BaseBackoff: 200 * time.Millisecond,
+		MaxBackoff:  10 * time.Second,
+		Statuses:    []string{"429", "5xx"},
+		Errors:      []string{ErrTimeout, ErrConnection, ErrDNS, ErrOther},
+		RetryAfter:  true,
+	}
+}
+
+// Overrides is the config/settings shape; unset fields and empty lists keep
+// the base policy (max_attempts: 1 turns retries off)
+type Overrides struct {
+	MaxAttempts int      `json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	BaseBackoff string   `json:"base_backoff,omitempty" yaml:"base_backoff,omitempty"` // Go duration
+	MaxBackoff  string   `json:"max_backoff,omitempty" yaml:"max_backoff,omitempty"`
+	Jitter      *float64 `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+	Statuses    []string `json:"statuses,omitempty" yaml:"statuses,omitempty"`
+	Errors      []string `json:"errors,omitempty" yaml:"errors,omitempty"`
+	RetryAfter  *bool    `json:"retry_after,omitempty" yaml:"retry_after,omitempty"`
+}
+
+func (o Overrides) IsZero() bool {
+	return o.MaxAttempts == 0 && o.BaseBackoff == "" && o.MaxBackoff == "" && o.Jitter == nil &&
+		len(o.Statuses) == 0 && len(o.Errors) == 0 && o.RetryAfter == nil
+}
+
+// Validate reports the first invalid field by its key
+func (o Overrides) Validate() error {
+	if o.MaxAttempts < 0 || o.MaxAttempts > 10 {
+		return fmt.Errorf("max_attempts: must be between 1 and 10, got %d", o.MaxAttempts)
+	}
+	for name, v := range map[string]string{"base_backoff": o.BaseBackoff, "max_backoff": o.MaxBackoff} {
+		if v == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err != nil || d < 0 {
+			return fmt.Errorf("%s: invalid duration %q", name, v)
+		}
+	}
+	if o.Jitter != nil && (*o.Jitter < 0 || *o.Jitter > 1) {
+		return fmt.Errorf("jitter: must be between 0 and 1, got %g", *o.Jitter)
+	}
+	for _, s := range o.Statuses {
+		if !validStatus(s) {
+			return fmt.Errorf("statuses: want a code like 503 or a class like 5xx, got %q", s)
+		}
+	}
+	for _, e := range o.Errors {
+		if !slices.Contains(ErrorClasses, e) {
+			return fmt.Errorf("errors: must be among %s, got %q", strings.Join(ErrorClasses, ", "), e)
+		}
+	}
+	return nil
+}
+
+// With applies o (may be nil) over p; o must be valid
+func (p Policy) With(o *Overrides) Policy {
+	if o == nil {
+		return p
+	}
+	if o.MaxAttempts > 0 {
+		p.MaxAttempts = o.MaxAttempts
+	}
+	if d, err := time.ParseDuration(o.BaseBackoff); err == nil {
+		p.BaseBackoff = d
+	}
+	if d, err := time.ParseDuration(o.MaxBackoff); err == nil {
+		p.MaxBackoff = d
+	}
+	if o.Jitter != nil {
+		p.Jitter = *o.Jitter
+	}
+	if len(o.Statuses) > 0 {
+		p.Statuses = o.Statuses
+	}
+	if len(o.Errors) > 0 {
+		p.Errors = o.Errors
+	}
+	if o.RetryAfter != nil {
+		p.RetryAfter = *o.RetryAfter
+	}
+	return p
+}
+
+// Next decides after failed attempt n (1-based): whether to try again and
+// how long to wait first. status is nil without an HTTP response; retryAfter
+// is the server's Retry-After, 0 if none
+func (p Policy) Next(n int, status *int, err error, retryAfter time.Duration) (time.Duration, bool) {
+	if n >= p.MaxAttempts {
+		return 0, false
+	}
+	switch {
+	case err != nil:
+		if !slices.Contains(p.Errors, Classify(err)) {
+			return 0, false
+		}
+	case status != nil:
+		if !slices.ContainsFunc(p.Statuses, func(s string) bool { return matchStatus(s, *status) }) {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if p.RetryAfter && retryAfter > 0 {
+		return min(retryAfter, p.MaxBackoff), true
+	}
+	return p.Backoff(n), true
+}
+
+// Backoff before attempt n+1: BaseBackoff·2^(n-1), capped, minus jitter
+func (p Policy) Backoff(n int) time.Duration {
+	d := p.BaseBackoff
+	for i := 1; i < n && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxBackoff)
+	if p.Jitter > 0 && d > 0 {
+		d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// Classify maps an attempt's error to one of ErrorClasses
+func Classify(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var recErr tls.RecordHeaderError
+	var authErr x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &dnsErr):
+		return ErrDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	case errors.As(err, &certErr), errors.As(err, &recErr), errors.As(err, &authErr),
+		errors.As(err, &hostErr), errors.As(err, &invalidErr), strings.Contains(err.Error(), "tls: "):
+		return ErrTLS
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH),
+		errors.Is(err, syscall.ECONNABORTED), errors.Is(err, syscall.EPIPE):
+		return ErrConnection
+	}
+	return ErrOther
+}
+
+// ParseRetryAfter reads delay-seconds or an HTTP date; 0 if absent or invalid
+func ParseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(s)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+// Sleep waits d or until ctx is done, whichever is first
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func validStatus(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	if strings.HasSuffix(s, "xx") {
+		return s[0] >= '1' && s[0] <= '5'
+	}
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 100 && n <= 599
+}
+
+func matchStatus(pattern string, code int) bool {
+	if strings.HasSuffix(pattern, "xx") {
+		return code/100 == int(pattern[0]-'0')
+	}
+	n, _ := strconv.Atoi(pattern)
+	return n == code
+}
@@ -0,0 +1,134 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNext(t *testing.T) {
+	p := Default()
+	code := func(n int) *int { return &n }
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	d, ok := p.Next(1, code(503), nil, 0)
+	require.True(t, ok)
+	require.Equal(t, 200*time.Millisecond, d)
+	d, ok = p.Next(2, code(500), nil, 0)
+	require.True(t, ok)
+	require.Equal(t, 400*time.Millisecond, d)
+	_, ok = p.Next(3, code(500), nil, 0)
+	require.False(t, ok, "max_attempts reached")
+
+	_, ok = p.Next(1, code(404), nil, 0)
+	require.False(t, ok)
+	_, ok = p.Next(1, code(200), nil, 0)
+	require.False(t, ok)
+	_, ok = p.Next(1, nil, nil, 0)
+	require.False(t, ok, "up without a status")
+
+	d, ok = p.Next(1, code(429), nil, 3*time.Second)
+	require.True(t, ok)
+	require.Equal(t, 3*time.Second, d, "Retry-After replaces the backoff")
+	d, _ = p.Next(1, code(429), nil, time.Hour)
+	require.Equal(t, p.MaxBackoff, d, "but is capped")
+
+	_, ok = p.Next(1, nil, fmt.Errorf("get: %w", refused), 0)
+	require.True(t, ok)
+	_, ok = p.Next(1, nil, errors.New("tls: failed to verify certificate"), 0)
+	require.False(t, ok, "tls is not retried by default")
+
+	p = p.With(&Overrides{Statuses: []string{"502"}, Errors: []string{ErrTLS}})
+	_, ok = p.Next(1, code(503), nil, 0)
+	require.False(t, ok)
+	_, ok = p.Next(1, code(502), nil, 0)
+	require.True(t, ok)
+	_, ok = p.Next(1, nil, refused, 0)
+	require.False(t, ok)
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	require.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second},
+		[]time.Duration{p.Backoff(1), p.Backoff(2), p.Backoff(3), p.Backoff(4), p.Backoff(5), p.Backoff(60)})
+
+	p.Jitter = 0.5
+	for range 100 {
+		d := p.Backoff(2)
+		require.GreaterOrEqual(t, d, 100*time.Millisecond)
+		require.LessOrEqual(t, d, 200*time.Millisecond)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for err, want := range map[error]string{
+		&net.DNSError{Err: "no such host", Name: "x.test", IsNotFound: true}: ErrDNS,
+		context.DeadlineExceeded:                                                   ErrTimeout,
+		&net.OpError{Op: "read", Err: &timeoutErr{}}:                               ErrTimeout,
+		&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}:                        ErrConnection,
+		fmt.Errorf("send: %w", &net.OpError{Op: "write", Err: syscall.ECONNRESET}): ErrConnection,
+		errors.New("tls: handshake failure"):                                       ErrTLS,
+		errors.New(`expected "220", got "554"`):                                    ErrOther,
+	} {
+		require.Equal(t, want, Classify(err), err.Error())
+	}
+}
+
+type timeoutErr struct{}
+
+func (*timeoutErr) Error() string   { return "i/o timeout" }
+func (*timeoutErr) Timeout() bool   { return true }
+func (*timeoutErr) Temporary() bool { return true }
+
+func TestOverrides(t *testing.T) {
+	jitter, off := 0.3, false
+	o := Overrides{MaxAttempts: 5, BaseBackoff: "1s", MaxBackoff: "1m", Jitter: &jitter, RetryAfter: &off}
+	require.NoError(t, o.Validate())
+	p := Default().With(&o)
+	require.Equal(t, 5, p.MaxAttempts)
+	require.Equal(t, time.Second, p.BaseBackoff)
+	require.Equal(t, time.Minute, p.MaxBackoff)
+	require.Equal(t, 0.3, p.Jitter)
+	require.False(t, p.RetryAfter)
+	require.Equal(t, Default().Statuses, p.Statuses, "unset fields are kept")
+	require.Equal(t, Default(), Default().With(nil))
+	require.True(t, Overrides{}.IsZero())
+	require.Equal(t, Default(), Default().With(&Overrides{Statuses: []string{}}), "an empty list is unset")
+
+	bad := 1.5
+	for o, want := range map[*Overrides]string{
+		{MaxAttempts: 11}:           "max_attempts",
+		{BaseBackoff: "soon"}:       "base_backoff",
+		{Jitter: &bad}:              "jitter",
+		{Statuses: []string{"6xx"}}: "statuses",
+		{Statuses: []string{"42"}}:  "statuses",
+		{Errors: []string{"dsn"}}:   "errors",
+	} {
+		require.ErrorContains(t, o.Validate(), want)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.Equal(t, 120*time.Second, ParseRetryAfter("120", now))
+	require.Equal(t, 30*time.Second, ParseRetryAfter("Fri, 02 Jan 2026 03:04:35 GMT", now))
+	require.Zero(t, ParseRetryAfter("Fri, 02 Jan 2026 03:00:00 GMT", now), "in the past")
+	require.Zero(t, ParseRetryAfter("", now))
+	require.Zero(t, ParseRetryAfter("soon", now))
+	require.Zero(t, ParseRetryAfter("-5", now))
+}
+
+func TestSleepHonoursContext(t *testing.T) {
+	require.NoError(t, Sleep(context.Background(), time.Millisecond))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	t0 := time.Now()
+	require.ErrorIs(t, Sleep(ctx, time.Hour), context.DeadlineExceeded)
+	require.Less(t, time.Since(t0), time.Second)
+}
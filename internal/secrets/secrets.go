@@ -0,0 +1,153 @@
+// Package secrets keeps check credentials encrypted at rest (AES-256-GCM with
+// an operator key from SECRETS_KEY or secrets_key_file) and resolves the
+// "${secret:name}" references target settings use instead of the values.
+// Values are only decrypted by the checker, right before a check.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// KeySize is the length of a key in bytes (AES-256)
+const KeySize = 32
+
+// Redacted replaces secret values in errors and API responses
+const Redacted = "[redacted]"
+
+var ErrNoKey = errors.New("no secrets key configured (SECRETS_KEY or secrets_key_file)")
+
+// Box seals and opens secret values with one key
+type Box struct {
+	aead cipher.AEAD
+}
+
+// ParseKey reads a key encoded as standard base64 or hex
+func ParseKey(s string) (*Box, error) {
+	s = strings.TrimSpace(s)
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != KeySize {
+		if key, err = hex.DecodeString(s); err != nil || len(key) != KeySize {
+			return nil, fmt.Errorf("want %d bytes as base64 or hex", KeySize)
+		}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// LoadKeyFile reads a key file in the form ParseKey accepts
+func LoadKeyFile(path string) (*Box, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKey(string(b))
+}
+
+// NewKey returns a random key, base64 encoded
+func NewKey() string {
+	b := make([]byte, KeySize)
+	_, _ = rand.Read(b) //never fails
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// the ciphertext only opens for the same project and name, so rows cannot be
+// swapped between secrets
+func additional(project, name string) []byte { return []byte(project + "\x00" + name) }
+
+// Seal encrypts value as the secret name of project: nonce || ciphertext
+func (b *Box) Seal(project, name string, value []byte) []byte {
+	nonce := make([]byte, b.aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	return b.aead.Seal(nonce, nonce, value, additional(project, name))
+}
+
+// Open decrypts what Seal returned for the same project and name
+func (b *Box) Open(project, name string, sealed []byte) ([]byte, error) {
+	n := b.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, errors.New("sealed value too short")
+	}
+	v, err := b.aead.Open(nil, sealed[:n], sealed[n:], additional(project, name))
+	if err != nil {
+		return nil, errors.New("cannot decrypt (wrong key?)")
+	}
+	return v, nil
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// ValidateName checks a secret name: letters, digits, "_", "." and "-", at
+// most 64 characters
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q (letters, digits, _ . -, at most 64)", name)
+	}
+	return nil
+}
+
+var refPattern = regexp.MustCompile(`\$\{secret:([^}]*)\}`)
+
+// Ref is the reference to name that Expand replaces
+func Ref(name string) string { return "${secret:" + name + "}" }
+
+// Refs lists the names referenced in s, in order
+func Refs(s string) []string {
+	var names []string
+	for _, m := range refPattern.FindAllStringSubmatch(s, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// ValidateRefs checks every name referenced in s
+func ValidateRefs(s string) error {
+	for _, name := range Refs(s) {
+		if err := ValidateName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Expand replaces the references in s with the values lookup returns
+func Expand(s string, lookup func(name string) (string, error)) (string, error) {
+	var err error
+	out := refPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		var v string
+		v, err = lookup(refPattern.FindStringSubmatch(ref)[1])
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// Redact replaces every occurrence of values in s
+func Redact(s string, values []string) string {
+	for _, v := range values {
+		if v != "" {
+			s = strings.ReplaceAll(s, v, Redacted)
+		}
+	}
+	return s
+}
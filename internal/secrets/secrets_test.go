@@ -0,0 +1,72 @@
+package secrets
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpen(t *testing.T) {
+	b, err := ParseKey(NewKey())
+	require.NoError(t, err)
+	sealed := b.Seal("p_default", "token", []byte("s3cret"))
+	require.NotContains(t, string(sealed), "s3cret")
+	v, err := b.Open("p_default", "token", sealed)
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", string(v))
+
+	//bound to project and name
+	_, err = b.Open("p_other", "token", sealed)
+	require.Error(t, err)
+	_, err = b.Open("p_default", "other", sealed)
+	require.Error(t, err)
+
+	other, err := ParseKey(NewKey())
+	require.NoError(t, err)
+	_, err = other.Open("p_default", "token", sealed)
+	require.ErrorContains(t, err, "wrong key")
+	_, err = b.Open("p_default", "token", sealed[:4])
+	require.Error(t, err)
+}
+
+func TestParseKey(t *testing.T) {
+	_, err := ParseKey(hex.EncodeToString(make([]byte, KeySize)))
+	require.NoError(t, err)
+	_, err = ParseKey("c2hvcnQ=")
+	require.ErrorContains(t, err, "32 bytes")
+
+	p := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(p, []byte(NewKey()+"\n"), 0o600))
+	_, err = LoadKeyFile(p)
+	require.NoError(t, err)
+	_, err = LoadKeyFile(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+}
+
+func TestRefs(t *testing.T) {
+	require.Equal(t, []string{"a", "b.c"}, Refs("Bearer ${secret:a} ${secret:b.c}"))
+	require.Nil(t, Refs("plain"))
+	require.NoError(t, ValidateRefs("${secret:api-token_1}"))
+	require.Error(t, ValidateRefs("${secret:}"))
+	require.Error(t, ValidateRefs("${secret:a b}"))
+	require.Error(t, ValidateName(strings.Repeat("a", 65)))
+
+	lookup := func(name string) (string, error) {
+		if name == "missing" {
+			return "", errors.New("secret missing not found")
+		}
+		return strings.ToUpper(name), nil
+	}
+	v, err := Expand("Bearer ${secret:tok}", lookup)
+	require.NoError(t, err)
+	require.Equal(t, "Bearer TOK", v)
+	_, err = Expand("${secret:tok}:${secret:missing}", lookup)
+	require.ErrorContains(t, err, "missing")
+
+	require.Equal(t, "auth failed for [redacted]", Redact("auth failed for TOK", []string{"TOK", ""}))
+}
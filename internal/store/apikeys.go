@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type APIKey struct {
+	ID         string     `json:"id"`
+	ProjectID  string     `json:"project_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // first characters of the key, for humans
+	Hash       string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+const apiKeyCols = `id, project_id, name, prefix, hash, scopes, created_at, last_used_at, revoked_at`
+
+func (p *Postgres) CreateAPIKey(ctx context.Context, k APIKey) error {
+	_, err := p.Pool.Exec(ctx, `
+		INSERT INTO api_keys (id, project_id, name, prefix, hash, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, k.ID, orDefaultProject(k.ProjectID), k.Name, k.Prefix, k.Hash, k.Scopes, k.CreatedAt)
+	return err
+}
+
+func scanPGAPIKey(row pgx.Row) (APIKey, error) {
+	var k APIKey
+	err := row.Scan(&k.ID, &k.ProjectID, &k.Name, &k.Prefix, &k.Hash, &k.Scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return k, ErrNotFound
+	}
+	return k, err
+}
+
+func (p *Postgres) GetAPIKeyByHash(ctx context.Context, hash string) (APIKey, error) {
+	return scanPGAPIKey(p.Pool.QueryRow(ctx, `SELECT `+apiKeyCols+` FROM api_keys WHERE hash = $1`, hash))
+}
+
+func (p *Postgres) ListAPIKeys(ctx context.Context, projectID string) ([]APIKey, error) {
+	rows, err := p.Pool.Query(ctx, `
+		SELECT `+apiKeyCols+` FROM api_keys WHERE $1 = '' OR project_id = $1 ORDER BY created_at, id
+	`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []APIKey{}
+	for rows.Next() {
+		k, err := scanPGAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+func (p *Postgres) RevokeAPIKey(ctx context.Context, id string) error {
+	ct, err := p.Pool.Exec(ctx, `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (p *Postgres) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	_, err := p.Pool.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, at)
+	return err
+}
+
+func (s *SQLite) CreateAPIKey(ctx context.Context, k APIKey) error {
+	_, err := s.DB.ExecContext(ctx, `
+		INSERT INTO api_keys (id, project_id, name, prefix, hash, scopes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, k.ID, orDefaultProject(k.ProjectID), k.Name, k.Prefix, k.Hash, sqliteJSON(k.Scopes), sqliteTime(k.CreatedAt))
+	return err
+}
+
+func scanSQLiteAPIKey(row rowScanner) (APIKey, error) {
+	var k APIKey
+	var scopes, created string
+	var lastUsed, revoked *string
+	if err := row.Scan(&k.ID, &k.ProjectID, &k.Name, &k.Prefix, &k.Hash, &scopes, &created, &lastUsed, &revoked); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return k, ErrNotFound
+		}
+		return k, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &k.Scopes); err != nil {
+		return k, err
+	}
+	var err error
+	if k.CreatedAt, err = parseSQLiteTime(created); err != nil {
+		return k, err
+	}
+	if k.LastUsedAt, err = parseSQLiteNullTime(lastUsed); err != nil {
+		return k, err
+	}
+	k.RevokedAt, err = parseSQLiteNullTime(revoked)
+	return k, err
+}
+
+func (s *SQLite) GetAPIKeyByHash(ctx context.Context, hash string) (APIKey, error) {
+	return scanSQLiteAPIKey(s.DB.QueryRowContext(ctx, `SELECT `+apiKeyCols+` FROM api_keys WHERE hash = ?`, hash))
+}
+
+func (s *SQLite) ListAPIKeys(ctx context.Context, projectID string) ([]APIKey, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT `+apiKeyCols+` FROM api_keys WHERE ? = '' OR project_id = ? ORDER BY created_at, id
+	`, projectID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []APIKey{}
+	for rows.Next() {
+		k, err := scanSQLiteAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLite) RevokeAPIKey(ctx context.Context, id string) error {
+	res, err := s.DB.ExecContext(ctx, `UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`,
+		sqliteTime(time.Now()), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLite) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	_, err := s.DB.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, sqliteTime(at), id)
+	return err
+}
@@ -12,16 +12,21 @@ import (
 var ErrIdemConflict = errors.New("idempotency key conflict")
 
 // checks if hash and key match
-func (p *Postgres) UpsertIdempotencyKey(ctx context.Context, key, requestHash, newID, canonURL, host string) (string, bool, error) {
+func (p *Postgres) UpsertIdempotencyKey(ctx context.Context, projectID, key, requestHash, newID, canonURL, host string) (string, bool, error) {
 	tx, err := p.Pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return "", false, err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
+	projectID = orDefaultProject(projectID)
+	//same-project requests queue here, so a concurrent retry sees the committed key
+	if _, err := pgLockProject(ctx, tx, projectID); err != nil {
+		return "", false, err
+	}
 
 	//existing key
 	var existingHash, existingTarget string
-	err = tx.QueryRow(ctx, `SELECT request_hash, target_id FROM idempotency_keys WHERE key = $1`, key).
+	err = tx.QueryRow(ctx, `SELECT request_hash, target_id FROM idempotency_keys WHERE project_id = $1 AND key = $2`, projectID, key).
 		Scan(&existingHash, &existingTarget)
 	switch {
 	case err == nil:
@@ -38,38 +43,27 @@ func (p *Postgres) UpsertIdempotencyKey(ctx context.Context, key, requestHash, n
 		return "", false, err
 	}
 
-	//target exists
-	var tid string
-	err = tx.QueryRow(ctx, `SELECT id FROM targets WHERE url = $1`, canonURL).Scan(&tid)
-	if errors.Is(err, pgx.ErrNoRows) {
-		//insert target
-		_, err = tx.Exec(ctx, `
-			INSERT INTO targets (id, url, host, created_at)
-			VALUES ($1, $2, $3, $4)
-			ON CONFLICT (url) DO NOTHING
-		`, newID, canonURL, host, time.Now().UTC())
-		if err != nil {
-			return "", false, err
-		}
-		//read id
-		if err = tx.QueryRow(ctx, `SELECT id FROM targets WHERE url = $1`, canonURL).Scan(&tid); err != nil {
-			return "", false, err
-		}
-	} else if err != nil {
+	//target exists or is created
+	t, _, err := pgEnsureTarget(ctx, tx, Target{
+		ID: newID, ProjectID: projectID, URL: canonURL, Host: host,
+		CreatedAt: time.Now(), Source: SourceAPI,
+	})
+	if err != nil {
 		return "", false, err
 	}
+	tid := t.ID
 
 	//idempotency mapping
 	if _, err = tx.Exec(ctx, `
-		INSERT INTO idempotency_keys (key, request_hash, target_id)
-		VALUES ($1, $2, $3)
-	`, key, requestHash, tid); err != nil {
+		INSERT INTO idempotency_keys (project_id, key, request_hash, target_id)
+		VALUES ($1, $2, $3, $4)
+	`, projectID, key, requestHash, tid); err != nil {
 
 		//23505 if concurrency issues
 		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
 			var h2, t2 string
 			if err2 := tx.QueryRow(ctx,
-				`SELECT request_hash, target_id FROM idempotency_keys WHERE key = $1`, key,
+				`SELECT request_hash, target_id FROM idempotency_keys WHERE project_id = $1 AND key = $2`, projectID, key,
 			).Scan(&h2, &t2); err2 == nil {
 				if h2 != requestHash {
 					return t2, true, ErrIdemConflict
@@ -43,16 +43,16 @@ func TestUpsertIdempotencyKey(t *testing.T) {
 	url2, host2 := "https://different.org/", "different.org"
 	key := "abc123"
 
-	tid1, existed, err := pg.UpsertIdempotencyKey(ctx, key, sha(url1), "t_new_1", url1, host1)
+	tid1, existed, err := pg.UpsertIdempotencyKey(ctx, DefaultProjectID, key, sha(url1), "t_new_1", url1, host1)
 	require.NoError(t, err)
 	require.False(t, existed)
 	require.NotEmpty(t, tid1)
 
-	tidAgain, existed, err := pg.UpsertIdempotencyKey(ctx, key, sha(url1), "ignored", url1, host1)
+	tidAgain, existed, err := pg.UpsertIdempotencyKey(ctx, DefaultProjectID, key, sha(url1), "ignored", url1, host1)
 	require.NoError(t, err)
 	require.True(t, existed)
 	require.Equal(t, tid1, tidAgain)
 
-	_, _, err = pg.UpsertIdempotencyKey(ctx, key, sha(url2), "t_new_2", url2, host2)
+	_, _, err = pg.UpsertIdempotencyKey(ctx, DefaultProjectID, key, sha(url2), "t_new_2", url2, host2)
 	require.ErrorIs(t, err, ErrIdemConflict)
 }
@@ -9,11 +9,15 @@ import (
 )
 
 // returns up to limit targets
-func (p *Postgres) ListTargets(ctx context.Context, host *string, after *api.Cursor, limit int) (items []Target, next *api.Cursor, err error) {
+func (p *Postgres) ListTargets(ctx context.Context, projectID string, host *string, after *api.Cursor, limit int) (items []Target, next *api.Cursor, err error) {
 	args := []any{}
-	q := `SELECT id, url, host, created_at FROM targets`
+	q := `SELECT ` + targetCols + ` FROM targets`
 
-	conds := []string{}
+	conds := []string{"archived_at IS NULL"}
+	if projectID != "" {
+		conds = append(conds, "project_id = $"+strconv.Itoa(len(args)+1))
+		args = append(args, projectID)
+	}
 	//filter
 	if host != nil && *host != "" {
 		conds = append(conds, "host = $"+strconv.Itoa(len(args)+1))
@@ -25,9 +29,7 @@ func (p *Postgres) ListTargets(ctx context.Context, host *string, after *api.Cur
 		args = append(args, after.CreatedAt, after.ID)
 	}
 	//where
-	if len(conds) > 0 {
-		q += " WHERE " + strings.Join(conds, " AND ")
-	}
+	q += " WHERE " + strings.Join(conds, " AND ")
 
 	//order and limit
 	args = append(args, limit+1)
@@ -42,8 +44,8 @@ func (p *Postgres) ListTargets(ctx context.Context, host *string, after *api.Cur
 	items = make([]Target, 0, limit+1)
 
 	for rows.Next() {
-		var t Target
-		if err := rows.Scan(&t.ID, &t.URL, &t.Host, &t.CreatedAt); err != nil {
+		t, err := scanPGTarget(rows)
+		if err != nil {
 			return nil, nil, err
 		}
 		items = append(items, t)
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/migrate"
+	"github.com/nurzh/linkwatch/migrations"
+)
+
+// arbitrary key shared by every linkwatch process
+const migrationLockKey = 7_305_118_211
+
+// migration runner for the store's dialect
+func Migrator(s Store) (*migrate.Runner, error) {
+	switch db := s.(type) {
+	case *Postgres:
+		return migrate.New(pgMigrations{db}, migrations.Postgres)
+	case *SQLite:
+		return migrate.New(sqliteMigrations{db}, migrations.SQLite)
+	default:
+		return nil, errors.New("store has no migrations")
+	}
+}
+
+type pgMigrations struct{ p *Postgres }
+
+func (m pgMigrations) Lock(ctx context.Context) (func(), error) {
+	conn, err := m.p.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, int64(migrationLockKey)); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	return func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, int64(migrationLockKey))
+		conn.Release()
+	}, nil
+}
+
+func (m pgMigrations) EnsureVersionTable(ctx context.Context) error {
+	_, err := m.p.Pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		  version INT PRIMARY KEY,
+		  name TEXT NOT NULL,
+		  applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`)
+	return err
+}
+
+func (m pgMigrations) AppliedVersions(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := m.p.Pool.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int]time.Time{}
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		out[v] = at
+	}
+	return out, rows.Err()
+}
+
+func (m pgMigrations) Apply(ctx context.Context, version int, name, sql string, up bool) error {
+	tx, err := m.p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	//no args → simple protocol, multiple statements allowed
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if up {
+		_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, version, name)
+	} else {
+		_, err = tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SQLite has no advisory locks: a process-local mutex plus a re-check of the
+// version inside each write transaction keeps concurrent runners safe.
+var sqliteMigrateMu sync.Mutex
+
+type sqliteMigrations struct{ s *SQLite }
+
+func (m sqliteMigrations) Lock(ctx context.Context) (func(), error) {
+	sqliteMigrateMu.Lock()
+	return sqliteMigrateMu.Unlock, nil
+}
+
+func (m sqliteMigrations) EnsureVersionTable(ctx context.Context) error {
+	_, err := m.s.DB.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		  version INTEGER PRIMARY KEY,
+		  name TEXT NOT NULL,
+		  applied_at TEXT NOT NULL
+		)
+	`)
+	return err
+}
+
+func (m sqliteMigrations) AppliedVersions(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := m.s.DB.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int]time.Time{}
+	for rows.Next() {
+		var v int
+		var at string
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		t, err := parseSQLiteTime(at)
+		if err != nil {
+			return nil, err
+		}
+		out[v] = t
+	}
+	return out, rows.Err()
+}
+
+// a migration starting with this line runs with foreign keys disabled, which
+// SQLite needs for table rebuilds (DROP TABLE would otherwise cascade)
+const sqliteFKOff = "-- +foreign_keys off"
+
+func (m sqliteMigrations) Apply(ctx context.Context, version int, name, sql string, up bool) error {
+	if strings.HasPrefix(sql, sqliteFKOff) {
+		//pragma is a no-op inside a tx; single connection, so it sticks
+		if _, err := m.s.DB.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return err
+		}
+		defer func() { _, _ = m.s.DB.ExecContext(context.Background(), `PRAGMA foreign_keys = ON`) }()
+	}
+
+	tx, err := m.s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT count(*) FROM schema_migrations WHERE version = ?`, version).Scan(&n); err != nil {
+		return err
+	}
+	if (n > 0) == up {
+		//another runner got here first
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, sql); err != nil {
+		return err
+	}
+	var violations int
+	if err := tx.QueryRowContext(ctx, `SELECT count(*) FROM pragma_foreign_key_check`).Scan(&violations); err != nil {
+		return err
+	}
+	if violations > 0 {
+		return errors.New("migration leaves foreign key violations")
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			version, name, sqliteTime(time.Now()))
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -53,19 +53,19 @@ func TestListTargets_PaginationStable(t *testing.T) {
 	for _, r := range rs {
 		_, err := pool.Exec(ctx, `
 			INSERT INTO targets (id, url, host, created_at) VALUES ($1,$2,$3,$4)
-			ON CONFLICT (url) DO NOTHING
+			ON CONFLICT (project_id, url) DO NOTHING
 		`, r.id, r.url, r.host, r.at)
 		require.NoError(t, err)
 	}
 
 	//page 1
-	items1, next, err := pg.ListTargets(ctx, nil, nil, 2)
+	items1, next, err := pg.ListTargets(ctx, "", nil, nil, 2)
 	require.NoError(t, err)
 	require.Len(t, items1, 2)
 	require.NotNil(t, next)
 
 	//page 2
-	items2, next2, err := pg.ListTargets(ctx, nil, next, 2)
+	items2, next2, err := pg.ListTargets(ctx, "", nil, next, 2)
 	require.NoError(t, err)
 	require.Len(t, items2, 1)
 	require.Nil(t, next2)
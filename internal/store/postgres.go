@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -13,33 +14,147 @@ type Postgres struct {
 	Pool *pgxpool.Pool
 }
 
-// insert or return existing url
-func (p *Postgres) CreateOrGetTarget(ctx context.Context, id, canonURL, host string) (Target, bool, error) {
+func OpenPostgres(ctx context.Context, dsn string) (*Postgres, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &Postgres{Pool: pool}, nil
+}
+
+func (p *Postgres) Ping(ctx context.Context) error { return p.Pool.Ping(ctx) }
+
+func (p *Postgres) Close() { p.Pool.Close() }
+
+// insert or return the project's existing target for the url
+func (p *Postgres) CreateOrGetTarget(ctx context.Context, projectID, id, canonURL, host string) (Target, bool, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return Target{}, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	t, created, err := pgEnsureTarget(ctx, tx, Target{
+		ID: id, ProjectID: orDefaultProject(projectID), URL: canonURL, Host: host,
+		CreatedAt: time.Now(), Source: SourceAPI,
+	})
+	if err != nil {
+		return Target{}, false, err
+	}
+	return t, created, tx.Commit(ctx)
+}
+
+const targetCols = `id, project_id, url, host, created_at, labels, settings, source, archived_at`
+
+func scanPGTarget(row pgx.Row) (Target, error) {
 	var t Target
-	//try inserting
-	ct := time.Now().UTC()
-	_, err := p.Pool.Exec(ctx, `
-		INSERT INTO targets (id, url, host, created_at)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (url) DO NOTHING
-	`, id, canonURL, host, ct)
-	if err != nil {
-		return t, false, err
-	}
-
-	//read row
-	row := p.Pool.QueryRow(ctx, `
-		SELECT id, url, host, created_at
-		FROM targets
-		WHERE url = $1
-	`, canonURL)
-	if err := row.Scan(&t.ID, &t.URL, &t.Host, &t.CreatedAt); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return t, false, errors.New("failed to read target after insert/select")
+	err := row.Scan(&t.ID, &t.ProjectID, &t.URL, &t.Host, &t.CreatedAt, &t.Labels, &t.Settings, &t.Source, &t.ArchivedAt)
+	return t, err
+}
+
+func (p *Postgres) GetTarget(ctx context.Context, id string) (Target, error) {
+	t, err := scanPGTarget(p.Pool.QueryRow(ctx, `SELECT `+targetCols+` FROM targets WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+func (p *Postgres) GetTargetByURL(ctx context.Context, projectID, url string) (Target, error) {
+	t, err := scanPGTarget(p.Pool.QueryRow(ctx,
+		`SELECT `+targetCols+` FROM targets WHERE project_id = $1 AND url = $2`, orDefaultProject(projectID), url))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+func (p *Postgres) InsertTarget(ctx context.Context, t Target) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	t.ProjectID = orDefaultProject(t.ProjectID)
+	if _, created, err := pgEnsureTarget(ctx, tx, t); err != nil {
+		return err
+	} else if !created {
+		return fmt.Errorf("target %s already exists in project %s", t.URL, t.ProjectID)
+	}
+	return tx.Commit(ctx)
+}
+
+// unarchiving counts against the project quota again
+func (p *Postgres) UpdateTarget(ctx context.Context, t Target) error {
+	if t.Labels == nil {
+		t.Labels = map[string]string{}
+	}
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var projectID string
+	var archived *time.Time
+	err = tx.QueryRow(ctx, `SELECT project_id, archived_at FROM targets WHERE id = $1`, t.ID).Scan(&projectID, &archived)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	if archived != nil && t.ArchivedAt == nil {
+		max, err := pgLockProject(ctx, tx, projectID)
+		if err != nil {
+			return err
+		}
+		if err := pgCheckQuota(ctx, tx, projectID, max); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE targets SET labels = $2, settings = $3, archived_at = $4 WHERE id = $1
+	`, t.ID, t.Labels, t.Settings, t.ArchivedAt); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *Postgres) ListTargetsBySource(ctx context.Context, source string) ([]Target, error) {
+	rows, err := p.Pool.Query(ctx, `SELECT `+targetCols+` FROM targets WHERE source = $1 ORDER BY project_id, created_at, id`, source)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Target{}
+	for rows.Next() {
+		t, err := scanPGTarget(rows)
+		if err != nil {
+			return nil, err
 		}
-		return t, false, err
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// removes the target, its results and idempotency keys
+func (p *Postgres) DeleteTarget(ctx context.Context, id string) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	created := (t.ID == id)
-	return t, created, nil
+	if _, err := tx.Exec(ctx, `DELETE FROM idempotency_keys WHERE target_id = $1`, id); err != nil {
+		return err
+	}
+	ct, err := tx.Exec(ctx, `DELETE FROM targets WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return tx.Commit(ctx)
 }
@@ -0,0 +1,298 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// owns every row that existed before projects were introduced
+const (
+	DefaultProjectID   = "p_default"
+	DefaultProjectName = "default"
+)
+
+var ErrQuotaExceeded = errors.New("project target quota exceeded")
+
+type Project struct {
+	ID                 string    `json:"id"`
+	Name               string    `json:"name"`
+	MaxTargets         *int      `json:"max_targets,omitempty"`                // nil = unlimited
+	MinIntervalSeconds *int      `json:"min_check_interval_seconds,omitempty"` // nil = global interval
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// 0 if the project has no minimum
+func (p Project) MinInterval() time.Duration {
+	if p.MinIntervalSeconds == nil {
+		return 0
+	}
+	return time.Duration(*p.MinIntervalSeconds) * time.Second
+}
+
+// FieldError names the JSON field a Validate failure is about
+type FieldError struct {
+	Field, Message string
+}
+
+func (e *FieldError) Error() string { return e.Field + " " + e.Message }
+
+func (p Project) Validate() error {
+	if p.Name == "" {
+		return &FieldError{"name", "is required"}
+	}
+	if p.MaxTargets != nil && *p.MaxTargets < 0 {
+		return &FieldError{"max_targets", "must be >= 0"}
+	}
+	if p.MinIntervalSeconds != nil && *p.MinIntervalSeconds <= 0 {
+		return &FieldError{"min_check_interval_seconds", "must be > 0"}
+	}
+	return nil
+}
+
+func quotaErr(max, active int) error {
+	if active >= max {
+		return fmt.Errorf("%w (%d of %d)", ErrQuotaExceeded, active, max)
+	}
+	return nil
+}
+
+func orDefaultProject(id string) string {
+	if id == "" {
+		return DefaultProjectID
+	}
+	return id
+}
+
+const projectCols = `id, name, max_targets, min_check_interval_seconds, created_at`
+
+func (p *Postgres) CreateProject(ctx context.Context, pr Project) error {
+	_, err := p.Pool.Exec(ctx, `
+		INSERT INTO projects (id, name, max_targets, min_check_interval_seconds, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, pr.ID, pr.Name, pr.MaxTargets, pr.MinIntervalSeconds, pr.CreatedAt)
+	return err
+}
+
+func scanPGProject(row pgx.Row) (Project, error) {
+	var pr Project
+	err := row.Scan(&pr.ID, &pr.Name, &pr.MaxTargets, &pr.MinIntervalSeconds, &pr.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pr, ErrNotFound
+	}
+	return pr, err
+}
+
+func (p *Postgres) GetProject(ctx context.Context, id string) (Project, error) {
+	return scanPGProject(p.Pool.QueryRow(ctx, `SELECT `+projectCols+` FROM projects WHERE id = $1`, id))
+}
+
+func (p *Postgres) GetProjectByName(ctx context.Context, name string) (Project, error) {
+	return scanPGProject(p.Pool.QueryRow(ctx, `SELECT `+projectCols+` FROM projects WHERE name = $1`, name))
+}
+
+func (p *Postgres) ListProjects(ctx context.Context) ([]Project, error) {
+	rows, err := p.Pool.Query(ctx, `SELECT `+projectCols+` FROM projects ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Project{}
+	for rows.Next() {
+		pr, err := scanPGProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pr)
+	}
+	return out, rows.Err()
+}
+
+func (p *Postgres) UpdateProject(ctx context.Context, pr Project) error {
+	ct, err := p.Pool.Exec(ctx, `
+		UPDATE projects SET name = $2, max_targets = $3, min_check_interval_seconds = $4 WHERE id = $1
+	`, pr.ID, pr.Name, pr.MaxTargets, pr.MinIntervalSeconds)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// locks the project row so concurrent creates in one project are counted one at a time
+func pgLockProject(ctx context.Context, tx pgx.Tx, projectID string) (max *int, err error) {
+	err = tx.QueryRow(ctx, `SELECT max_targets FROM projects WHERE id = $1 FOR UPDATE`, projectID).Scan(&max)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("project %s: %w", projectID, ErrNotFound)
+	}
+	return max, err
+}
+
+func pgCheckQuota(ctx context.Context, tx pgx.Tx, projectID string, max *int) error {
+	if max == nil {
+		return nil
+	}
+	var n int
+	if err := tx.QueryRow(ctx, `
+		SELECT count(*) FROM targets WHERE project_id = $1 AND archived_at IS NULL
+	`, projectID).Scan(&n); err != nil {
+		return err
+	}
+	return quotaErr(*max, n)
+}
+
+// returns the project's target for t.URL, or inserts t if the quota allows it
+func pgEnsureTarget(ctx context.Context, tx pgx.Tx, t Target) (Target, bool, error) {
+	max, err := pgLockProject(ctx, tx, t.ProjectID)
+	if err != nil {
+		return Target{}, false, err
+	}
+	existing, err := scanPGTarget(tx.QueryRow(ctx,
+		`SELECT `+targetCols+` FROM targets WHERE project_id = $1 AND url = $2`, t.ProjectID, t.URL))
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return Target{}, false, err
+	}
+	if t.ArchivedAt == nil {
+		if err := pgCheckQuota(ctx, tx, t.ProjectID, max); err != nil {
+			return Target{}, false, err
+		}
+	}
+	if t.Labels == nil {
+		t.Labels = map[string]string{}
+	}
+	t.CreatedAt = t.CreatedAt.UTC().Truncate(time.Microsecond) // as stored
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO targets (id, project_id, url, host, created_at, labels, settings, source, archived_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, t.ID, t.ProjectID, t.URL, t.Host, t.CreatedAt, t.Labels, t.Settings, t.Source, t.ArchivedAt); err != nil {
+		return Target{}, false, err
+	}
+	return t, true, nil
+}
+
+func (s *SQLite) CreateProject(ctx context.Context, pr Project) error {
+	_, err := s.DB.ExecContext(ctx, `
+		INSERT INTO projects (id, name, max_targets, min_check_interval_seconds, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, pr.ID, pr.Name, pr.MaxTargets, pr.MinIntervalSeconds, sqliteTime(pr.CreatedAt))
+	return err
+}
+
+func scanSQLiteProject(row rowScanner) (Project, error) {
+	var pr Project
+	var created string
+	if err := row.Scan(&pr.ID, &pr.Name, &pr.MaxTargets, &pr.MinIntervalSeconds, &created); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pr, ErrNotFound
+		}
+		return pr, err
+	}
+	var err error
+	pr.CreatedAt, err = parseSQLiteTime(created)
+	return pr, err
+}
+
+func (s *SQLite) GetProject(ctx context.Context, id string) (Project, error) {
+	return scanSQLiteProject(s.DB.QueryRowContext(ctx, `SELECT `+projectCols+` FROM projects WHERE id = ?`, id))
+}
+
+func (s *SQLite) GetProjectByName(ctx context.Context, name string) (Project, error) {
+	return scanSQLiteProject(s.DB.QueryRowContext(ctx, `SELECT `+projectCols+` FROM projects WHERE name = ?`, name))
+}
+
+func (s *SQLite) ListProjects(ctx context.Context) ([]Project, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+projectCols+` FROM projects ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Project{}
+	for rows.Next() {
+		pr, err := scanSQLiteProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pr)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLite) UpdateProject(ctx context.Context, pr Project) error {
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE projects SET name = ?, max_targets = ?, min_check_interval_seconds = ? WHERE id = ?
+	`, pr.Name, pr.MaxTargets, pr.MinIntervalSeconds, pr.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// no row lock needed: the single connection already serializes transactions
+func sqliteProjectQuota(ctx context.Context, tx *sql.Tx, projectID string) (max *int, err error) {
+	err = tx.QueryRowContext(ctx, `SELECT max_targets FROM projects WHERE id = ?`, projectID).Scan(&max)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("project %s: %w", projectID, ErrNotFound)
+	}
+	return max, err
+}
+
+func sqliteCheckQuota(ctx context.Context, tx *sql.Tx, projectID string, max *int) error {
+	if max == nil {
+		return nil
+	}
+	var n int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT count(*) FROM targets WHERE project_id = ? AND archived_at IS NULL
+	`, projectID).Scan(&n); err != nil {
+		return err
+	}
+	return quotaErr(*max, n)
+}
+
+// same contract as pgEnsureTarget
+func sqliteEnsureTarget(ctx context.Context, tx *sql.Tx, t Target) (Target, bool, error) {
+	max, err := sqliteProjectQuota(ctx, tx, t.ProjectID)
+	if err != nil {
+		return Target{}, false, err
+	}
+	existing, err := scanSQLiteTarget(tx.QueryRowContext(ctx,
+		`SELECT `+sqliteTargetCols+` FROM targets WHERE project_id = ? AND url = ?`, t.ProjectID, t.URL))
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Target{}, false, err
+	}
+	if t.ArchivedAt == nil {
+		if err := sqliteCheckQuota(ctx, tx, t.ProjectID, max); err != nil {
+			return Target{}, false, err
+		}
+	}
+	if t.Labels == nil {
+		t.Labels = map[string]string{}
+	}
+	t.CreatedAt = t.CreatedAt.UTC().Truncate(time.Microsecond) // as stored
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO targets (id, project_id, url, host, created_at, labels, settings, source, archived_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.ID, t.ProjectID, t.URL, t.Host, sqliteTime(t.CreatedAt), sqliteJSON(t.Labels), sqliteJSON(t.Settings),
+		t.Source, sqliteNullTime(t.ArchivedAt)); err != nil {
+		return Target{}, false, err
+	}
+	if len(t.Labels) == 0 {
+		t.Labels = nil
+	}
+	return t, true, nil
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_ProjectsIsolationAndQuota(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+
+	def, err := s.GetProjectByName(ctx, DefaultProjectName)
+	require.NoError(t, err)
+	require.Equal(t, DefaultProjectID, def.ID)
+
+	two := 2
+	require.NoError(t, s.CreateProject(ctx, Project{ID: "p_team", Name: "team", MaxTargets: &two, CreatedAt: time.Now()}))
+
+	//same url in two projects, two targets
+	a, created, err := s.CreateOrGetTarget(ctx, DefaultProjectID, "t_a", "https://a.test/", "a.test")
+	require.NoError(t, err)
+	require.True(t, created)
+	b, created, err := s.CreateOrGetTarget(ctx, "p_team", "t_b", "https://a.test/", "a.test")
+	require.NoError(t, err)
+	require.True(t, created)
+	require.NotEqual(t, a.ID, b.ID)
+	require.Equal(t, "p_team", b.ProjectID)
+
+	again, created, err := s.CreateOrGetTarget(ctx, "p_team", "t_x", "https://a.test/", "a.test")
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, b.ID, again.ID)
+
+	items, _, err := s.ListTargets(ctx, "p_team", nil, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	all, _, err := s.ListTargets(ctx, "", nil, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	//idempotency keys are per project too
+	tid, existed, err := s.UpsertIdempotencyKey(ctx, DefaultProjectID, "k", sha(a.URL), "ignored", a.URL, a.Host)
+	require.NoError(t, err)
+	require.False(t, existed)
+	require.Equal(t, a.ID, tid)
+	tid, existed, err = s.UpsertIdempotencyKey(ctx, "p_team", "k", sha(a.URL), "ignored", a.URL, a.Host)
+	require.NoError(t, err)
+	require.False(t, existed)
+	require.Equal(t, b.ID, tid)
+
+	//quota: second target fits, third does not, archived ones do not count
+	c, _, err := s.CreateOrGetTarget(ctx, "p_team", "t_c", "https://c.test/", "c.test")
+	require.NoError(t, err)
+	_, _, err = s.CreateOrGetTarget(ctx, "p_team", "t_d", "https://d.test/", "d.test")
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+	_, _, err = s.UpsertIdempotencyKey(ctx, "p_team", "k2", sha("https://d.test/"), "t_d", "https://d.test/", "d.test")
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+
+	now := time.Now()
+	c.ArchivedAt = &now
+	require.NoError(t, s.UpdateTarget(ctx, c))
+	_, created, err = s.CreateOrGetTarget(ctx, "p_team", "t_d", "https://d.test/", "d.test")
+	require.NoError(t, err)
+	require.True(t, created)
+	c.ArchivedAt = nil
+	require.ErrorIs(t, s.UpdateTarget(ctx, c), ErrQuotaExceeded, "unarchiving counts against the quota")
+
+	_, _, err = s.CreateOrGetTarget(ctx, "p_missing", "t_e", "https://e.test/", "e.test")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	//keys and uptime are filtered by project
+	require.NoError(t, s.CreateAPIKey(ctx, APIKey{ID: "k1", ProjectID: "p_team", Name: "ci", Prefix: "lw_1", Hash: "h1", Scopes: []string{"admin"}, CreatedAt: now}))
+	require.NoError(t, s.CreateAPIKey(ctx, APIKey{ID: "k2", Name: "ops", Prefix: "lw_2", Hash: "h2", Scopes: []string{"admin"}, CreatedAt: now}))
+	keys, err := s.ListAPIKeys(ctx, "p_team")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.Equal(t, "k1", keys[0].ID)
+	keys, err = s.ListAPIKeys(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Equal(t, DefaultProjectID, keys[1].ProjectID)
+
+	rows, err := s.Uptime(ctx, DefaultProjectID, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, a.ID, rows[0].TargetID)
+
+	//quota changes
+	ten := 10
+	team, err := s.GetProject(ctx, "p_team")
+	require.NoError(t, err)
+	team.MaxTargets, team.MinIntervalSeconds = nil, &ten
+	require.NoError(t, s.UpdateProject(ctx, team))
+	team, err = s.GetProject(ctx, "p_team")
+	require.NoError(t, err)
+	require.Nil(t, team.MaxTargets)
+	require.Equal(t, 10*time.Second, team.MinInterval())
+	require.NoError(t, s.UpdateTarget(ctx, c))
+}
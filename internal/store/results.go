@@ -2,24 +2,70 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// errors of results recorded instead of a check (e.g. while a host's circuit
+// is open) start with this; uptime leaves them out
+const SkippedPrefix = "skipped: "
+
 type CheckResult struct {
 	TargetID   string    `json:"target_id"`
 	CheckedAt  time.Time `json:"checked_at"`
 	StatusCode *int      `json:"status_code,omitempty"`
 	LatencyMS  *int      `json:"latency_ms,omitempty"`
 	Error      *string   `json:"error,omitempty"`
+	// probe specific, e.g. {"type":"A","answers":[...]} for dns: targets
+	Details json.RawMessage `json:"details,omitempty"`
+	// every try in order, the last one is the result above
+	Attempts []Attempt `json:"attempts,omitempty"`
+}
+
+// no check was made, see SkippedPrefix
+func (r CheckResult) Skipped() bool {
+	return r.Error != nil && strings.HasPrefix(*r.Error, SkippedPrefix)
+}
+
+type Attempt struct {
+	Attempt    int     `json:"attempt"` // 1-based
+	StatusCode *int    `json:"status_code,omitempty"`
+	LatencyMS  *int    `json:"latency_ms,omitempty"`
+	Error      *string `json:"error,omitempty"`
+}
+
+// nil for NULL
+func (r CheckResult) detailsArg() []byte {
+	if len(r.Details) == 0 {
+		return nil
+	}
+	return r.Details
+}
+
+// JSON, nil for NULL
+func (r CheckResult) attemptsArg() []byte {
+	if len(r.Attempts) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(r.Attempts)
+	return b
+}
+
+func (r *CheckResult) scanAttempts(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, &r.Attempts)
 }
 
 // store check result
 func (p *Postgres) AppendCheckResult(ctx context.Context, r CheckResult) error {
 	_, err := p.Pool.Exec(ctx, `
-		INSERT INTO check_results (target_id, checked_at, status_code, latency_ms, error)
-		VALUES ($1, $2, $3, $4, $5)
-	`, r.TargetID, r.CheckedAt, r.StatusCode, r.LatencyMS, r.Error)
+		INSERT INTO check_results (target_id, checked_at, status_code, latency_ms, error, details, attempts)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, r.TargetID, r.CheckedAt, r.StatusCode, r.LatencyMS, r.Error, r.detailsArg(), r.attemptsArg())
 	return err
 }
 
@@ -27,7 +73,7 @@ func (p *Postgres) AppendCheckResult(ctx context.Context, r CheckResult) error {
 func (p *Postgres) ListResults(ctx context.Context, targetID string, since *time.Time, limit int) ([]CheckResult, error) {
 	args := []any{targetID}
 	q := `
-		SELECT target_id, checked_at, status_code, latency_ms, error
+		SELECT target_id, checked_at, status_code, latency_ms, error, details, attempts
 		FROM check_results
 		WHERE target_id = $1
 	`
@@ -48,10 +94,63 @@ func (p *Postgres) ListResults(ctx context.Context, targetID string, since *time
 	out := make([]CheckResult, 0, limit)
 	for rows.Next() {
 		var r CheckResult
-		if err := rows.Scan(&r.TargetID, &r.CheckedAt, &r.StatusCode, &r.LatencyMS, &r.Error); err != nil {
+		var details, attempts []byte
+		if err := rows.Scan(&r.TargetID, &r.CheckedAt, &r.StatusCode, &r.LatencyMS, &r.Error, &details, &attempts); err != nil {
+			return nil, err
+		}
+		r.Details = details
+		if err := r.scanAttempts(attempts); err != nil {
 			return nil, err
 		}
 		out = append(out, r)
 	}
 	return out, rows.Err()
 }
+
+// per-target availability since a point in time
+type UptimeRow struct {
+	TargetID     string   `json:"target_id"`
+	URL          string   `json:"url"`
+	Checks       int      `json:"checks"`
+	Up           int      `json:"up"`
+	AvgLatencyMS *float64 `json:"avg_latency_ms,omitempty"`
+}
+
+// up = no error and, for HTTP, a status below 400
+func (r UptimeRow) Percent() float64 {
+	if r.Checks == 0 {
+		return 0
+	}
+	return 100 * float64(r.Up) / float64(r.Checks)
+}
+
+const uptimeSelect = `
+	SELECT t.id, t.url,
+	       count(r.target_id),
+	       count(CASE WHEN r.target_id IS NOT NULL AND r.error IS NULL AND (r.status_code IS NULL OR r.status_code < 400) THEN 1 END),
+	       CAST(avg(r.latency_ms) AS DOUBLE PRECISION)
+	FROM targets t
+	LEFT JOIN check_results r ON r.target_id = t.id AND (r.error IS NULL OR r.error NOT LIKE '` + SkippedPrefix + `%')
+	                          AND r.checked_at >= `
+
+func (p *Postgres) Uptime(ctx context.Context, projectID string, since time.Time) ([]UptimeRow, error) {
+	rows, err := p.Pool.Query(ctx, uptimeSelect+`$1
+		WHERE t.archived_at IS NULL AND ($2 = '' OR t.project_id = $2)
+		GROUP BY t.id, t.url, t.created_at
+		ORDER BY t.created_at ASC, t.id ASC
+	`, since, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []UptimeRow{}
+	for rows.Next() {
+		var u UptimeRow
+		if err := rows.Scan(&u.TargetID, &u.URL, &u.Checks, &u.Up, &u.AvgLatencyMS); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Secret is a named credential of a project. Value is sealed by
+// internal/secrets before it is stored and is never serialized
+type Secret struct {
+	ProjectID string    `json:"project_id"`
+	Name      string    `json:"name"`
+	Value     []byte    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const secretCols = `project_id, name, value, created_at, updated_at`
+
+func (p *Postgres) PutSecret(ctx context.Context, s Secret) error {
+	_, err := p.Pool.Exec(ctx, `
+		INSERT INTO secrets (project_id, name, value, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (project_id, name) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
+	`, orDefaultProject(s.ProjectID), s.Name, s.Value, s.UpdatedAt)
+	return err
+}
+
+func scanPGSecret(row pgx.Row) (Secret, error) {
+	var s Secret
+	err := row.Scan(&s.ProjectID, &s.Name, &s.Value, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, ErrNotFound
+	}
+	return s, err
+}
+
+func (p *Postgres) GetSecret(ctx context.Context, projectID, name string) (Secret, error) {
+	return scanPGSecret(p.Pool.QueryRow(ctx, `SELECT `+secretCols+` FROM secrets WHERE project_id = $1 AND name = $2`,
+		orDefaultProject(projectID), name))
+}
+
+func (p *Postgres) ListSecrets(ctx context.Context, projectID string) ([]Secret, error) {
+	rows, err := p.Pool.Query(ctx, `SELECT `+secretCols+` FROM secrets WHERE project_id = $1 ORDER BY name`, orDefaultProject(projectID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Secret{}
+	for rows.Next() {
+		s, err := scanPGSecret(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (p *Postgres) DeleteSecret(ctx context.Context, projectID, name string) error {
+	ct, err := p.Pool.Exec(ctx, `DELETE FROM secrets WHERE project_id = $1 AND name = $2`, orDefaultProject(projectID), name)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLite) PutSecret(ctx context.Context, sec Secret) error {
+	at := sqliteTime(sec.UpdatedAt)
+	_, err := s.DB.ExecContext(ctx, `
+		INSERT INTO secrets (project_id, name, value, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (project_id, name) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`, orDefaultProject(sec.ProjectID), sec.Name, sec.Value, at, at)
+	return err
+}
+
+func scanSQLiteSecret(row rowScanner) (Secret, error) {
+	var sec Secret
+	var created, updated string
+	if err := row.Scan(&sec.ProjectID, &sec.Name, &sec.Value, &created, &updated); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sec, ErrNotFound
+		}
+		return sec, err
+	}
+	var err error
+	if sec.CreatedAt, err = parseSQLiteTime(created); err != nil {
+		return sec, err
+	}
+	sec.UpdatedAt, err = parseSQLiteTime(updated)
+	return sec, err
+}
+
+func (s *SQLite) GetSecret(ctx context.Context, projectID, name string) (Secret, error) {
+	return scanSQLiteSecret(s.DB.QueryRowContext(ctx, `SELECT `+secretCols+` FROM secrets WHERE project_id = ? AND name = ?`,
+		orDefaultProject(projectID), name))
+}
+
+func (s *SQLite) ListSecrets(ctx context.Context, projectID string) ([]Secret, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+secretCols+` FROM secrets WHERE project_id = ? ORDER BY name`, orDefaultProject(projectID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Secret{}
+	for rows.Next() {
+		sec, err := scanSQLiteSecret(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sec)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLite) DeleteSecret(ctx context.Context, projectID, name string) error {
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM secrets WHERE project_id = ? AND name = ?`, orDefaultProject(projectID), name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/headers"
+	"github.com/nurzh/linkwatch/internal/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_Secrets(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+	require.NoError(t, s.CreateProject(ctx, Project{ID: "p_team", Name: "team", CreatedAt: time.Now()}))
+
+	t0 := time.Now().Add(-time.Minute)
+	require.NoError(t, s.PutSecret(ctx, Secret{ProjectID: DefaultProjectID, Name: "token", Value: []byte{1}, UpdatedAt: t0}))
+	require.NoError(t, s.PutSecret(ctx, Secret{ProjectID: "p_team", Name: "token", Value: []byte{2}, UpdatedAt: t0}))
+
+	//replacing keeps created_at
+	require.NoError(t, s.PutSecret(ctx, Secret{ProjectID: DefaultProjectID, Name: "token", Value: []byte{3}, UpdatedAt: time.Now()}))
+	got, err := s.GetSecret(ctx, DefaultProjectID, "token")
+	require.NoError(t, err)
+	require.Equal(t, []byte{3}, got.Value)
+	require.WithinDuration(t, t0, got.CreatedAt, time.Millisecond)
+	require.True(t, got.UpdatedAt.After(got.CreatedAt))
+
+	team, err := s.ListSecrets(ctx, "p_team")
+	require.NoError(t, err)
+	require.Len(t, team, 1)
+	require.Equal(t, []byte{2}, team[0].Value)
+
+	require.NoError(t, s.DeleteSecret(ctx, "p_team", "token"))
+	require.ErrorIs(t, s.DeleteSecret(ctx, "p_team", "token"), ErrNotFound)
+	_, err = s.GetSecret(ctx, "p_team", "token")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = s.GetSecret(ctx, DefaultProjectID, "token")
+	require.NoError(t, err, "other projects keep theirs")
+}
+
+func TestTargetSettingsRedacted(t *testing.T) {
+	s := TargetSettings{
+		Headers:   map[string]string{"Authorization": "Bearer abc", "X-Token": "${secret:t}"},
+		BasicAuth: &headers.BasicAuth{Username: "svc", Password: "hunter2"},
+		Transport: &transport.Settings{CertSecret: "client.pem", KeySecret: "client.key"},
+	}
+	r := s.Redacted()
+	require.Equal(t, map[string]string{"Authorization": "[redacted]", "X-Token": "${secret:t}"}, r.Headers)
+	require.Equal(t, "[redacted]", r.BasicAuth.Password)
+	require.Equal(t, "hunter2", s.BasicAuth.Password, "the original is unchanged")
+	require.Equal(t, []string{"client.key", "client.pem", "t"}, s.SecretRefs())
+
+	require.ErrorContains(t, TargetSettings{Headers: map[string]string{"Bad Name": "x"}}.Validate(), "settings.headers")
+	require.ErrorContains(t, TargetSettings{BasicAuth: &headers.BasicAuth{Password: "x"}}.Validate(), "settings.basic_auth.username")
+}
@@ -0,0 +1,416 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+
+	_ "modernc.org/sqlite"
+)
+
+// timestamps are stored as fixed-width UTC text so (created_at, id) sorts correctly
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+type SQLite struct {
+	DB *sql.DB
+}
+
+// path is a file path or ":memory:"; the schema comes from migrations
+func OpenSQLite(ctx context.Context, path string) (*SQLite, error) {
+	if path == "" {
+		return nil, errors.New("sqlite path is empty")
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	dsn := path + sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	//single writer: keeps tx semantics simple and :memory: shared
+	db.SetMaxOpenConns(1)
+	return &SQLite{DB: db}, nil
+}
+
+func (s *SQLite) Ping(ctx context.Context) error { return s.DB.PingContext(ctx) }
+
+func (s *SQLite) Close() { _ = s.DB.Close() }
+
+func sqliteTime(t time.Time) string { return t.UTC().Format(sqliteTimeLayout) }
+
+func parseSQLiteTime(s string) (time.Time, error) { return time.Parse(sqliteTimeLayout, s) }
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+const sqliteTargetCols = `id, project_id, url, host, created_at, labels, settings, source, archived_at`
+
+func scanSQLiteTarget(row rowScanner) (Target, error) {
+	var t Target
+	var created, labels, settings string
+	var archived *string
+	if err := row.Scan(&t.ID, &t.ProjectID, &t.URL, &t.Host, &created, &labels, &settings, &t.Source, &archived); err != nil {
+		return t, err
+	}
+	ct, err := parseSQLiteTime(created)
+	if err != nil {
+		return t, err
+	}
+	t.CreatedAt = ct
+	if t.ArchivedAt, err = parseSQLiteNullTime(archived); err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal([]byte(labels), &t.Labels); err != nil {
+		return t, err
+	}
+	if len(t.Labels) == 0 {
+		t.Labels = nil
+	}
+	if err := json.Unmarshal([]byte(settings), &t.Settings); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+func sqliteNullTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := sqliteTime(*t)
+	return &s
+}
+
+func parseSQLiteNullTime(s *string) (*time.Time, error) {
+	if s == nil {
+		return nil, nil
+	}
+	t, err := parseSQLiteTime(*s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func sqliteJSON(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// insert or return the project's existing target for the url
+func (s *SQLite) CreateOrGetTarget(ctx context.Context, projectID, id, canonURL, host string) (Target, bool, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return Target{}, false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	t, created, err := sqliteEnsureTarget(ctx, tx, Target{
+		ID: id, ProjectID: orDefaultProject(projectID), URL: canonURL, Host: host,
+		CreatedAt: time.Now(), Source: SourceAPI,
+	})
+	if err != nil {
+		return Target{}, false, err
+	}
+	return t, created, tx.Commit()
+}
+
+func (s *SQLite) GetTarget(ctx context.Context, id string) (Target, error) {
+	t, err := scanSQLiteTarget(s.DB.QueryRowContext(ctx,
+		`SELECT `+sqliteTargetCols+` FROM targets WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+func (s *SQLite) GetTargetByURL(ctx context.Context, projectID, url string) (Target, error) {
+	t, err := scanSQLiteTarget(s.DB.QueryRowContext(ctx,
+		`SELECT `+sqliteTargetCols+` FROM targets WHERE project_id = ? AND url = ?`, orDefaultProject(projectID), url))
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+func (s *SQLite) InsertTarget(ctx context.Context, t Target) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	t.ProjectID = orDefaultProject(t.ProjectID)
+	if _, created, err := sqliteEnsureTarget(ctx, tx, t); err != nil {
+		return err
+	} else if !created {
+		return fmt.Errorf("target %s already exists in project %s", t.URL, t.ProjectID)
+	}
+	return tx.Commit()
+}
+
+// unarchiving counts against the project quota again
+func (s *SQLite) UpdateTarget(ctx context.Context, t Target) error {
+	if t.Labels == nil {
+		t.Labels = map[string]string{}
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var projectID string
+	var archived *string
+	err = tx.QueryRowContext(ctx, `SELECT project_id, archived_at FROM targets WHERE id = ?`, t.ID).Scan(&projectID, &archived)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	if archived != nil && t.ArchivedAt == nil {
+		max, err := sqliteProjectQuota(ctx, tx, projectID)
+		if err != nil {
+			return err
+		}
+		if err := sqliteCheckQuota(ctx, tx, projectID, max); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE targets SET labels = ?, settings = ?, archived_at = ? WHERE id = ?
+	`, sqliteJSON(t.Labels), sqliteJSON(t.Settings), sqliteNullTime(t.ArchivedAt), t.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLite) ListTargetsBySource(ctx context.Context, source string) ([]Target, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+sqliteTargetCols+` FROM targets WHERE source = ? ORDER BY project_id, created_at, id`, source)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Target{}
+	for rows.Next() {
+		t, err := scanSQLiteTarget(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// removes the target, its results and idempotency keys
+func (s *SQLite) DeleteTarget(ctx context.Context, id string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE target_id = ?`, id); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM targets WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
+
+// returns up to limit targets, same ordering as Postgres
+func (s *SQLite) ListTargets(ctx context.Context, projectID string, host *string, after *api.Cursor, limit int) (items []Target, next *api.Cursor, err error) {
+	args := []any{}
+	q := `SELECT ` + sqliteTargetCols + ` FROM targets`
+
+	conds := []string{"archived_at IS NULL"}
+	if projectID != "" {
+		conds = append(conds, "project_id = ?")
+		args = append(args, projectID)
+	}
+	if host != nil && *host != "" {
+		conds = append(conds, "host = ?")
+		args = append(args, *host)
+	}
+	if after != nil {
+		conds = append(conds, "(created_at, id) > (?, ?)")
+		args = append(args, sqliteTime(after.CreatedAt), after.ID)
+	}
+	q += " WHERE " + strings.Join(conds, " AND ")
+	q += " ORDER BY created_at ASC, id ASC LIMIT ?"
+	args = append(args, limit+1)
+
+	rows, err := s.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	items = make([]Target, 0, limit+1)
+	for rows.Next() {
+		t, err := scanSQLiteTarget(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, t)
+	}
+	if rows.Err() != nil {
+		return nil, nil, rows.Err()
+	}
+
+	if len(items) > limit {
+		lastKept := items[limit-1]
+		items = items[:limit]
+		nc := api.Cursor{CreatedAt: lastKept.CreatedAt, ID: lastKept.ID}
+		next = &nc
+	}
+	return items, next, nil
+}
+
+// same contract as Postgres.UpsertIdempotencyKey
+func (s *SQLite) UpsertIdempotencyKey(ctx context.Context, projectID, key, requestHash, newID, canonURL, host string) (string, bool, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	projectID = orDefaultProject(projectID)
+
+	//existing key
+	var existingHash, existingTarget string
+	err = tx.QueryRowContext(ctx, `SELECT request_hash, target_id FROM idempotency_keys WHERE project_id = ? AND key = ?`, projectID, key).
+		Scan(&existingHash, &existingTarget)
+	switch {
+	case err == nil:
+		if existingHash != requestHash {
+			return existingTarget, true, ErrIdemConflict
+		}
+		if err := tx.Commit(); err != nil {
+			return "", true, err
+		}
+		return existingTarget, true, nil
+	case errors.Is(err, sql.ErrNoRows):
+	default:
+		return "", false, err
+	}
+
+	//ensure target
+	t, _, err := sqliteEnsureTarget(ctx, tx, Target{
+		ID: newID, ProjectID: projectID, URL: canonURL, Host: host,
+		CreatedAt: time.Now(), Source: SourceAPI,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	tid := t.ID
+
+	//idempotency mapping
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (project_id, key, request_hash, target_id)
+		VALUES (?, ?, ?, ?)
+	`, projectID, key, requestHash, tid); err != nil {
+		return "", false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", false, err
+	}
+	return tid, false, nil
+}
+
+// store check result
+func (s *SQLite) AppendCheckResult(ctx context.Context, r CheckResult) error {
+	_, err := s.DB.ExecContext(ctx, `
+		INSERT INTO check_results (target_id, checked_at, status_code, latency_ms, error, details, attempts)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, r.TargetID, sqliteTime(r.CheckedAt), r.StatusCode, r.LatencyMS, r.Error, sqliteText(r.detailsArg()), sqliteText(r.attemptsArg()))
+	return err
+}
+
+// JSON text or NULL
+func sqliteText(b []byte) any {
+	if b != nil {
+		return string(b)
+	}
+	return nil
+}
+
+// most recent results for a target
+func (s *SQLite) ListResults(ctx context.Context, targetID string, since *time.Time, limit int) ([]CheckResult, error) {
+	args := []any{targetID}
+	q := `
+		SELECT target_id, checked_at, status_code, latency_ms, error, details, attempts
+		FROM check_results
+		WHERE target_id = ?
+	`
+	if since != nil {
+		q += " AND checked_at >= ?"
+		args = append(args, sqliteTime(*since))
+	}
+	q += " ORDER BY checked_at DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]CheckResult, 0, limit)
+	for rows.Next() {
+		var r CheckResult
+		var checked string
+		var details, attempts *string
+		if err := rows.Scan(&r.TargetID, &checked, &r.StatusCode, &r.LatencyMS, &r.Error, &details, &attempts); err != nil {
+			return nil, err
+		}
+		if details != nil {
+			r.Details = json.RawMessage(*details)
+		}
+		if attempts != nil {
+			if err := r.scanAttempts([]byte(*attempts)); err != nil {
+				return nil, err
+			}
+		}
+		if r.CheckedAt, err = parseSQLiteTime(checked); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLite) Uptime(ctx context.Context, projectID string, since time.Time) ([]UptimeRow, error) {
+	rows, err := s.DB.QueryContext(ctx, uptimeSelect+`?
+		WHERE t.archived_at IS NULL AND (? = '' OR t.project_id = ?)
+		GROUP BY t.id, t.url, t.created_at
+		ORDER BY t.created_at ASC, t.id ASC
+	`, sqliteTime(since), projectID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []UptimeRow{}
+	for rows.Next() {
+		var u UptimeRow
+		if err := rows.Scan(&u.TargetID, &u.URL, &u.Checks, &u.Up, &u.AvgLatencyMS); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testSQLite(t *testing.T) *SQLite {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	s, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "linkwatch.db"))
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	m, err := Migrator(s)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	return s
+}
+
+func TestOpenErrors(t *testing.T) {
+	ctx := context.Background()
+	for _, dsn := range []string{"sqlite://", "postgres://u@host:notaport/db", "host=db port=notaport", "mysql://db"} {
+		st, err := Open(ctx, dsn)
+		require.Error(t, err, dsn)
+		require.True(t, st == nil, "%s: untyped nil, got %#v", dsn, st)
+	}
+}
+
+func TestSQLite_ListTargetsPaginationStable(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+
+	t0 := time.Now().Add(-3 * time.Second).UTC()
+	rs := []struct {
+		id, url, host string
+		at            time.Time
+	}{
+		{"t1", "https://a.test/1", "a.test", t0.Add(1 * time.Second)},
+		{"t2", "https://a.test/2", "a.test", t0.Add(2 * time.Second)},
+		{"t3", "https://b.test/3", "b.test", t0.Add(3 * time.Second)},
+	}
+	for _, r := range rs {
+		_, err := s.DB.ExecContext(ctx, `INSERT INTO targets (id, url, host, created_at) VALUES (?,?,?,?)`,
+			r.id, r.url, r.host, sqliteTime(r.at))
+		require.NoError(t, err)
+	}
+
+	//page 1
+	items1, next, err := s.ListTargets(ctx, "", nil, nil, 2)
+	require.NoError(t, err)
+	require.Len(t, items1, 2)
+	require.NotNil(t, next)
+
+	//page 2
+	items2, next2, err := s.ListTargets(ctx, "", nil, next, 2)
+	require.NoError(t, err)
+	require.Len(t, items2, 1)
+	require.Nil(t, next2)
+
+	got := []string{items1[0].ID, items1[1].ID, items2[0].ID}
+	require.Equal(t, []string{"t1", "t2", "t3"}, got)
+
+	//host filter
+	host := "b.test"
+	items3, _, err := s.ListTargets(ctx, "", &host, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, items3, 1)
+	require.Equal(t, "t3", items3[0].ID)
+}
+
+func TestSQLite_UpsertIdempotencyKey(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+
+	url1, host1 := "https://example.org/", "example.org"
+	url2, host2 := "https://different.org/", "different.org"
+	key := "abc123"
+
+	tid1, existed, err := s.UpsertIdempotencyKey(ctx, DefaultProjectID, key, sha(url1), "t_new_1", url1, host1)
+	require.NoError(t, err)
+	require.False(t, existed)
+	require.Equal(t, "t_new_1", tid1)
+
+	tidAgain, existed, err := s.UpsertIdempotencyKey(ctx, DefaultProjectID, key, sha(url1), "ignored", url1, host1)
+	require.NoError(t, err)
+	require.True(t, existed)
+	require.Equal(t, tid1, tidAgain)
+
+	_, _, err = s.UpsertIdempotencyKey(ctx, DefaultProjectID, key, sha(url2), "t_new_2", url2, host2)
+	require.ErrorIs(t, err, ErrIdemConflict)
+}
+
+func TestSQLite_Results(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+
+	tg, created, err := s.CreateOrGetTarget(ctx, DefaultProjectID, "t1", "https://a.test/", "a.test")
+	require.NoError(t, err)
+	require.True(t, created)
+
+	base := time.Now().Add(-time.Minute).UTC()
+	for i := 0; i < 3; i++ {
+		code := 200 + i
+		require.NoError(t, s.AppendCheckResult(ctx, CheckResult{
+			TargetID: tg.ID, CheckedAt: base.Add(time.Duration(i) * time.Second), StatusCode: &code,
+		}))
+	}
+
+	items, err := s.ListResults(ctx, tg.ID, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	require.Equal(t, 202, *items[0].StatusCode)
+
+	since := base.Add(time.Second)
+	items, err = s.ListResults(ctx, tg.ID, &since, 10)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Nil(t, items[0].Details)
+
+	require.Nil(t, items[0].Attempts)
+
+	details := json.RawMessage(`{"answers":["192.0.2.1"],"type":"A"}`)
+	code, msg := 200, "timeout"
+	attempts := []Attempt{{Attempt: 1, Error: &msg}, {Attempt: 2, StatusCode: &code}}
+	require.NoError(t, s.AppendCheckResult(ctx, CheckResult{TargetID: tg.ID, CheckedAt: base.Add(time.Hour), StatusCode: &code, Details: details, Attempts: attempts}))
+	items, err = s.ListResults(ctx, tg.ID, nil, 1)
+	require.NoError(t, err)
+	require.JSONEq(t, string(details), string(items[0].Details))
+	require.Equal(t, attempts, items[0].Attempts)
+}
+
+func TestSQLite_MigrationsDownUp(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+	m, err := Migrator(s)
+	require.NoError(t, err)
+
+	st, err := m.Status(ctx)
+	require.NoError(t, err)
+	for _, v := range st {
+		require.NotNil(t, v.AppliedAt, "migration %d", v.Version)
+	}
+
+	ran, err := m.Down(ctx, len(st))
+	require.NoError(t, err)
+	require.Len(t, ran, len(st))
+	_, _, err = s.ListTargets(ctx, "", nil, nil, 1)
+	require.Error(t, err, "tables should be gone")
+
+	ran, err = m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, ran, len(st))
+	_, _, err = s.ListTargets(ctx, "", nil, nil, 1)
+	require.NoError(t, err)
+}
+
+func TestSQLite_DeleteAndUptime(t *testing.T) {
+	s := testSQLite(t)
+	ctx := context.Background()
+
+	tg, _, err := s.CreateOrGetTarget(ctx, DefaultProjectID, "t1", "https://a.test/", "a.test")
+	require.NoError(t, err)
+	_, _, err = s.UpsertIdempotencyKey(ctx, DefaultProjectID, "k1", sha(tg.URL), "ignored", tg.URL, tg.Host)
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	ok, bad := 200, 503
+	lat := 10
+	boom := "dial tcp: refused"
+	require.NoError(t, s.AppendCheckResult(ctx, CheckResult{TargetID: tg.ID, CheckedAt: now.Add(-3 * time.Second), StatusCode: &ok, LatencyMS: &lat}))
+	require.NoError(t, s.AppendCheckResult(ctx, CheckResult{TargetID: tg.ID, CheckedAt: now.Add(-2 * time.Second), StatusCode: &bad, LatencyMS: &lat}))
+	require.NoError(t, s.AppendCheckResult(ctx, CheckResult{TargetID: tg.ID, CheckedAt: now.Add(-1 * time.Second), Error: &boom}))
+	//no check was made, so it counts neither way
+	skip := SkippedPrefix + "host circuit open"
+	require.NoError(t, s.AppendCheckResult(ctx, CheckResult{TargetID: tg.ID, CheckedAt: now, Error: &skip}))
+
+	rows, err := s.Uptime(ctx, "", now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, 3, rows[0].Checks)
+	require.Equal(t, 1, rows[0].Up)
+	require.NotNil(t, rows[0].AvgLatencyMS)
+	require.InDelta(t, 10, *rows[0].AvgLatencyMS, 0.001)
+
+	//archived targets are no longer monitored
+	archived := now
+	tg.ArchivedAt = &archived
+	require.NoError(t, s.UpdateTarget(ctx, tg))
+	rows, err = s.Uptime(ctx, "", now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, rows)
+	rows, err = s.Uptime(ctx, DefaultProjectID, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, rows)
+
+	require.NoError(t, s.DeleteTarget(ctx, tg.ID))
+	require.ErrorIs(t, s.DeleteTarget(ctx, tg.ID), ErrNotFound)
+	_, err = s.GetTarget(ctx, tg.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
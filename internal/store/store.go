@@ -1,10 +1,195 @@
 package store
 
-import "time"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/api"
+	"github.com/nurzh/linkwatch/internal/headers"
+	"github.com/nurzh/linkwatch/internal/retry"
+	"github.com/nurzh/linkwatch/internal/secrets"
+	"github.com/nurzh/linkwatch/internal/transport"
+)
+
+var ErrNotFound = errors.New("not found")
+
+const (
+	SourceAPI  = "api"  // created via POST /v1/targets or the CLI
+	SourceFile = "file" // owned by the declarative targets file
+)
 
 type Target struct {
-	ID        string    `json:"id"`
-	URL       string    `json:"url"`
-	Host      string    `json:"host"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         string            `json:"id"`
+	ProjectID  string            `json:"project_id"`
+	URL        string            `json:"url"`
+	Host       string            `json:"host"`
+	CreatedAt  time.Time         `json:"created_at"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Settings   TargetSettings    `json:"settings,omitzero"`
+	Source     string            `json:"source"`
+	ArchivedAt *time.Time        `json:"archived_at,omitempty"`
+}
+
+// per-target check settings; zero values mean "use the global default"
+type TargetSettings struct {
+	Interval string           `json:"interval,omitempty"` // Go duration, e.g. "30s"
+	Timeout  string           `json:"timeout,omitempty"`
+	Retry    *retry.Overrides `json:"retry,omitempty"` // over the global retry policy
+	// proxy and TLS, over the global transport settings
+	Transport *transport.Settings `json:"transport,omitempty"`
+	// sent with HTTP checks; values may reference secrets as ${secret:name}
+	Headers   map[string]string  `json:"headers,omitempty"`
+	BasicAuth *headers.BasicAuth `json:"basic_auth,omitempty"`
+}
+
+// Redacted is t as API responses and events show it, see TargetSettings.Redacted
+func (t Target) Redacted() Target {
+	t.Settings = t.Settings.Redacted()
+	return t
+}
+
+// Redacted is s as API responses show it: secret references stay, literal
+// passwords and credentials in sensitive headers are replaced
+func (s TargetSettings) Redacted() TargetSettings {
+	s.Headers = headers.Redacted(s.Headers)
+	if s.BasicAuth != nil {
+		b := s.BasicAuth.Redacted()
+		s.BasicAuth = &b
+	}
+	return s
+}
+
+// SecretRefs lists the secrets s references, without duplicates
+func (s TargetSettings) SecretRefs() []string {
+	var names []string
+	for _, v := range s.Headers {
+		names = append(names, secrets.Refs(v)...)
+	}
+	if s.BasicAuth != nil {
+		names = append(names, secrets.Refs(s.BasicAuth.Username)...)
+		names = append(names, secrets.Refs(s.BasicAuth.Password)...)
+	}
+	if s.Transport != nil {
+		for _, n := range []string{s.Transport.CertSecret, s.Transport.KeySecret} {
+			if n != "" {
+				names = append(names, n)
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
 }
+
+func (s TargetSettings) Validate() error {
+	if s.Retry != nil {
+		if err := s.Retry.Validate(); err != nil {
+			return fmt.Errorf("settings.retry.%w", err)
+		}
+	}
+	if s.Transport != nil {
+		if err := s.Transport.Validate(); err != nil {
+			return fmt.Errorf("settings.transport.%w", err)
+		}
+	}
+	if err := headers.Validate(s.Headers); err != nil {
+		return fmt.Errorf("settings.headers: %w", err)
+	}
+	if s.BasicAuth != nil {
+		if err := s.BasicAuth.Validate(); err != nil {
+			return fmt.Errorf("settings.basic_auth.%w", err)
+		}
+	}
+	for name, v := range map[string]string{"interval": s.Interval, "timeout": s.Timeout} {
+		if v == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			return fmt.Errorf("settings.%s: invalid duration %q", name, v)
+		}
+	}
+	return nil
+}
+
+// parsed duration, 0 if unset
+func (s TargetSettings) IntervalD() time.Duration {
+	d, _ := time.ParseDuration(s.Interval)
+	return d
+}
+
+func (s TargetSettings) TimeoutD() time.Duration {
+	d, _ := time.ParseDuration(s.Timeout)
+	return d
+}
+
+// Store is implemented by every storage backend (Postgres, SQLite)
+type Store interface {
+	// target creation fails with ErrQuotaExceeded when the project is full
+	CreateOrGetTarget(ctx context.Context, projectID, id, canonURL, host string) (Target, bool, error)
+	GetTarget(ctx context.Context, id string) (Target, error)
+	DeleteTarget(ctx context.Context, id string) error
+	// InsertTarget stores t as is (project, labels, settings, source); url must be new in the project
+	InsertTarget(ctx context.Context, t Target) error
+	// UpdateTarget rewrites labels, settings and archived_at
+	UpdateTarget(ctx context.Context, t Target) error
+	// all targets of a source in every project, archived ones included
+	ListTargetsBySource(ctx context.Context, source string) ([]Target, error)
+	GetTargetByURL(ctx context.Context, projectID, url string) (Target, error)
+	// ListTargets skips archived targets; projectID "" lists every project
+	ListTargets(ctx context.Context, projectID string, host *string, after *api.Cursor, limit int) ([]Target, *api.Cursor, error)
+	UpsertIdempotencyKey(ctx context.Context, projectID, key, requestHash, newID, canonURL, host string) (string, bool, error)
+	AppendCheckResult(ctx context.Context, r CheckResult) error
+	ListResults(ctx context.Context, targetID string, since *time.Time, limit int) ([]CheckResult, error)
+	// projectID "" covers every project
+	Uptime(ctx context.Context, projectID string, since time.Time) ([]UptimeRow, error)
+	CreateProject(ctx context.Context, p Project) error
+	GetProject(ctx context.Context, id string) (Project, error)
+	GetProjectByName(ctx context.Context, name string) (Project, error)
+	ListProjects(ctx context.Context) ([]Project, error)
+	// UpdateProject rewrites name and quotas
+	UpdateProject(ctx context.Context, p Project) error
+	CreateAPIKey(ctx context.Context, k APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (APIKey, error)
+	// projectID "" lists every project
+	ListAPIKeys(ctx context.Context, projectID string) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	TouchAPIKey(ctx context.Context, id string, at time.Time) error
+	// PutSecret creates the secret or replaces its value (sealed by the caller)
+	PutSecret(ctx context.Context, s Secret) error
+	GetSecret(ctx context.Context, projectID, name string) (Secret, error)
+	// ordered by name
+	ListSecrets(ctx context.Context, projectID string) ([]Secret, error)
+	DeleteSecret(ctx context.Context, projectID, name string) error
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// picks the backend by DATABASE_URL scheme; a DSN without one is pgx's
+// key=value form ("host=db user=linkwatch")
+func Open(ctx context.Context, dsn string) (Store, error) {
+	//errors return an untyped nil: a nil *Postgres in a Store is not == nil
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"), !strings.Contains(dsn, "://"):
+		p, err := OpenPostgres(ctx, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case strings.HasPrefix(dsn, "sqlite://"):
+		s, err := OpenSQLite(ctx, strings.TrimPrefix(dsn, "sqlite://"))
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unsupported DATABASE_URL scheme (want postgres://, sqlite:// or a key=value DSN)")
+	}
+}
+
+var (
+	_ Store = (*Postgres)(nil)
+	_ Store = (*SQLite)(nil)
+)
@@ -0,0 +1,54 @@
+// Package targets holds the target operations shared by the REST and gRPC
+// APIs, so both canonicalize, apply the egress policy, enforce quotas and
+// honour idempotency keys the same way.
+package targets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/store"
+)
+
+// Create registers rawURL in project and reports whether it is new. With an
+// idempotency key, repeating it with the same url returns the first target
+// (created false) and another url fails with store.ErrIdemConflict. A bad
+// url is a *store.FieldError for "url"; a full project is
+// store.ErrQuotaExceeded and an unknown one store.ErrNotFound.
+func Create(ctx context.Context, st store.Store, egress *netguard.Policy, project, rawURL, key string) (store.Target, bool, error) {
+	if rawURL == "" {
+		return store.Target{}, false, &store.FieldError{Field: "url", Message: "is required"}
+	}
+	canon, host, err := core.Canonicalize(rawURL)
+	if err != nil {
+		return store.Target{}, false, &store.FieldError{Field: "url", Message: err.Error()}
+	}
+	//early feedback for literal IPs and denied hosts; names are checked again on every dial
+	if err := egress.CheckHost(host); err != nil {
+		return store.Target{}, false, &store.FieldError{Field: "url", Message: err.Error()}
+	}
+
+	if key == "" {
+		return st.CreateOrGetTarget(ctx, project, core.NewID("t"), canon, host)
+	}
+	h := sha256.Sum256([]byte(canon))
+	tid, existed, err := st.UpsertIdempotencyKey(ctx, project, key, hex.EncodeToString(h[:]), core.NewID("t"), canon, host)
+	if err != nil {
+		return store.Target{}, false, err
+	}
+	t, err := st.GetTarget(ctx, tid)
+	return t, !existed, err
+}
+
+// Get returns the target if it belongs to project; other projects' targets
+// do not exist (store.ErrNotFound)
+func Get(ctx context.Context, st store.Store, project, id string) (store.Target, error) {
+	t, err := st.GetTarget(ctx, id)
+	if err == nil && t.ProjectID != project {
+		return store.Target{}, store.ErrNotFound
+	}
+	return t, err
+}
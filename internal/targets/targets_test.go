@@ -0,0 +1,62 @@
+package targets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T) store.Store {
+	ctx := context.Background()
+	s, err := store.OpenSQLite(ctx, ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	m, err := store.Migrator(s)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	return s
+}
+
+func TestCreate(t *testing.T) {
+	st := testStore(t)
+	ctx := context.Background()
+	p := netguard.Default()
+	project := store.DefaultProjectID
+
+	var fe *store.FieldError
+	for _, raw := range []string{"", "ftp://a.test/", "http://10.0.0.1/"} {
+		_, _, err := Create(ctx, st, p, project, raw, "")
+		require.True(t, errors.As(err, &fe), raw)
+		require.Equal(t, "url", fe.Field)
+	}
+
+	a, created, err := Create(ctx, st, p, project, "HTTPS://A.test/", "")
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, "https://a.test/", a.URL)
+	again, created, err := Create(ctx, st, p, project, "https://a.test", "")
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, a.ID, again.ID)
+
+	b, created, err := Create(ctx, st, p, project, "https://b.test/", "k")
+	require.NoError(t, err)
+	require.True(t, created)
+	same, created, err := Create(ctx, st, p, project, "https://B.test/", "k")
+	require.NoError(t, err)
+	require.False(t, created, "a repeated key is not a new target")
+	require.Equal(t, b.ID, same.ID)
+	_, _, err = Create(ctx, st, p, project, "https://c.test/", "k")
+	require.ErrorIs(t, err, store.ErrIdemConflict)
+
+	got, err := Get(ctx, st, project, b.ID)
+	require.NoError(t, err)
+	require.Equal(t, b.URL, got.URL)
+	_, err = Get(ctx, st, "p_other", b.ID)
+	require.ErrorIs(t, err, store.ErrNotFound)
+}
@@ -0,0 +1,287 @@
+// Package targetsync reconciles the declarative target list (config file /
+// targets file) against the targets table. Only targets with
+// source = "file" are created, updated or archived; API-created ones are
+// never touched.
+package targetsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/config"
+	"github.com/nurzh/linkwatch/internal/core"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/store"
+)
+
+type Action string
+
+const (
+	Create    Action = "create"
+	Update    Action = "update"
+	Unarchive Action = "unarchive"
+	Archive   Action = "archive"
+	Skip      Action = "skip" // url already registered via the API
+)
+
+type Change struct {
+	Action Action
+	Target store.Target  // desired state
+	Before *store.Target // current row, nil for create
+	Reason string
+}
+
+type Plan struct {
+	Changes []Change
+}
+
+// Specs gathers the inline config targets and the targets file
+func Specs(cfg config.Config) ([]config.TargetSpec, error) {
+	specs := append([]config.TargetSpec(nil), cfg.Targets...)
+	if cfg.TargetsFile != "" {
+		fromFile, err := config.LoadTargets(cfg.TargetsFile)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, fromFile...)
+	}
+	return specs, nil
+}
+
+// Sync = Specs + Compute + Apply
+func Sync(ctx context.Context, st store.Store, cfg config.Config) (Plan, error) {
+	specs, err := Specs(cfg)
+	if err != nil {
+		return Plan{}, err
+	}
+	p, err := Compute(ctx, st, specs)
+	if err != nil {
+		return p, err
+	}
+	return p, p.Apply(ctx, st)
+}
+
+// targets are matched by project and url
+func key(t store.Target) string { return t.ProjectID + " " + t.URL }
+
+// Compute diffs specs against the store without writing anything
+func Compute(ctx context.Context, st store.Store, specs []config.TargetSpec) (Plan, error) {
+	projects := map[string]store.Project{}
+	desired := map[string]store.Target{}
+	order := []string{}
+	for i, sp := range specs {
+		canon, host, err := core.Canonicalize(sp.URL)
+		if err != nil {
+			return Plan{}, fmt.Errorf("targets[%d] %q: %w", i, sp.URL, err)
+		}
+		name := sp.Project
+		if name == "" {
+			name = store.DefaultProjectName
+		}
+		proj, ok := projects[name]
+		if !ok {
+			proj, err = st.GetProjectByName(ctx, name)
+			if errors.Is(err, store.ErrNotFound) {
+				return Plan{}, fmt.Errorf("targets[%d]: unknown project %q", i, name)
+			} else if err != nil {
+				return Plan{}, err
+			}
+			projects[name] = proj
+		}
+		if floor := proj.MinInterval(); sp.Interval > 0 && sp.Interval.D() < floor {
+			return Plan{}, fmt.Errorf("targets[%d]: interval %s is below project %q minimum %s", i, sp.Interval, name, floor)
+		}
+		t := store.Target{ProjectID: proj.ID, URL: canon, Host: host, Labels: sp.Labels, Source: store.SourceFile}
+		if sp.Interval > 0 {
+			t.Settings.Interval = sp.Interval.String()
+		}
+		if sp.Timeout > 0 {
+			t.Settings.Timeout = sp.Timeout.String()
+		}
+		if sp.Retry != nil && !sp.Retry.IsZero() {
+			t.Settings.Retry = sp.Retry
+		}
+		if sp.Transport != nil && !sp.Transport.IsZero() {
+			t.Settings.Transport = sp.Transport
+		}
+		if len(sp.Headers) > 0 {
+			t.Settings.Headers = sp.Headers
+		}
+		t.Settings.BasicAuth = sp.BasicAuth
+		if _, dup := desired[key(t)]; dup {
+			return Plan{}, fmt.Errorf("targets[%d]: %s is listed twice in project %q", i, canon, name)
+		}
+		desired[key(t)] = t
+		order = append(order, key(t))
+	}
+
+	owned, err := st.ListTargetsBySource(ctx, store.SourceFile)
+	if err != nil {
+		return Plan{}, err
+	}
+	current := map[string]store.Target{}
+	for _, t := range owned {
+		current[key(t)] = t
+	}
+
+	var p Plan
+	for _, k := range order {
+		want := desired[k]
+		have, ok := current[k]
+		if !ok {
+			other, err := st.GetTargetByURL(ctx, want.ProjectID, want.URL)
+			switch {
+			case err == nil:
+				p.Changes = append(p.Changes, Change{Action: Skip, Target: want, Before: &other,
+					Reason: "already registered via " + other.Source})
+			case errors.Is(err, store.ErrNotFound):
+				p.Changes = append(p.Changes, Change{Action: Create, Target: want})
+			default:
+				return Plan{}, err
+			}
+			continue
+		}
+		want.ID, want.CreatedAt = have.ID, have.CreatedAt
+		before := have
+		switch {
+		case have.ArchivedAt != nil:
+			p.Changes = append(p.Changes, Change{Action: Unarchive, Target: want, Before: &before, Reason: describe(have, want)})
+		case !sameSpec(have, want):
+			p.Changes = append(p.Changes, Change{Action: Update, Target: want, Before: &before, Reason: describe(have, want)})
+		}
+	}
+
+	stale := []store.Target{}
+	for k, have := range current {
+		if _, ok := desired[k]; !ok && have.ArchivedAt == nil {
+			stale = append(stale, have)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return key(stale[i]) < key(stale[j]) })
+	for _, have := range stale {
+		before := have
+		p.Changes = append(p.Changes, Change{Action: Archive, Target: have, Before: &before, Reason: "no longer in the targets file"})
+	}
+	return p, nil
+}
+
+// Apply writes the plan; skips are reported, not errors. Each change's
+// Target becomes the row as written (new ids, archived_at).
+func (p Plan) Apply(ctx context.Context, st store.Store) error {
+	now := time.Now().UTC()
+	for i, c := range p.Changes {
+		t := c.Target
+		var err error
+		switch c.Action {
+		case Create:
+			t.ID, t.CreatedAt = core.NewID("t"), now
+			err = st.InsertTarget(ctx, t)
+		case Update, Unarchive:
+			t.ArchivedAt = nil
+			err = st.UpdateTarget(ctx, t)
+		case Archive:
+			t.ArchivedAt = &now
+			err = st.UpdateTarget(ctx, t)
+		}
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", c.Action, t.URL, err)
+		}
+		p.Changes[i].Target = t
+	}
+	return nil
+}
+
+// Publish sends a target event for every applied write
+func (p Plan) Publish(pub events.Publisher) {
+	action := map[Action]string{Create: events.Created, Update: events.Updated, Unarchive: events.Unarchived, Archive: events.Archived}
+	for _, c := range p.Changes {
+		if a, ok := action[c.Action]; ok {
+			pub.Publish(events.TargetEvent(a, c.Target))
+		}
+	}
+}
+
+// true if applying would write something
+func (p Plan) HasWrites() bool {
+	for _, c := range p.Changes {
+		if c.Action != Skip {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Plan) Summary() string {
+	n := map[Action]int{}
+	for _, c := range p.Changes {
+		n[c.Action]++
+	}
+	return fmt.Sprintf("%d to create, %d to update, %d to unarchive, %d to archive, %d skipped",
+		n[Create], n[Update], n[Unarchive], n[Archive], n[Skip])
+}
+
+// diff-style lines, one per change
+func (p Plan) String() string {
+	if len(p.Changes) == 0 {
+		return "no changes\n"
+	}
+	sign := map[Action]string{Create: "+", Update: "~", Unarchive: "+", Archive: "-", Skip: "!"}
+	var b strings.Builder
+	for _, c := range p.Changes {
+		fmt.Fprintf(&b, "%s %-9s %s", sign[c.Action], c.Action, c.Target.URL)
+		if c.Target.ProjectID != store.DefaultProjectID {
+			fmt.Fprintf(&b, " [%s]", c.Target.ProjectID)
+		}
+		if c.Reason != "" {
+			fmt.Fprintf(&b, "  (%s)", c.Reason)
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString(p.Summary() + "\n")
+	return b.String()
+}
+
+func sameSpec(a, b store.Target) bool {
+	return maps.Equal(a.Labels, b.Labels) && sameJSON(a.Settings, b.Settings)
+}
+
+// as stored, so an empty list in the file equals a missing one in the DB
+func sameJSON(a, b any) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+func describe(have, want store.Target) string {
+	var parts []string
+	if !maps.Equal(have.Labels, want.Labels) {
+		parts = append(parts, fmt.Sprintf("labels %v → %v", have.Labels, want.Labels))
+	}
+	if have.Settings.Interval != want.Settings.Interval {
+		parts = append(parts, fmt.Sprintf("interval %q → %q", have.Settings.Interval, want.Settings.Interval))
+	}
+	if have.Settings.Timeout != want.Settings.Timeout {
+		parts = append(parts, fmt.Sprintf("timeout %q → %q", have.Settings.Timeout, want.Settings.Timeout))
+	}
+	if !sameJSON(have.Settings.Retry, want.Settings.Retry) {
+		parts = append(parts, "retry changed")
+	}
+	if !sameJSON(have.Settings.Transport, want.Settings.Transport) {
+		parts = append(parts, "transport changed")
+	}
+	//values may be credentials, so they are never printed
+	if !sameJSON(have.Settings.Headers, want.Settings.Headers) {
+		parts = append(parts, "headers changed")
+	}
+	if !sameJSON(have.Settings.BasicAuth, want.Settings.BasicAuth) {
+		parts = append(parts, "basic_auth changed")
+	}
+	return strings.Join(parts, ", ")
+}
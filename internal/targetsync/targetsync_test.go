@@ -0,0 +1,193 @@
+package targetsync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/config"
+	"github.com/nurzh/linkwatch/internal/events"
+	"github.com/nurzh/linkwatch/internal/headers"
+	"github.com/nurzh/linkwatch/internal/retry"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/internal/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T) store.Store {
+	ctx := context.Background()
+	s, err := store.OpenSQLite(ctx, ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	m, err := store.Migrator(s)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	return s
+}
+
+func actions(p Plan) map[string]Action {
+	out := map[string]Action{}
+	for _, c := range p.Changes {
+		out[c.Target.URL] = c.Action
+	}
+	return out
+}
+
+func TestReconcileLifecycle(t *testing.T) {
+	st := testStore(t)
+	ctx := context.Background()
+
+	//API-owned target must be left alone
+	api, _, err := st.CreateOrGetTarget(ctx, store.DefaultProjectID, "t_api", "https://api.test/", "api.test")
+	require.NoError(t, err)
+
+	specs := []config.TargetSpec{
+		{URL: "https://A.test/x/", Labels: map[string]string{"team": "web"}, Interval: config.Duration(time.Minute)},
+		{URL: "https://b.test/"},
+		{URL: "https://api.test/"},
+	}
+	p, err := Compute(ctx, st, specs)
+	require.NoError(t, err)
+	require.Equal(t, map[string]Action{
+		"https://a.test/x":  Create,
+		"https://b.test/":   Create,
+		"https://api.test/": Skip,
+	}, actions(p))
+	require.NoError(t, p.Apply(ctx, st))
+
+	a, err := st.GetTargetByURL(ctx, store.DefaultProjectID, "https://a.test/x")
+	require.NoError(t, err)
+	require.Equal(t, store.SourceFile, a.Source)
+	require.Equal(t, "web", a.Labels["team"])
+	require.Equal(t, "1m0s", a.Settings.Interval)
+
+	//applying again is a no-op
+	p, err = Compute(ctx, st, specs)
+	require.NoError(t, err)
+	require.False(t, p.HasWrites())
+
+	//retry settings round-trip through the store; an empty list equals none
+	specs[1].Retry = &retry.Overrides{MaxAttempts: 1, Errors: []string{}}
+	p, err = Compute(ctx, st, specs)
+	require.NoError(t, err)
+	require.Equal(t, Update, actions(p)["https://b.test/"])
+	for _, c := range p.Changes {
+		if c.Action == Update {
+			require.Equal(t, "retry changed", c.Reason)
+		}
+	}
+	require.NoError(t, p.Apply(ctx, st))
+	p, err = Compute(ctx, st, specs)
+	require.NoError(t, err)
+	require.False(t, p.HasWrites())
+
+	specs[1].Transport = &transport.Settings{ServerName: "b.internal"}
+	p, err = Compute(ctx, st, specs)
+	require.NoError(t, err)
+	require.Equal(t, Update, actions(p)["https://b.test/"])
+	for _, c := range p.Changes {
+		if c.Action == Update {
+			require.Equal(t, "transport changed", c.Reason)
+		}
+	}
+	require.NoError(t, p.Apply(ctx, st))
+	p, err = Compute(ctx, st, specs)
+	require.NoError(t, err)
+	require.False(t, p.HasWrites())
+
+	//credentials are not printed
+	specs[1].Headers = map[string]string{"Authorization": "Bearer ${secret:b-token}"}
+	specs[1].BasicAuth = &headers.BasicAuth{Username: "svc", Password: "hunter2"}
+	p, err = Compute(ctx, st, specs)
+	require.NoError(t, err)
+	require.Contains(t, p.String(), "headers changed, basic_auth changed")
+	require.NotContains(t, p.String(), "hunter2")
+	require.NoError(t, p.Apply(ctx, st))
+	stored, err := st.GetTargetByURL(ctx, store.DefaultProjectID, "https://b.test/")
+	require.NoError(t, err)
+	require.Equal(t, "Bearer ${secret:b-token}", stored.Settings.Headers["Authorization"])
+
+	//change labels, drop b
+	specs = []config.TargetSpec{
+		{URL: "https://a.test/x", Labels: map[string]string{"team": "platform"}, Interval: config.Duration(time.Minute)},
+	}
+	p, err = Compute(ctx, st, specs)
+	require.NoError(t, err)
+	require.Equal(t, map[string]Action{"https://a.test/x": Update, "https://b.test/": Archive}, actions(p))
+	require.NoError(t, p.Apply(ctx, st))
+
+	//applied writes become target events carrying the written rows
+	b := events.NewBroker(0)
+	_, sub, err := b.Subscribe(events.Filter{}, "")
+	require.NoError(t, err)
+	p.Publish(b)
+	for range 2 {
+		ev := <-sub.C()
+		require.Equal(t, events.TypeTarget, ev.Type)
+		require.NotEmpty(t, ev.TargetID)
+		if ev.URL == "https://b.test/" {
+			require.Equal(t, events.Archived, ev.Action)
+			require.NotNil(t, ev.Target.ArchivedAt)
+		} else {
+			require.Equal(t, events.Updated, ev.Action)
+			require.Equal(t, "platform", ev.Labels["team"])
+		}
+	}
+
+	items, _, err := st.ListTargets(ctx, "", nil, nil, 10)
+	require.NoError(t, err)
+	urls := []string{}
+	for _, it := range items {
+		urls = append(urls, it.URL)
+	}
+	require.ElementsMatch(t, []string{api.URL, "https://a.test/x"}, urls, "archived targets are not listed")
+
+	//b comes back
+	specs = append(specs, config.TargetSpec{URL: "https://b.test/"})
+	p, err = Compute(ctx, st, specs)
+	require.NoError(t, err)
+	require.Equal(t, map[string]Action{"https://b.test/": Unarchive}, actions(p))
+	require.NoError(t, p.Apply(ctx, st))
+	bt, err := st.GetTargetByURL(ctx, store.DefaultProjectID, "https://b.test/")
+	require.NoError(t, err)
+	require.Nil(t, bt.ArchivedAt)
+
+	//the API target was never modified
+	got, err := st.GetTarget(ctx, api.ID)
+	require.NoError(t, err)
+	require.Equal(t, store.SourceAPI, got.Source)
+	require.Nil(t, got.ArchivedAt)
+}
+
+func TestComputeRejectsDuplicates(t *testing.T) {
+	st := testStore(t)
+	_, err := Compute(context.Background(), st, []config.TargetSpec{
+		{URL: "https://a.test"}, {URL: "https://A.test/"},
+	})
+	require.ErrorContains(t, err, "listed twice")
+}
+
+func TestComputeProjects(t *testing.T) {
+	st := testStore(t)
+	ctx := context.Background()
+	thirty := 30
+	require.NoError(t, st.CreateProject(ctx, store.Project{ID: "p_team", Name: "team", MinIntervalSeconds: &thirty, CreatedAt: time.Now()}))
+
+	//same url in two projects is two targets
+	p, err := Compute(ctx, st, []config.TargetSpec{
+		{URL: "https://a.test/"},
+		{URL: "https://a.test/", Project: "team", Interval: config.Duration(time.Minute)},
+	})
+	require.NoError(t, err)
+	require.Len(t, p.Changes, 2)
+	require.NoError(t, p.Apply(ctx, st))
+	tg, err := st.GetTargetByURL(ctx, "p_team", "https://a.test/")
+	require.NoError(t, err)
+	require.Equal(t, "1m0s", tg.Settings.Interval)
+
+	_, err = Compute(ctx, st, []config.TargetSpec{{URL: "https://a.test/", Project: "nope"}})
+	require.ErrorContains(t, err, `unknown project "nope"`)
+	_, err = Compute(ctx, st, []config.TargetSpec{{URL: "https://a.test/", Project: "team", Interval: config.Duration(10 * time.Second)}})
+	require.ErrorContains(t, err, "below project")
+}
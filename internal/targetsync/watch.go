@@ -0,0 +1,36 @@
+package targetsync
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"time"
+)
+
+// Watch calls fn whenever the file content changes (polled every interval).
+// Polling keeps it working on bind mounts and ConfigMap symlink swaps.
+func Watch(ctx context.Context, path string, every time.Duration, fn func()) {
+	last := fileSum(path)
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		sum := fileSum(path)
+		if sum != last {
+			last = sum
+			fn()
+		}
+	}
+}
+
+func fileSum(path string) [32]byte {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return [32]byte{}
+	}
+	return sha256.Sum256(b)
+}
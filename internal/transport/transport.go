@@ -0,0 +1,159 @@
+// Package transport holds the connection settings of checks: an HTTP proxy,
+// extra trusted CAs, a client certificate (mTLS), the minimum TLS version,
+// an SNI override and, as an explicit opt-in, skipping verification. The
+// global config ("transport:") and per-target settings share one shape;
+// a target's fields win over the global ones.
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/nurzh/linkwatch/internal/secrets"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Settings is the config/settings shape; empty fields keep the base
+type Settings struct {
+	// http://, https:// or socks5:// URL; only HTTP(S) checks use it
+	Proxy string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// PEM bundle trusted in addition to the system roots
+	CAFile string `json:"ca_file,omitempty" yaml:"ca_file,omitempty"`
+	// client certificate and its key (PEM), presented when the server asks
+	CertFile string `json:"cert_file,omitempty" yaml:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	// or the names of secrets holding them (targets only); the checker
+	// resolves them, TLSConfig leaves them out
+	CertSecret string `json:"cert_secret,omitempty" yaml:"cert_secret,omitempty"`
+	KeySecret  string `json:"key_secret,omitempty" yaml:"key_secret,omitempty"`
+	// "1.0" to "1.3", default 1.2
+	TLSMinVersion string `json:"tls_min_version,omitempty" yaml:"tls_min_version,omitempty"`
+	// sent as SNI and verified instead of the URL's host
+	ServerName string `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+	// accept any certificate; recorded on every result it applies to
+	InsecureSkipVerify *bool `json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
+}
+
+func (s Settings) IsZero() bool { return s == Settings{} }
+
+// Validate checks the syntax; Load also reads the files
+func (s Settings) Validate() error {
+	if s.Proxy != "" {
+		u, err := url.Parse(s.Proxy)
+		if err != nil || u.Host == "" || !slices.Contains([]string{"http", "https", "socks5"}, u.Scheme) {
+			return fmt.Errorf("proxy: want an http://, https:// or socks5:// URL, got %q", s.Proxy)
+		}
+	}
+	if (s.CertFile == "") != (s.KeyFile == "") {
+		return errors.New("cert_file: cert_file and key_file go together")
+	}
+	if (s.CertSecret == "") != (s.KeySecret == "") {
+		return errors.New("cert_secret: cert_secret and key_secret go together")
+	}
+	if s.CertSecret != "" && s.CertFile != "" {
+		return errors.New("cert_secret: set cert_file or cert_secret, not both")
+	}
+	for _, n := range []string{s.CertSecret, s.KeySecret} {
+		if n == "" {
+			continue
+		}
+		if err := secrets.ValidateName(n); err != nil {
+			return fmt.Errorf("cert_secret: %w", err)
+		}
+	}
+	if _, ok := tlsVersions[s.TLSMinVersion]; s.TLSMinVersion != "" && !ok {
+		return fmt.Errorf("tls_min_version: must be 1.0, 1.1, 1.2 or 1.3, got %q", s.TLSMinVersion)
+	}
+	if strings.ContainsAny(s.ServerName, "/: ") {
+		return fmt.Errorf("server_name: want a host name, got %q", s.ServerName)
+	}
+	return nil
+}
+
+// Load validates s and reads its files, as checks will
+func (s Settings) Load() error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	_, err := s.TLSConfig()
+	return err
+}
+
+// With applies o (may be nil) over s; a certificate replaces both files or
+// secrets
+func (s Settings) With(o *Settings) Settings {
+	if o == nil {
+		return s
+	}
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&s.Proxy, o.Proxy)
+	set(&s.CAFile, o.CAFile)
+	if o.CertFile != "" || o.CertSecret != "" {
+		s.CertFile, s.KeyFile = o.CertFile, o.KeyFile
+		s.CertSecret, s.KeySecret = o.CertSecret, o.KeySecret
+	}
+	set(&s.TLSMinVersion, o.TLSMinVersion)
+	set(&s.ServerName, o.ServerName)
+	if o.InsecureSkipVerify != nil {
+		s.InsecureSkipVerify = o.InsecureSkipVerify
+	}
+	return s
+}
+
+func (s Settings) Insecure() bool { return s.InsecureSkipVerify != nil && *s.InsecureSkipVerify }
+
+// TLSConfig reads the files; s must be valid
+func (s Settings) TLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: s.ServerName, InsecureSkipVerify: s.Insecure()}
+	if v, ok := tlsVersions[s.TLSMinVersion]; ok {
+		cfg.MinVersion = v
+	}
+	if s.CAFile != "" {
+		pem, err := os.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("ca_file: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file: no PEM certificates in %s", s.CAFile)
+		}
+		cfg.RootCAs = roots
+	}
+	if s.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cert_file: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// ProxyFunc for http.Transport.Proxy, nil without a proxy; s must be valid
+func (s Settings) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	if s.Proxy == "" {
+		return nil
+	}
+	u, _ := url.Parse(s.Proxy)
+	return http.ProxyURL(u)
+}
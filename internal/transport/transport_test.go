@@ -0,0 +1,101 @@
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// self-signed certificate and key as PEM files in a temp dir
+func writeCert(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "linkwatch test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	kder, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kder}), 0o600))
+	return certFile, keyFile
+}
+
+func TestTLSConfig(t *testing.T) {
+	cert, key := writeCert(t)
+	yes := true
+	cfg, err := Settings{CAFile: cert, CertFile: cert, KeyFile: key, TLSMinVersion: "1.3", ServerName: "internal.test", InsecureSkipVerify: &yes}.TLSConfig()
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+	require.Equal(t, "internal.test", cfg.ServerName)
+	require.True(t, cfg.InsecureSkipVerify)
+	require.Len(t, cfg.Certificates, 1)
+	require.NotNil(t, cfg.RootCAs)
+
+	cfg, err = Settings{}.TLSConfig()
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+	require.Nil(t, cfg.RootCAs, "system roots")
+	require.False(t, cfg.InsecureSkipVerify)
+
+	_, err = Settings{CAFile: key}.TLSConfig()
+	require.ErrorContains(t, err, "ca_file: no PEM certificates")
+	_, err = Settings{CAFile: filepath.Join(t.TempDir(), "missing.pem")}.TLSConfig()
+	require.ErrorContains(t, err, "ca_file")
+	require.ErrorContains(t, Settings{CertFile: cert, KeyFile: cert}.Load(), "cert_file")
+}
+
+func TestWith(t *testing.T) {
+	yes, no := true, false
+	base := Settings{Proxy: "http://proxy.test:3128", CAFile: "/etc/ca.pem", CertFile: "/a.pem", KeyFile: "/a.key", InsecureSkipVerify: &yes}
+	require.Equal(t, base, base.With(nil))
+	require.Equal(t, base, base.With(&Settings{}))
+	got := base.With(&Settings{CertFile: "/b.pem", KeyFile: "/b.key", ServerName: "b.test", InsecureSkipVerify: &no})
+	require.Equal(t, Settings{Proxy: "http://proxy.test:3128", CAFile: "/etc/ca.pem", CertFile: "/b.pem", KeyFile: "/b.key", ServerName: "b.test", InsecureSkipVerify: &no}, got)
+	require.False(t, got.Insecure())
+	require.True(t, Settings{}.IsZero())
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Settings{Proxy: "socks5://127.0.0.1:1080", TLSMinVersion: "1.2", ServerName: "a.test"}.Validate())
+	for s, want := range map[*Settings]string{
+		{Proxy: "proxy.test:3128"}:  "proxy",
+		{Proxy: "ftp://proxy.test"}: "proxy",
+		{CertFile: "/a.pem"}:        "cert_file",
+		{KeyFile: "/a.key"}:         "cert_file",
+		{TLSMinVersion: "1.4"}:      "tls_min_version",
+		{ServerName: "a.test:443"}:  "server_name",
+	} {
+		require.ErrorContains(t, s.Validate(), want)
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	require.Nil(t, Settings{}.ProxyFunc())
+	req, _ := http.NewRequest(http.MethodGet, "https://a.test/", nil)
+	u, err := Settings{Proxy: "http://proxy.test:3128"}.ProxyFunc()(req)
+	require.NoError(t, err)
+	require.Equal(t, "proxy.test:3128", u.Host)
+}
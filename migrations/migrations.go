@@ -0,0 +1,24 @@
+// Package migrations embeds the SQL schema for every storage backend.
+// Files are named <version>_<name>.up.sql / <version>_<name>.down.sql.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+var (
+	Postgres = sub("postgres")
+	SQLite   = sub("sqlite")
+)
+
+func sub(dir string) fs.FS {
+	f, err := fs.Sub(files, dir)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
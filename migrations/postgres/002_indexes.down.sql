@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS results_target_checked_idx;
+DROP INDEX IF EXISTS targets_host_created_idx;
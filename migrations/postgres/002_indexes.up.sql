@@ -1,2 +1,2 @@
 CREATE INDEX IF NOT EXISTS targets_host_created_idx ON targets (host, created_at, id);
-CREATE INDEX IF NOT EXISTS results_target_checked_idx ON check_results (target_id, checked_at DESC);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS results_target_checked_idx ON check_results (target_id, checked_at DESC);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS targets_source_idx;
+
+ALTER TABLE targets
+  DROP COLUMN IF EXISTS archived_at,
+  DROP COLUMN IF EXISTS source,
+  DROP COLUMN IF EXISTS settings,
+  DROP COLUMN IF EXISTS labels;
@@ -0,0 +1,7 @@
+ALTER TABLE targets
+  ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}',
+  ADD COLUMN IF NOT EXISTS settings JSONB NOT NULL DEFAULT '{}',
+  ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'api',
+  ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS targets_source_idx ON targets (source);
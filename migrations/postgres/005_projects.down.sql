@@ -0,0 +1,15 @@
+-- fails if two projects registered the same URL or idempotency key
+ALTER TABLE api_keys DROP COLUMN IF EXISTS project_id;
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS project_id;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (key);
+
+DROP INDEX IF EXISTS targets_project_created_idx;
+DROP INDEX IF EXISTS targets_project_host_created_idx;
+CREATE INDEX IF NOT EXISTS targets_host_created_idx ON targets (host, created_at, id);
+ALTER TABLE targets DROP CONSTRAINT IF EXISTS targets_project_url_key;
+ALTER TABLE targets DROP COLUMN IF EXISTS project_id;
+ALTER TABLE targets ADD CONSTRAINT targets_url_key UNIQUE (url);
+
+DROP TABLE IF EXISTS projects;
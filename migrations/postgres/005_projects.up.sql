@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS projects (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  max_targets INT,                 -- NULL = unlimited
+  min_check_interval_seconds INT,  -- NULL = global check_interval
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- everything that existed before projects belongs to "default"
+INSERT INTO projects (id, name) VALUES ('p_default', 'default') ON CONFLICT DO NOTHING;
+
+ALTER TABLE targets ADD COLUMN IF NOT EXISTS project_id TEXT NOT NULL DEFAULT 'p_default' REFERENCES projects(id);
+ALTER TABLE targets DROP CONSTRAINT IF EXISTS targets_url_key;
+ALTER TABLE targets ADD CONSTRAINT targets_project_url_key UNIQUE (project_id, url);
+DROP INDEX IF EXISTS targets_host_created_idx;
+CREATE INDEX IF NOT EXISTS targets_project_host_created_idx ON targets (project_id, host, created_at, id);
+CREATE INDEX IF NOT EXISTS targets_project_created_idx ON targets (project_id, created_at, id);
+
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS project_id TEXT NOT NULL DEFAULT 'p_default' REFERENCES projects(id);
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (project_id, key);
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS project_id TEXT NOT NULL DEFAULT 'p_default' REFERENCES projects(id);
@@ -0,0 +1,2 @@
+-- per-check extras, e.g. the answers of dns: checks
+ALTER TABLE check_results ADD COLUMN IF NOT EXISTS details JSONB;
@@ -0,0 +1,2 @@
+-- every try of a retried check: [{"attempt":1,"status_code":503,...}, ...]
+ALTER TABLE check_results ADD COLUMN IF NOT EXISTS attempts JSONB;
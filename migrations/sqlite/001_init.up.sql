@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS targets (
+  id TEXT PRIMARY KEY,
+  url TEXT NOT NULL UNIQUE,
+  host TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS check_results (
+  target_id TEXT NOT NULL REFERENCES targets(id) ON DELETE CASCADE,
+  checked_at TEXT NOT NULL,
+  status_code INTEGER,
+  latency_ms INTEGER,
+  error TEXT,
+  PRIMARY KEY (target_id, checked_at)
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  key TEXT PRIMARY KEY,
+  request_hash TEXT NOT NULL,
+  target_id TEXT NOT NULL REFERENCES targets(id),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+
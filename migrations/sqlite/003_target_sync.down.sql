@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS targets_source_idx;
+
+ALTER TABLE targets DROP COLUMN archived_at;
+ALTER TABLE targets DROP COLUMN source;
+ALTER TABLE targets DROP COLUMN settings;
+ALTER TABLE targets DROP COLUMN labels;
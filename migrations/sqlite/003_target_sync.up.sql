@@ -0,0 +1,6 @@
+ALTER TABLE targets ADD COLUMN labels TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE targets ADD COLUMN settings TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE targets ADD COLUMN source TEXT NOT NULL DEFAULT 'api';
+ALTER TABLE targets ADD COLUMN archived_at TEXT;
+
+CREATE INDEX IF NOT EXISTS targets_source_idx ON targets (source);
@@ -0,0 +1,44 @@
+-- +foreign_keys off
+-- fails if two projects registered the same URL or idempotency key
+
+CREATE TABLE api_keys_old (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL,
+  hash TEXT NOT NULL UNIQUE,
+  scopes TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  last_used_at TEXT,
+  revoked_at TEXT
+);
+INSERT INTO api_keys_old SELECT id, name, prefix, hash, scopes, created_at, last_used_at, revoked_at FROM api_keys;
+DROP TABLE api_keys;
+ALTER TABLE api_keys_old RENAME TO api_keys;
+
+CREATE TABLE idempotency_keys_old (
+  key TEXT PRIMARY KEY,
+  request_hash TEXT NOT NULL,
+  target_id TEXT NOT NULL REFERENCES targets(id),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+INSERT INTO idempotency_keys_old SELECT key, request_hash, target_id, created_at FROM idempotency_keys;
+DROP TABLE idempotency_keys;
+ALTER TABLE idempotency_keys_old RENAME TO idempotency_keys;
+
+CREATE TABLE targets_old (
+  id TEXT PRIMARY KEY,
+  url TEXT NOT NULL UNIQUE,
+  host TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  labels TEXT NOT NULL DEFAULT '{}',
+  settings TEXT NOT NULL DEFAULT '{}',
+  source TEXT NOT NULL DEFAULT 'api',
+  archived_at TEXT
+);
+INSERT INTO targets_old SELECT id, url, host, created_at, labels, settings, source, archived_at FROM targets;
+DROP TABLE targets;
+ALTER TABLE targets_old RENAME TO targets;
+CREATE INDEX targets_host_created_idx ON targets (host, created_at, id);
+CREATE INDEX targets_source_idx ON targets (source);
+
+DROP TABLE projects;
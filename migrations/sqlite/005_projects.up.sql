@@ -0,0 +1,51 @@
+-- +foreign_keys off
+-- SQLite cannot drop the inline UNIQUE(url) / PRIMARY KEY(key), so targets,
+-- idempotency_keys and api_keys are rebuilt (foreign keys are off while this runs).
+
+CREATE TABLE IF NOT EXISTS projects (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  max_targets INTEGER,                 -- NULL = unlimited
+  min_check_interval_seconds INTEGER,  -- NULL = global check_interval
+  created_at TEXT NOT NULL
+);
+
+-- everything that existed before projects belongs to "default"
+INSERT INTO projects (id, name, created_at)
+VALUES ('p_default', 'default', strftime('%Y-%m-%dT%H:%M:%f000Z', 'now'))
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE targets_new (
+  id TEXT PRIMARY KEY,
+  project_id TEXT NOT NULL DEFAULT 'p_default' REFERENCES projects(id),
+  url TEXT NOT NULL,
+  host TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  labels TEXT NOT NULL DEFAULT '{}',
+  settings TEXT NOT NULL DEFAULT '{}',
+  source TEXT NOT NULL DEFAULT 'api',
+  archived_at TEXT,
+  UNIQUE (project_id, url)
+);
+INSERT INTO targets_new (id, url, host, created_at, labels, settings, source, archived_at)
+SELECT id, url, host, created_at, labels, settings, source, archived_at FROM targets;
+DROP TABLE targets;
+ALTER TABLE targets_new RENAME TO targets;
+CREATE INDEX targets_project_host_created_idx ON targets (project_id, host, created_at, id);
+CREATE INDEX targets_project_created_idx ON targets (project_id, created_at, id);
+CREATE INDEX targets_source_idx ON targets (source);
+
+CREATE TABLE idempotency_keys_new (
+  project_id TEXT NOT NULL DEFAULT 'p_default' REFERENCES projects(id),
+  key TEXT NOT NULL,
+  request_hash TEXT NOT NULL,
+  target_id TEXT NOT NULL REFERENCES targets(id),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+  PRIMARY KEY (project_id, key)
+);
+INSERT INTO idempotency_keys_new (key, request_hash, target_id, created_at)
+SELECT key, request_hash, target_id, created_at FROM idempotency_keys;
+DROP TABLE idempotency_keys;
+ALTER TABLE idempotency_keys_new RENAME TO idempotency_keys;
+
+ALTER TABLE api_keys ADD COLUMN project_id TEXT NOT NULL DEFAULT 'p_default' REFERENCES projects(id);
@@ -0,0 +1 @@
+ALTER TABLE check_results DROP COLUMN details;
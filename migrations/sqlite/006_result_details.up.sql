@@ -0,0 +1,2 @@
+-- per-check extras, e.g. the answers of dns: checks (JSON text)
+ALTER TABLE check_results ADD COLUMN details TEXT;
@@ -0,0 +1,2 @@
+-- every try of a retried check (JSON text)
+ALTER TABLE check_results ADD COLUMN attempts TEXT;
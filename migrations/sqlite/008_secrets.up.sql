@@ -0,0 +1,10 @@
+-- credentials referenced by targets as ${secret:name}; value is AES-GCM sealed
+-- with the operator's key (internal/secrets), never stored in plain text
+CREATE TABLE IF NOT EXISTS secrets (
+  project_id TEXT NOT NULL REFERENCES projects(id),
+  name TEXT NOT NULL,
+  value BLOB NOT NULL,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  PRIMARY KEY (project_id, name)
+);
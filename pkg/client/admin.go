@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// admin endpoints need the admin scope; other projects need projects:admin
+
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ProjectID string   `json:"project_id,omitempty"` // "" is the caller's project
+}
+
+// CreateAPIKey is not retried: a lost response would leave a key nobody knows
+func (c *Client) CreateAPIKey(ctx context.Context, req CreateAPIKeyRequest) (NewAPIKey, error) {
+	var k NewAPIKey
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/v1/admin/api-keys", body: req}, &k)
+	return k, err
+}
+
+// ListAPIKeys lists the caller's project keys, revoked ones included
+func (c *Client) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var resp struct {
+		Items []APIKey `json:"items"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/v1/admin/api-keys", retry: true}, &resp)
+	return resp.Items, err
+}
+
+// RevokeAPIKey is retried, so a 404 may also mean an earlier attempt succeeded
+func (c *Client) RevokeAPIKey(ctx context.Context, id string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/v1/admin/api-keys/" + url.PathEscape(id), retry: true}, nil)
+	return err
+}
+
+type CreateProjectRequest struct {
+	Name                    string `json:"name"`
+	MaxTargets              *int   `json:"max_targets,omitempty"`
+	MinCheckIntervalSeconds *int   `json:"min_check_interval_seconds,omitempty"`
+}
+
+// CreateProject needs projects:admin; a taken name is a 409. Not retried
+func (c *Client) CreateProject(ctx context.Context, req CreateProjectRequest) (Project, error) {
+	var p Project
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/v1/admin/projects", body: req}, &p)
+	return p, err
+}
+
+func (c *Client) ListProjects(ctx context.Context) ([]Project, error) {
+	var resp struct {
+		Items []Project `json:"items"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/v1/admin/projects", retry: true}, &resp)
+	return resp.Items, err
+}
+
+// ProjectUpdate changes only the fields that are set
+type ProjectUpdate struct {
+	Name                    *string
+	MaxTargets              *int
+	MinCheckIntervalSeconds *int
+	// lift a quota; wins over the value above
+	ClearMaxTargets              bool
+	ClearMinCheckIntervalSeconds bool
+}
+
+func (u ProjectUpdate) body() map[string]any {
+	b := map[string]any{}
+	if u.Name != nil {
+		b["name"] = *u.Name
+	}
+	quota := func(name string, v *int, clear bool) {
+		switch {
+		case clear:
+			b[name] = nil
+		case v != nil:
+			b[name] = *v
+		}
+	}
+	quota("max_targets", u.MaxTargets, u.ClearMaxTargets)
+	quota("min_check_interval_seconds", u.MinCheckIntervalSeconds, u.ClearMinCheckIntervalSeconds)
+	return b
+}
+
+// UpdateProject needs projects:admin
+func (c *Client) UpdateProject(ctx context.Context, id string, u ProjectUpdate) (Project, error) {
+	var p Project
+	_, err := c.do(ctx, request{method: http.MethodPatch, path: "/v1/admin/projects/" + url.PathEscape(id), body: u.body(), retry: true}, &p)
+	return p, err
+}
+
+// ListBreakers needs projects:admin; hosts without recent failures are not listed
+func (c *Client) ListBreakers(ctx context.Context) ([]Breaker, error) {
+	var resp struct {
+		Items []Breaker `json:"items"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/v1/admin/breakers", retry: true}, &resp)
+	return resp.Items, err
+}
+
+// PutSecret needs admin; it creates or replaces a secret of the caller's
+// project. The value is write-only: no call returns it
+func (c *Client) PutSecret(ctx context.Context, name, value string) (Secret, error) {
+	var sec Secret
+	body := struct {
+		Value string `json:"value"`
+	}{value}
+	_, err := c.do(ctx, request{method: http.MethodPut, path: "/v1/admin/secrets/" + url.PathEscape(name), body: body, retry: true}, &sec)
+	return sec, err
+}
+
+// ListSecrets needs admin; names and timestamps only
+func (c *Client) ListSecrets(ctx context.Context) ([]Secret, error) {
+	var resp struct {
+		Items []Secret `json:"items"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/v1/admin/secrets", retry: true}, &resp)
+	return resp.Items, err
+}
+
+// DeleteSecret needs admin; a secret targets still reference is a 409
+func (c *Client) DeleteSecret(ctx context.Context, name string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/v1/admin/secrets/" + url.PathEscape(name), retry: true}, nil)
+	return err
+}
@@ -0,0 +1,252 @@
+// Package client is the Go SDK for the linkwatch REST API.
+//
+//	c, err := client.New("https://linkwatch.example.com", client.WithAPIKey(key))
+//	t, created, err := c.CreateTarget(ctx, client.CreateTargetRequest{URL: "https://example.org/"})
+//	for t, err := range c.ListTargets(ctx, client.ListTargetsOptions{}) { ... }
+//
+// Requests that are safe to repeat (reads, and target creation thanks to its
+// idempotency key) are retried on network errors, 429 and 5xx answers.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const userAgent = "linkwatch-go-client"
+
+type Client struct {
+	base    *url.URL
+	http    *http.Client
+	apiKey  string
+	project string // X-Project, only honoured with auth disabled
+	retries int
+	backoff time.Duration
+}
+
+type Option func(*Client)
+
+// WithAPIKey sends the key as "Authorization: Bearer"
+func WithAPIKey(key string) Option { return func(c *Client) { c.apiKey = key } }
+
+// WithHTTPClient replaces the default client (30s timeout)
+func WithHTTPClient(h *http.Client) Option { return func(c *Client) { c.http = h } }
+
+// WithProject picks the project on servers running with auth disabled; with
+// auth on, the key decides
+func WithProject(id string) Option { return func(c *Client) { c.project = id } }
+
+// WithRetries sets how often a retryable request is repeated (default 3, 0 disables)
+// and the first wait (default 200ms, doubled each time)
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) { c.retries, c.backoff = n, backoff }
+}
+
+// New returns a client for the service at baseURL, e.g. "http://localhost:8080"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("linkwatch: base url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("linkwatch: base url must be http(s)://host, got %q", baseURL)
+	}
+	c := &Client{base: u, http: &http.Client{Timeout: 30 * time.Second}, retries: 3, backoff: 200 * time.Millisecond}
+	for _, o := range opts {
+		o(c)
+	}
+	return c, nil
+}
+
+// Problem types, see Error.Type
+const (
+	ProblemValidation          = "/problems/validation"
+	ProblemUnauthorized        = "/problems/unauthorized"
+	ProblemForbidden           = "/problems/forbidden"
+	ProblemQuotaExceeded       = "/problems/quota-exceeded"
+	ProblemIdempotencyConflict = "/problems/idempotency-conflict"
+)
+
+// Error is an API error response (RFC 7807 problem details)
+type Error struct {
+	Type          string       `json:"type"`
+	Title         string       `json:"title"`
+	Status        int          `json:"status"`
+	Detail        string       `json:"detail,omitempty"`
+	Instance      string       `json:"instance,omitempty"`
+	RequestID     string       `json:"request_id,omitempty"`
+	Errors        []FieldError `json:"errors,omitempty"`
+	RequiredScope string       `json:"required_scope,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = e.Title
+	}
+	if e.RequestID != "" {
+		return fmt.Sprintf("linkwatch: %d %s (request %s)", e.Status, msg, e.RequestID)
+	}
+	return fmt.Sprintf("linkwatch: %d %s", e.Status, msg)
+}
+
+// IsStatus reports whether err is an API error with the given HTTP status
+func IsStatus(err error, status int) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Status == status
+}
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	header http.Header
+	retry  bool // safe to send again
+}
+
+// do sends req, decodes a 2xx body into out (if not nil) and returns the
+// status; API errors are *Error
+func (c *Client) do(ctx context.Context, req request, out any) (int, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return 0, fmt.Errorf("linkwatch: encoding %s %s: %w", req.method, req.path, err)
+		}
+	}
+	u := c.base.JoinPath(req.path)
+	u.RawQuery = req.query.Encode()
+
+	attempts := 1
+	if req.retry {
+		attempts += max(c.retries, 0)
+	}
+	var (
+		resp *http.Response
+		data []byte
+		err  error
+	)
+	for attempt := range attempts {
+		if attempt > 0 {
+			if err := sleep(ctx, c.wait(attempt, resp)); err != nil {
+				return 0, err
+			}
+		}
+		resp, data, err = c.send(ctx, req, u.String(), body)
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		if !retryable(resp, err) {
+			break
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("linkwatch: %s %s: %w", req.method, req.path, err)
+	}
+	if resp.StatusCode >= 300 {
+		e := &Error{}
+		_ = json.Unmarshal(data, e) // proxies answer with anything
+		e.Status = resp.StatusCode
+		if e.Title == "" {
+			e.Title = http.StatusText(resp.StatusCode)
+		}
+		return resp.StatusCode, e
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("linkwatch: decoding %s %s: %w", req.method, req.path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// one attempt; the body is read so the connection can be reused
+func (c *Client) send(ctx context.Context, req request, u string, body []byte) (*http.Response, []byte, error) {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	hr, err := http.NewRequestWithContext(ctx, req.method, u, rd)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, vs := range req.header {
+		hr.Header[k] = vs
+	}
+	hr.Header.Set("Accept", "application/json")
+	hr.Header.Set("User-Agent", userAgent)
+	if body != nil {
+		hr.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		hr.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if c.project != "" {
+		hr.Header.Set("X-Project", c.project)
+	}
+
+	resp, err := c.http.Do(hr)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
+
+// network errors, 429 and 5xx other than 501
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
+
+// exponential with jitter, or the server's Retry-After; both capped at 30s
+func (c *Client) wait(attempt int, last *http.Response) time.Duration {
+	if last != nil {
+		if s, err := strconv.Atoi(last.Header.Get("Retry-After")); err == nil && s > 0 {
+			return min(time.Duration(s)*time.Second, 30*time.Second)
+		}
+	}
+	d := min(c.backoff<<(attempt-1), 30*time.Second)
+	return d/2 + rand.N(d/2+1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Health is GET /healthz; it is not retried, a 503 means the database is down
+func (c *Client) Health(ctx context.Context) (Health, error) {
+	var h Health
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/healthz"}, &h)
+	return h, err
+}
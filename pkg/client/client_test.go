@@ -0,0 +1,337 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nurzh/linkwatch/internal/auth"
+	"github.com/nurzh/linkwatch/internal/checker"
+	"github.com/nurzh/linkwatch/internal/httpapi"
+	"github.com/nurzh/linkwatch/internal/netguard"
+	"github.com/nurzh/linkwatch/internal/secrets"
+	"github.com/nurzh/linkwatch/internal/store"
+	"github.com/nurzh/linkwatch/pkg/client"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	t   *testing.T
+	st  store.Store
+	url string
+
+	mu    sync.Mutex
+	fail  int      // answer the next n requests with 502 after handling them
+	drop  int      // close the connection of the next n requests without handling them
+	seen  []string // method and path of every request
+	idems []string // Idempotency-Key of every request
+}
+
+// the real handlers with auth on, behind a wrapper that injects failures
+func newEnv(t *testing.T) *testEnv {
+	ctx := context.Background()
+	st, err := store.OpenSQLite(ctx, ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(st.Close)
+	m, err := store.Migrator(st)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	chk := checker.New(st, 1, time.Second, time.Minute, checker.WithEgressPolicy(netguard.Default()))
+	box, err := secrets.ParseKey(secrets.NewKey())
+	require.NoError(t, err)
+	srv := httpapi.New(st, chk, &auth.Authenticator{Store: st}, httpapi.WithSecrets(box))
+	e := &testEnv{t: t, st: st}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.mu.Lock()
+		e.seen = append(e.seen, r.Method+" "+r.URL.Path)
+		e.idems = append(e.idems, r.Header.Get("Idempotency-Key"))
+		fail, drop := e.fail > 0, e.fail == 0 && e.drop > 0
+		if fail {
+			e.fail--
+		}
+		if drop {
+			e.drop--
+		}
+		e.mu.Unlock()
+		switch {
+		case drop:
+			conn, _, err := http.NewResponseController(w).Hijack()
+			require.NoError(t, err)
+			conn.Close()
+		case fail:
+			srv.ServeHTTP(httptest.NewRecorder(), r)
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		default:
+			srv.ServeHTTP(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	e.url = ts.URL
+	return e
+}
+
+// client with a fresh key of the default project
+func (e *testEnv) client(scopes ...string) *client.Client {
+	e.t.Helper()
+	raw, k, err := auth.NewKey(store.DefaultProjectID, "test", scopes)
+	require.NoError(e.t, err)
+	require.NoError(e.t, e.st.CreateAPIKey(context.Background(), k))
+	c, err := client.New(e.url, client.WithAPIKey(raw), client.WithRetries(3, time.Millisecond))
+	require.NoError(e.t, err)
+	return c
+}
+
+func (e *testEnv) requests() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.seen...)
+}
+
+func (e *testEnv) keys() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.idems...)
+}
+
+// forgets past requests and sets the failures for the next ones
+func (e *testEnv) reset(fail, drop int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.seen, e.idems = nil, nil
+	e.fail, e.drop = fail, drop
+}
+
+func requireAPIError(t *testing.T, err error, status int, typ string) *client.Error {
+	t.Helper()
+	var ae *client.Error
+	require.True(t, errors.As(err, &ae), "not an API error: %v", err)
+	require.Equal(t, status, ae.Status, ae.Detail)
+	require.Equal(t, typ, ae.Type)
+	return ae
+}
+
+func TestNew(t *testing.T) {
+	for _, u := range []string{"", "localhost:8080", "ftp://a.test", "http://"} {
+		_, err := client.New(u)
+		require.Error(t, err, u)
+	}
+	_, err := client.New("http://localhost:8080/")
+	require.NoError(t, err)
+}
+
+func TestTargets(t *testing.T) {
+	e := newEnv(t)
+	c := e.client(auth.ScopeTargetsRead, auth.ScopeTargetsWrite, auth.ScopeResultsRead)
+	ctx := context.Background()
+
+	first, created, err := c.CreateTarget(ctx, client.CreateTargetRequest{URL: "HTTPS://A.test/x"})
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, "https://a.test/x", first.URL)
+	require.Equal(t, store.DefaultProjectID, first.ProjectID)
+	//a fresh key for a known url: 201 with the existing target
+	again, created, err := c.CreateTarget(ctx, client.CreateTargetRequest{URL: "https://a.test/x"})
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, first.ID, again.ID)
+	again, created, err = c.CreateTarget(ctx, client.CreateTargetRequest{URL: "https://a.test/x", IdempotencyKey: "k0"})
+	require.NoError(t, err)
+	again, created, err = c.CreateTarget(ctx, client.CreateTargetRequest{URL: "https://a.test/x", IdempotencyKey: "k0"})
+	require.NoError(t, err)
+	require.False(t, created, "repeated key")
+	require.Equal(t, first.ID, again.ID)
+
+	_, _, err = c.CreateTarget(ctx, client.CreateTargetRequest{URL: "ftp://a.test/"})
+	ae := requireAPIError(t, err, http.StatusBadRequest, client.ProblemValidation)
+	require.Equal(t, "url", ae.Errors[0].Field)
+	require.NotEmpty(t, ae.RequestID)
+	_, _, err = c.CreateTarget(ctx, client.CreateTargetRequest{URL: "https://b.test/", IdempotencyKey: "k1"})
+	require.NoError(t, err)
+	_, _, err = c.CreateTarget(ctx, client.CreateTargetRequest{URL: "https://c.test/", IdempotencyKey: "k1"})
+	requireAPIError(t, err, http.StatusConflict, client.ProblemIdempotencyConflict)
+
+	for _, u := range []string{"https://c.test/", "https://d.test/", "https://e.test/"} {
+		_, _, err := c.CreateTarget(ctx, client.CreateTargetRequest{URL: u})
+		require.NoError(t, err)
+	}
+	e.reset(0, 0)
+	var urls []string
+	for tg, err := range c.ListTargets(ctx, client.ListTargetsOptions{Limit: 2}) {
+		require.NoError(t, err)
+		urls = append(urls, tg.URL)
+	}
+	require.Equal(t, []string{"https://a.test/x", "https://b.test/", "https://c.test/", "https://d.test/", "https://e.test/"}, urls)
+	require.Len(t, e.requests(), 3, "follows next_page_token")
+
+	e.reset(0, 0)
+	for range c.ListTargets(ctx, client.ListTargetsOptions{Limit: 2}) {
+		break
+	}
+	require.Len(t, e.requests(), 1, "stops fetching when the loop ends")
+
+	page, err := c.ListTargetsPage(ctx, client.ListTargetsOptions{Host: "B.TEST"})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	require.Empty(t, page.NextPageToken)
+	for _, err := range c.ListTargets(ctx, client.ListTargetsOptions{Limit: 500}) {
+		requireAPIError(t, err, http.StatusBadRequest, client.ProblemValidation)
+	}
+
+	base := time.Now().UTC().Truncate(time.Second)
+	for i := range 3 {
+		code := 200
+		require.NoError(t, e.st.AppendCheckResult(ctx, store.CheckResult{TargetID: first.ID, CheckedAt: base.Add(time.Duration(i) * time.Minute), StatusCode: &code}))
+	}
+	results, err := c.ListResults(ctx, first.ID, client.ListResultsOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.True(t, results[0].CheckedAt.After(results[1].CheckedAt), "newest first")
+	results, err = c.ListResults(ctx, first.ID, client.ListResultsOptions{Since: base.Add(90 * time.Second), Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	_, err = c.ListResults(ctx, "t_missing", client.ListResultsOptions{})
+	require.True(t, client.IsStatus(err, http.StatusNotFound))
+}
+
+func TestRetries(t *testing.T) {
+	e := newEnv(t)
+	c := e.client(auth.ScopeAdmin, auth.ScopeProjectsAdmin)
+	ctx := context.Background()
+
+	//the first attempt is applied but its answer lost; the retry reuses the key
+	e.reset(1, 0)
+	tg, created, err := c.CreateTarget(ctx, client.CreateTargetRequest{URL: "https://a.test/"})
+	require.NoError(t, err)
+	require.False(t, created, "the server already had it")
+	keys := e.keys()
+	require.Len(t, keys, 2)
+	require.NotEmpty(t, keys[0])
+	require.Equal(t, keys[0], keys[1])
+	page, err := c.ListTargetsPage(ctx, client.ListTargetsOptions{})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	require.Equal(t, tg.ID, page.Items[0].ID)
+
+	//every call gets its own key
+	e.reset(0, 0)
+	_, _, err = c.CreateTarget(ctx, client.CreateTargetRequest{URL: "https://b.test/"})
+	require.NoError(t, err)
+	_, _, err = c.CreateTarget(ctx, client.CreateTargetRequest{URL: "https://c.test/"})
+	require.NoError(t, err)
+	keys = e.keys()
+	require.NotEqual(t, keys[0], keys[1])
+
+	e.reset(0, 2)
+	_, err = c.ListTargetsPage(ctx, client.ListTargetsOptions{})
+	require.NoError(t, err, "network errors are retried")
+	require.Len(t, e.requests(), 3)
+
+	e.reset(10, 0)
+	_, err = c.ListAPIKeys(ctx)
+	require.True(t, client.IsStatus(err, http.StatusBadGateway))
+	require.Len(t, e.requests(), 4, "1 + 3 retries")
+
+	//not idempotent: never repeated
+	e.reset(1, 0)
+	_, err = c.CreateProject(ctx, client.CreateProjectRequest{Name: "team"})
+	require.True(t, client.IsStatus(err, http.StatusBadGateway))
+	require.Len(t, e.requests(), 1)
+
+	//client errors are not retried
+	e.reset(0, 0)
+	_, err = c.ListResults(ctx, "t_missing", client.ListResultsOptions{})
+	require.True(t, client.IsStatus(err, http.StatusNotFound))
+	require.Len(t, e.requests(), 1)
+
+	//cancelled while backing off
+	slow, err := client.New(e.url, client.WithRetries(3, time.Hour))
+	require.NoError(t, err)
+	e.reset(1, 0)
+	cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = slow.ListTargetsPage(cctx, client.ListTargetsOptions{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAuthAndAdmin(t *testing.T) {
+	e := newEnv(t)
+	ctx := context.Background()
+
+	anon, err := client.New(e.url)
+	require.NoError(t, err)
+	_, err = anon.ListTargetsPage(ctx, client.ListTargetsOptions{})
+	requireAPIError(t, err, http.StatusUnauthorized, client.ProblemUnauthorized)
+	h, err := anon.Health(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "ok", h.DB)
+
+	reader := e.client(auth.ScopeTargetsRead)
+	_, _, err = reader.CreateTarget(ctx, client.CreateTargetRequest{URL: "https://a.test/"})
+	ae := requireAPIError(t, err, http.StatusForbidden, client.ProblemForbidden)
+	require.Equal(t, client.ScopeTargetsWrite, ae.RequiredScope)
+
+	admin := e.client(auth.ScopeAdmin)
+	k, err := admin.CreateAPIKey(ctx, client.CreateAPIKeyRequest{Name: "ci", Scopes: []string{client.ScopeTargetsRead}})
+	require.NoError(t, err)
+	require.NotEmpty(t, k.Key)
+	ci, err := client.New(e.url, client.WithAPIKey(k.Key))
+	require.NoError(t, err)
+	_, err = ci.ListTargetsPage(ctx, client.ListTargetsOptions{})
+	require.NoError(t, err)
+
+	keys, err := admin.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	require.NoError(t, admin.RevokeAPIKey(ctx, k.ID))
+	require.True(t, client.IsStatus(admin.RevokeAPIKey(ctx, k.ID), http.StatusNotFound))
+	_, err = ci.ListTargetsPage(ctx, client.ListTargetsOptions{})
+	requireAPIError(t, err, http.StatusUnauthorized, client.ProblemUnauthorized)
+
+	_, err = admin.CreateProject(ctx, client.CreateProjectRequest{Name: "team"})
+	requireAPIError(t, err, http.StatusForbidden, client.ProblemForbidden)
+	root := e.client(auth.ScopeProjectsAdmin)
+	limit := 5
+	p, err := root.CreateProject(ctx, client.CreateProjectRequest{Name: "team", MaxTargets: &limit})
+	require.NoError(t, err)
+	require.Equal(t, 5, *p.MaxTargets)
+	_, err = root.CreateProject(ctx, client.CreateProjectRequest{Name: "team"})
+	require.True(t, client.IsStatus(err, http.StatusConflict))
+
+	name, every := "squad", 60
+	p, err = root.UpdateProject(ctx, p.ID, client.ProjectUpdate{Name: &name, MinCheckIntervalSeconds: &every, ClearMaxTargets: true})
+	require.NoError(t, err)
+	require.Equal(t, "squad", p.Name)
+	require.Nil(t, p.MaxTargets)
+	require.Equal(t, 60, *p.MinCheckIntervalSeconds)
+	p, err = root.UpdateProject(ctx, p.ID, client.ProjectUpdate{})
+	require.NoError(t, err)
+	require.Equal(t, 60, *p.MinCheckIntervalSeconds, "unset fields are kept")
+
+	projects, err := root.ListProjects(ctx)
+	require.NoError(t, err)
+	require.Len(t, projects, 2)
+	breakers, err := root.ListBreakers(ctx)
+	require.NoError(t, err)
+	require.Empty(t, breakers)
+	_, err = admin.ListBreakers(ctx)
+	requireAPIError(t, err, http.StatusForbidden, client.ProblemForbidden)
+	_, err = root.UpdateProject(ctx, "p_missing", client.ProjectUpdate{Name: &name})
+	require.True(t, client.IsStatus(err, http.StatusNotFound))
+
+	sec, err := admin.PutSecret(ctx, "api-token", "tok-123")
+	require.NoError(t, err)
+	require.Equal(t, "api-token", sec.Name)
+	secs, err := admin.ListSecrets(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []client.Secret{sec}, secs)
+	_, err = reader.PutSecret(ctx, "api-token", "x")
+	requireAPIError(t, err, http.StatusForbidden, client.ProblemForbidden)
+	require.NoError(t, admin.DeleteSecret(ctx, "api-token"))
+	require.True(t, client.IsStatus(admin.DeleteSecret(ctx, "api-token"), http.StatusNotFound))
+}
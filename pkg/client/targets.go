@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type CreateTargetRequest struct {
+	URL string `json:"url"`
+	// IdempotencyKey makes retries safe; a random one is generated when empty.
+	// Reusing a key with another URL fails with 409
+	IdempotencyKey string `json:"-"`
+}
+
+// CreateTarget adds a URL to the caller's project. created is the server's
+// 201: the first request with this idempotency key, which returns the
+// existing target when the URL is already registered. After a retry whose
+// first attempt the server had applied, created is false
+func (c *Client) CreateTarget(ctx context.Context, req CreateTargetRequest) (t Target, created bool, err error) {
+	key := req.IdempotencyKey
+	if key == "" {
+		key = newKey()
+	}
+	status, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/v1/targets",
+		body:   req,
+		header: http.Header{"Idempotency-Key": {key}},
+		retry:  true,
+	}, &t)
+	return t, status == http.StatusCreated, err
+}
+
+// 128 random bits, like the server's IDs
+func newKey() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+type ListTargetsOptions struct {
+	Host      string // only targets on this host[:port]
+	Limit     int    // page size, server default 20, max 100
+	PageToken string // NextPageToken of the previous page
+}
+
+type TargetPage struct {
+	Items         []Target `json:"items"`
+	NextPageToken string   `json:"next_page_token,omitempty"` // "" on the last page
+}
+
+// ListTargetsPage fetches one page of non-archived targets, oldest first
+func (c *Client) ListTargetsPage(ctx context.Context, opts ListTargetsOptions) (TargetPage, error) {
+	q := url.Values{}
+	if opts.Host != "" {
+		q.Set("host", opts.Host)
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.PageToken != "" {
+		q.Set("page_token", opts.PageToken)
+	}
+	var p TargetPage
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/v1/targets", query: q, retry: true}, &p)
+	return p, err
+}
+
+// ListTargets yields every target, fetching pages as needed; it stops after
+// the first error
+//
+//	for t, err := range c.ListTargets(ctx, client.ListTargetsOptions{}) {
+//		if err != nil { ... }
+//	}
+func (c *Client) ListTargets(ctx context.Context, opts ListTargetsOptions) iter.Seq2[Target, error] {
+	return func(yield func(Target, error) bool) {
+		for {
+			p, err := c.ListTargetsPage(ctx, opts)
+			if err != nil {
+				yield(Target{}, err)
+				return
+			}
+			for _, t := range p.Items {
+				if !yield(t, nil) {
+					return
+				}
+			}
+			if p.NextPageToken == "" {
+				return
+			}
+			opts.PageToken = p.NextPageToken
+		}
+	}
+}
+
+type ListResultsOptions struct {
+	Since time.Time // zero: no lower bound
+	Limit int       // server default 50, max 200
+}
+
+// ListResults returns a target's most recent check results, newest first
+func (c *Client) ListResults(ctx context.Context, targetID string, opts ListResultsOptions) ([]CheckResult, error) {
+	q := url.Values{}
+	if !opts.Since.IsZero() {
+		q.Set("since", opts.Since.UTC().Format(time.RFC3339Nano))
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	var resp struct {
+		Items []CheckResult `json:"items"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/v1/targets/" + url.PathEscape(targetID) + "/results", query: q, retry: true}, &resp)
+	return resp.Items, err
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// the JSON shapes of the REST API; see /openapi.json
+
+type Target struct {
+	ID         string            `json:"id"`
+	ProjectID  string            `json:"project_id"`
+	URL        string            `json:"url"`
+	Host       string            `json:"host"`
+	CreatedAt  time.Time         `json:"created_at"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Settings   TargetSettings    `json:"settings,omitzero"`
+	Source     string            `json:"source"` // "api" or "file"
+	ArchivedAt *time.Time        `json:"archived_at,omitempty"`
+}
+
+// zero values mean the server default
+type TargetSettings struct {
+	Interval  string             `json:"interval,omitempty"` // Go duration, e.g. "30s"
+	Timeout   string             `json:"timeout,omitempty"`
+	Retry     *RetrySettings     `json:"retry,omitempty"`
+	Transport *TransportSettings `json:"transport,omitempty"`
+	// secrets show as ${secret:name}, literal credentials as "[redacted]"
+	Headers   map[string]string `json:"headers,omitempty"`
+	BasicAuth *BasicAuth        `json:"basic_auth,omitempty"`
+}
+
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+}
+
+// overrides of the server's retry policy; zero values keep it
+type RetrySettings struct {
+	MaxAttempts int      `json:"max_attempts,omitempty"`
+	BaseBackoff string   `json:"base_backoff,omitempty"`
+	MaxBackoff  string   `json:"max_backoff,omitempty"`
+	Jitter      *float64 `json:"jitter,omitempty"`
+	Statuses    []string `json:"statuses,omitempty"` // "503" or "5xx"
+	Errors      []string `json:"errors,omitempty"`   // timeout, connection, dns, tls, other
+	RetryAfter  *bool    `json:"retry_after,omitempty"`
+}
+
+// overrides of the server's proxy/TLS settings; files are paths on the server
+type TransportSettings struct {
+	Proxy              string `json:"proxy,omitempty"`
+	CAFile             string `json:"ca_file,omitempty"`
+	CertFile           string `json:"cert_file,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+	CertSecret         string `json:"cert_secret,omitempty"` // secret names
+	KeySecret          string `json:"key_secret,omitempty"`
+	TLSMinVersion      string `json:"tls_min_version,omitempty"` // "1.0" to "1.3"
+	ServerName         string `json:"server_name,omitempty"`
+	InsecureSkipVerify *bool  `json:"insecure_skip_verify,omitempty"`
+}
+
+// CheckResult has either StatusCode and LatencyMS or Error
+type CheckResult struct {
+	TargetID   string    `json:"target_id"`
+	CheckedAt  time.Time `json:"checked_at"`
+	StatusCode *int      `json:"status_code,omitempty"`
+	LatencyMS  *int      `json:"latency_ms,omitempty"`
+	Error      *string   `json:"error,omitempty"`
+	// probe specific, e.g. the answers of dns: targets
+	Details json.RawMessage `json:"details,omitempty"`
+	// every try in order, the last one is the result above
+	Attempts []Attempt `json:"attempts,omitempty"`
+}
+
+type Attempt struct {
+	Attempt    int     `json:"attempt"`
+	StatusCode *int    `json:"status_code,omitempty"`
+	LatencyMS  *int    `json:"latency_ms,omitempty"`
+	Error      *string `json:"error,omitempty"`
+}
+
+type APIKey struct {
+	ID         string     `json:"id"`
+	ProjectID  string     `json:"project_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// NewAPIKey is returned once by CreateAPIKey; Key is the secret
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type Project struct {
+	ID                      string    `json:"id"`
+	Name                    string    `json:"name"`
+	MaxTargets              *int      `json:"max_targets,omitempty"`                // nil = unlimited
+	MinCheckIntervalSeconds *int      `json:"min_check_interval_seconds,omitempty"` // nil = global interval
+	CreatedAt               time.Time `json:"created_at"`
+}
+
+// circuit breaker state of a host that failed recently, as seen by one replica
+type Breaker struct {
+	Host     string     `json:"host"`
+	State    string     `json:"state"` // BreakerClosed, BreakerOpen or BreakerHalfOpen
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	ProbeAt  *time.Time `json:"probe_at,omitempty"` // earliest next probe while open
+}
+
+// a secret without its value
+type Secret struct {
+	ProjectID string    `json:"project_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+type Health struct {
+	Liveness string `json:"liveness"`
+	DB       string `json:"db"`
+	Checker  string `json:"checker"`
+}
+
+// API key scopes
+const (
+	ScopeTargetsRead   = "targets:read"
+	ScopeTargetsWrite  = "targets:write"
+	ScopeResultsRead   = "results:read"
+	ScopeAdmin         = "admin"          // every other scope within the key's project
+	ScopeProjectsAdmin = "projects:admin" // manage projects and other projects' keys
+)
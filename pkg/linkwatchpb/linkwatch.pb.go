@@ -0,0 +1,1274 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: linkwatch/v1/linkwatch.proto
+
+// gRPC API of linkwatch. It is served next to the REST API (grpc_listen_addr)
+// and behaves the same: same store, URL canonicalization, quotas and
+// idempotency keys. Authenticate with "authorization: Bearer <key>" or
+// "x-api-key: <key>" metadata.
+
+package linkwatchpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Target struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ProjectId string                 `protobuf:"bytes,2,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	Url       string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	Host      string                 `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Labels    map[string]string      `protobuf:"bytes,6,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// "api" or "file"
+	Source string `protobuf:"bytes,7,opt,name=source,proto3" json:"source,omitempty"`
+	// Go durations; empty means the instance default
+	Interval   string                 `protobuf:"bytes,8,opt,name=interval,proto3" json:"interval,omitempty"`
+	Timeout    string                 `protobuf:"bytes,9,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	ArchivedAt *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=archived_at,json=archivedAt,proto3" json:"archived_at,omitempty"`
+	// overrides of the instance retry policy, unset when there are none
+	Retry *RetrySettings `protobuf:"bytes,11,opt,name=retry,proto3" json:"retry,omitempty"`
+	// overrides of the instance proxy/TLS settings, unset when there are none
+	Transport *TransportSettings `protobuf:"bytes,12,opt,name=transport,proto3" json:"transport,omitempty"`
+	// sent with HTTP checks; secrets appear as ${secret:name}, literal
+	// credentials as [redacted]
+	Headers       map[string]string `protobuf:"bytes,13,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	BasicAuth     *BasicAuth        `protobuf:"bytes,14,opt,name=basic_auth,json=basicAuth,proto3" json:"basic_auth,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Target) Reset() {
+	*x = Target{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Target) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Target) ProtoMessage() {}
+
+func (x *Target) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Target.ProtoReflect.Descriptor instead.
+func (*Target) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Target) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Target) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *Target) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Target) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *Target) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Target) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Target) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Target) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+func (x *Target) GetTimeout() string {
+	if x != nil {
+		return x.Timeout
+	}
+	return ""
+}
+
+func (x *Target) GetArchivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ArchivedAt
+	}
+	return nil
+}
+
+func (x *Target) GetRetry() *RetrySettings {
+	if x != nil {
+		return x.Retry
+	}
+	return nil
+}
+
+func (x *Target) GetTransport() *TransportSettings {
+	if x != nil {
+		return x.Transport
+	}
+	return nil
+}
+
+func (x *Target) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *Target) GetBasicAuth() *BasicAuth {
+	if x != nil {
+		return x.BasicAuth
+	}
+	return nil
+}
+
+type BasicAuth struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Username string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	// a ${secret:name} reference or [redacted]
+	Password      string `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BasicAuth) Reset() {
+	*x = BasicAuth{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BasicAuth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BasicAuth) ProtoMessage() {}
+
+func (x *BasicAuth) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BasicAuth.ProtoReflect.Descriptor instead.
+func (*BasicAuth) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BasicAuth) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *BasicAuth) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type RetrySettings struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 0 and empty values keep the instance policy
+	MaxAttempts int32    `protobuf:"varint,1,opt,name=max_attempts,json=maxAttempts,proto3" json:"max_attempts,omitempty"`
+	BaseBackoff string   `protobuf:"bytes,2,opt,name=base_backoff,json=baseBackoff,proto3" json:"base_backoff,omitempty"`
+	MaxBackoff  string   `protobuf:"bytes,3,opt,name=max_backoff,json=maxBackoff,proto3" json:"max_backoff,omitempty"`
+	Jitter      *float64 `protobuf:"fixed64,4,opt,name=jitter,proto3,oneof" json:"jitter,omitempty"`
+	// "503" or a class like "5xx"
+	Statuses []string `protobuf:"bytes,5,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	// timeout, connection, dns, tls, other
+	Errors        []string `protobuf:"bytes,6,rep,name=errors,proto3" json:"errors,omitempty"`
+	RetryAfter    *bool    `protobuf:"varint,7,opt,name=retry_after,json=retryAfter,proto3,oneof" json:"retry_after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RetrySettings) Reset() {
+	*x = RetrySettings{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetrySettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetrySettings) ProtoMessage() {}
+
+func (x *RetrySettings) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetrySettings.ProtoReflect.Descriptor instead.
+func (*RetrySettings) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RetrySettings) GetMaxAttempts() int32 {
+	if x != nil {
+		return x.MaxAttempts
+	}
+	return 0
+}
+
+func (x *RetrySettings) GetBaseBackoff() string {
+	if x != nil {
+		return x.BaseBackoff
+	}
+	return ""
+}
+
+func (x *RetrySettings) GetMaxBackoff() string {
+	if x != nil {
+		return x.MaxBackoff
+	}
+	return ""
+}
+
+func (x *RetrySettings) GetJitter() float64 {
+	if x != nil && x.Jitter != nil {
+		return *x.Jitter
+	}
+	return 0
+}
+
+func (x *RetrySettings) GetStatuses() []string {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+func (x *RetrySettings) GetErrors() []string {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *RetrySettings) GetRetryAfter() bool {
+	if x != nil && x.RetryAfter != nil {
+		return *x.RetryAfter
+	}
+	return false
+}
+
+type TransportSettings struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// empty values keep the instance settings; files are paths on the server
+	Proxy    string `protobuf:"bytes,1,opt,name=proxy,proto3" json:"proxy,omitempty"`
+	CaFile   string `protobuf:"bytes,2,opt,name=ca_file,json=caFile,proto3" json:"ca_file,omitempty"`
+	CertFile string `protobuf:"bytes,3,opt,name=cert_file,json=certFile,proto3" json:"cert_file,omitempty"`
+	KeyFile  string `protobuf:"bytes,4,opt,name=key_file,json=keyFile,proto3" json:"key_file,omitempty"`
+	// names of secrets holding the client certificate and key
+	CertSecret string `protobuf:"bytes,8,opt,name=cert_secret,json=certSecret,proto3" json:"cert_secret,omitempty"`
+	KeySecret  string `protobuf:"bytes,9,opt,name=key_secret,json=keySecret,proto3" json:"key_secret,omitempty"`
+	// "1.0" to "1.3"
+	TlsMinVersion      string `protobuf:"bytes,5,opt,name=tls_min_version,json=tlsMinVersion,proto3" json:"tls_min_version,omitempty"`
+	ServerName         string `protobuf:"bytes,6,opt,name=server_name,json=serverName,proto3" json:"server_name,omitempty"`
+	InsecureSkipVerify *bool  `protobuf:"varint,7,opt,name=insecure_skip_verify,json=insecureSkipVerify,proto3,oneof" json:"insecure_skip_verify,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *TransportSettings) Reset() {
+	*x = TransportSettings{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransportSettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransportSettings) ProtoMessage() {}
+
+func (x *TransportSettings) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransportSettings.ProtoReflect.Descriptor instead.
+func (*TransportSettings) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TransportSettings) GetProxy() string {
+	if x != nil {
+		return x.Proxy
+	}
+	return ""
+}
+
+func (x *TransportSettings) GetCaFile() string {
+	if x != nil {
+		return x.CaFile
+	}
+	return ""
+}
+
+func (x *TransportSettings) GetCertFile() string {
+	if x != nil {
+		return x.CertFile
+	}
+	return ""
+}
+
+func (x *TransportSettings) GetKeyFile() string {
+	if x != nil {
+		return x.KeyFile
+	}
+	return ""
+}
+
+func (x *TransportSettings) GetCertSecret() string {
+	if x != nil {
+		return x.CertSecret
+	}
+	return ""
+}
+
+func (x *TransportSettings) GetKeySecret() string {
+	if x != nil {
+		return x.KeySecret
+	}
+	return ""
+}
+
+func (x *TransportSettings) GetTlsMinVersion() string {
+	if x != nil {
+		return x.TlsMinVersion
+	}
+	return ""
+}
+
+func (x *TransportSettings) GetServerName() string {
+	if x != nil {
+		return x.ServerName
+	}
+	return ""
+}
+
+func (x *TransportSettings) GetInsecureSkipVerify() bool {
+	if x != nil && x.InsecureSkipVerify != nil {
+		return *x.InsecureSkipVerify
+	}
+	return false
+}
+
+type CheckResult struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	TargetId  string                 `protobuf:"bytes,1,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"`
+	CheckedAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=checked_at,json=checkedAt,proto3" json:"checked_at,omitempty"`
+	// unset when the check failed before a response, and for tcp:// and dns: targets
+	StatusCode *int32  `protobuf:"varint,3,opt,name=status_code,json=statusCode,proto3,oneof" json:"status_code,omitempty"`
+	LatencyMs  *int32  `protobuf:"varint,4,opt,name=latency_ms,json=latencyMs,proto3,oneof" json:"latency_ms,omitempty"`
+	Error      *string `protobuf:"bytes,5,opt,name=error,proto3,oneof" json:"error,omitempty"`
+	// probe specific; dns: targets record type, answers and resolver
+	Details *structpb.Struct `protobuf:"bytes,6,opt,name=details,proto3" json:"details,omitempty"`
+	// every try in order, the last one is this result
+	Attempts      []*Attempt `protobuf:"bytes,7,rep,name=attempts,proto3" json:"attempts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckResult) Reset() {
+	*x = CheckResult{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckResult) ProtoMessage() {}
+
+func (x *CheckResult) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckResult.ProtoReflect.Descriptor instead.
+func (*CheckResult) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CheckResult) GetTargetId() string {
+	if x != nil {
+		return x.TargetId
+	}
+	return ""
+}
+
+func (x *CheckResult) GetCheckedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CheckedAt
+	}
+	return nil
+}
+
+func (x *CheckResult) GetStatusCode() int32 {
+	if x != nil && x.StatusCode != nil {
+		return *x.StatusCode
+	}
+	return 0
+}
+
+func (x *CheckResult) GetLatencyMs() int32 {
+	if x != nil && x.LatencyMs != nil {
+		return *x.LatencyMs
+	}
+	return 0
+}
+
+func (x *CheckResult) GetError() string {
+	if x != nil && x.Error != nil {
+		return *x.Error
+	}
+	return ""
+}
+
+func (x *CheckResult) GetDetails() *structpb.Struct {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+func (x *CheckResult) GetAttempts() []*Attempt {
+	if x != nil {
+		return x.Attempts
+	}
+	return nil
+}
+
+type Attempt struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Attempt       int32                  `protobuf:"varint,1,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	StatusCode    *int32                 `protobuf:"varint,2,opt,name=status_code,json=statusCode,proto3,oneof" json:"status_code,omitempty"`
+	LatencyMs     *int32                 `protobuf:"varint,3,opt,name=latency_ms,json=latencyMs,proto3,oneof" json:"latency_ms,omitempty"`
+	Error         *string                `protobuf:"bytes,4,opt,name=error,proto3,oneof" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Attempt) Reset() {
+	*x = Attempt{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Attempt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Attempt) ProtoMessage() {}
+
+func (x *Attempt) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Attempt.ProtoReflect.Descriptor instead.
+func (*Attempt) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Attempt) GetAttempt() int32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+func (x *Attempt) GetStatusCode() int32 {
+	if x != nil && x.StatusCode != nil {
+		return *x.StatusCode
+	}
+	return 0
+}
+
+func (x *Attempt) GetLatencyMs() int32 {
+	if x != nil && x.LatencyMs != nil {
+		return *x.LatencyMs
+	}
+	return 0
+}
+
+func (x *Attempt) GetError() string {
+	if x != nil && x.Error != nil {
+		return *x.Error
+	}
+	return ""
+}
+
+type ListTargetsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// host[:port] without scheme
+	Host string `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	// 1-100, 0 means 20
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page
+	PageToken     string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTargetsRequest) Reset() {
+	*x = ListTargetsRequest{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTargetsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTargetsRequest) ProtoMessage() {}
+
+func (x *ListTargetsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTargetsRequest.ProtoReflect.Descriptor instead.
+func (*ListTargetsRequest) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListTargetsRequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *ListTargetsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListTargetsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListTargetsResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Targets []*Target              `protobuf:"bytes,1,rep,name=targets,proto3" json:"targets,omitempty"`
+	// empty on the last page
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTargetsResponse) Reset() {
+	*x = ListTargetsResponse{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTargetsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTargetsResponse) ProtoMessage() {}
+
+func (x *ListTargetsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTargetsResponse.ProtoReflect.Descriptor instead.
+func (*ListTargetsResponse) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListTargetsResponse) GetTargets() []*Target {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+func (x *ListTargetsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type CreateTargetRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Url   string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	// optional; repeating a key with the same url returns the same target,
+	// with another url fails with ALREADY_EXISTS. Shared with the REST
+	// Idempotency-Key header.
+	IdempotencyKey string `protobuf:"bytes,2,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CreateTargetRequest) Reset() {
+	*x = CreateTargetRequest{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTargetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTargetRequest) ProtoMessage() {}
+
+func (x *CreateTargetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTargetRequest.ProtoReflect.Descriptor instead.
+func (*CreateTargetRequest) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CreateTargetRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *CreateTargetRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type CreateTargetResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Target *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	// false when the url (or idempotency key) was already registered
+	Created       bool `protobuf:"varint,2,opt,name=created,proto3" json:"created,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTargetResponse) Reset() {
+	*x = CreateTargetResponse{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTargetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTargetResponse) ProtoMessage() {}
+
+func (x *CreateTargetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTargetResponse.ProtoReflect.Descriptor instead.
+func (*CreateTargetResponse) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CreateTargetResponse) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *CreateTargetResponse) GetCreated() bool {
+	if x != nil {
+		return x.Created
+	}
+	return false
+}
+
+type ListResultsRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	TargetId string                 `protobuf:"bytes,1,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"`
+	// only results checked at or after this time
+	Since *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=since,proto3" json:"since,omitempty"`
+	// 1-200, 0 means 50
+	PageSize      int32 `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResultsRequest) Reset() {
+	*x = ListResultsRequest{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResultsRequest) ProtoMessage() {}
+
+func (x *ListResultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResultsRequest.ProtoReflect.Descriptor instead.
+func (*ListResultsRequest) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListResultsRequest) GetTargetId() string {
+	if x != nil {
+		return x.TargetId
+	}
+	return ""
+}
+
+func (x *ListResultsRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *ListResultsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+type ListResultsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*CheckResult         `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResultsResponse) Reset() {
+	*x = ListResultsResponse{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResultsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResultsResponse) ProtoMessage() {}
+
+func (x *ListResultsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResultsResponse.ProtoReflect.Descriptor instead.
+func (*ListResultsResponse) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListResultsResponse) GetResults() []*CheckResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type WatchResultsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// all empty: every target of the project
+	TargetId string `protobuf:"bytes,1,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"`
+	Host     string `protobuf:"bytes,2,opt,name=host,proto3" json:"host,omitempty"`
+	// all must match
+	Labels map[string]string `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// resume after this event (from the in-memory history)
+	LastEventId   string `protobuf:"bytes,4,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResultsRequest) Reset() {
+	*x = WatchResultsRequest{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResultsRequest) ProtoMessage() {}
+
+func (x *WatchResultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResultsRequest.ProtoReflect.Descriptor instead.
+func (*WatchResultsRequest) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *WatchResultsRequest) GetTargetId() string {
+	if x != nil {
+		return x.TargetId
+	}
+	return ""
+}
+
+func (x *WatchResultsRequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *WatchResultsRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *WatchResultsRequest) GetLastEventId() string {
+	if x != nil {
+		return x.LastEventId
+	}
+	return ""
+}
+
+type WatchResultsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// pass as last_event_id to resume
+	EventId       string       `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	Result        *CheckResult `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResultsResponse) Reset() {
+	*x = WatchResultsResponse{}
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResultsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResultsResponse) ProtoMessage() {}
+
+func (x *WatchResultsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_linkwatch_v1_linkwatch_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResultsResponse.ProtoReflect.Descriptor instead.
+func (*WatchResultsResponse) Descriptor() ([]byte, []int) {
+	return file_linkwatch_v1_linkwatch_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WatchResultsResponse) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *WatchResultsResponse) GetResult() *CheckResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+var File_linkwatch_v1_linkwatch_proto protoreflect.FileDescriptor
+
+const file_linkwatch_v1_linkwatch_proto_rawDesc = "" +
+	"\n" +
+	"\x1clinkwatch/v1/linkwatch.proto\x12\flinkwatch.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xbb\x05\n" +
+	"\x06Target\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x02 \x01(\tR\tprojectId\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\x12\x12\n" +
+	"\x04host\x18\x04 \x01(\tR\x04host\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x128\n" +
+	"\x06labels\x18\x06 \x03(\v2 .linkwatch.v1.Target.LabelsEntryR\x06labels\x12\x16\n" +
+	"\x06source\x18\a \x01(\tR\x06source\x12\x1a\n" +
+	"\binterval\x18\b \x01(\tR\binterval\x12\x18\n" +
+	"\atimeout\x18\t \x01(\tR\atimeout\x12;\n" +
+	"\varchived_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"archivedAt\x121\n" +
+	"\x05retry\x18\v \x01(\v2\x1b.linkwatch.v1.RetrySettingsR\x05retry\x12=\n" +
+	"\ttransport\x18\f \x01(\v2\x1f.linkwatch.v1.TransportSettingsR\ttransport\x12;\n" +
+	"\aheaders\x18\r \x03(\v2!.linkwatch.v1.Target.HeadersEntryR\aheaders\x126\n" +
+	"\n" +
+	"basic_auth\x18\x0e \x01(\v2\x17.linkwatch.v1.BasicAuthR\tbasicAuth\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"C\n" +
+	"\tBasicAuth\x12\x1a\n" +
+	"\busername\x18\x01 \x01(\tR\busername\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\"\x88\x02\n" +
+	"\rRetrySettings\x12!\n" +
+	"\fmax_attempts\x18\x01 \x01(\x05R\vmaxAttempts\x12!\n" +
+	"\fbase_backoff\x18\x02 \x01(\tR\vbaseBackoff\x12\x1f\n" +
+	"\vmax_backoff\x18\x03 \x01(\tR\n" +
+	"maxBackoff\x12\x1b\n" +
+	"\x06jitter\x18\x04 \x01(\x01H\x00R\x06jitter\x88\x01\x01\x12\x1a\n" +
+	"\bstatuses\x18\x05 \x03(\tR\bstatuses\x12\x16\n" +
+	"\x06errors\x18\x06 \x03(\tR\x06errors\x12$\n" +
+	"\vretry_after\x18\a \x01(\bH\x01R\n" +
+	"retryAfter\x88\x01\x01B\t\n" +
+	"\a_jitterB\x0e\n" +
+	"\f_retry_after\"\xd3\x02\n" +
+	"\x11TransportSettings\x12\x14\n" +
+	"\x05proxy\x18\x01 \x01(\tR\x05proxy\x12\x17\n" +
+	"\aca_file\x18\x02 \x01(\tR\x06caFile\x12\x1b\n" +
+	"\tcert_file\x18\x03 \x01(\tR\bcertFile\x12\x19\n" +
+	"\bkey_file\x18\x04 \x01(\tR\akeyFile\x12\x1f\n" +
+	"\vcert_secret\x18\b \x01(\tR\n" +
+	"certSecret\x12\x1d\n" +
+	"\n" +
+	"key_secret\x18\t \x01(\tR\tkeySecret\x12&\n" +
+	"\x0ftls_min_version\x18\x05 \x01(\tR\rtlsMinVersion\x12\x1f\n" +
+	"\vserver_name\x18\x06 \x01(\tR\n" +
+	"serverName\x125\n" +
+	"\x14insecure_skip_verify\x18\a \x01(\bH\x00R\x12insecureSkipVerify\x88\x01\x01B\x17\n" +
+	"\x15_insecure_skip_verify\"\xd9\x02\n" +
+	"\vCheckResult\x12\x1b\n" +
+	"\ttarget_id\x18\x01 \x01(\tR\btargetId\x129\n" +
+	"\n" +
+	"checked_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tcheckedAt\x12$\n" +
+	"\vstatus_code\x18\x03 \x01(\x05H\x00R\n" +
+	"statusCode\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"latency_ms\x18\x04 \x01(\x05H\x01R\tlatencyMs\x88\x01\x01\x12\x19\n" +
+	"\x05error\x18\x05 \x01(\tH\x02R\x05error\x88\x01\x01\x121\n" +
+	"\adetails\x18\x06 \x01(\v2\x17.google.protobuf.StructR\adetails\x121\n" +
+	"\battempts\x18\a \x03(\v2\x15.linkwatch.v1.AttemptR\battemptsB\x0e\n" +
+	"\f_status_codeB\r\n" +
+	"\v_latency_msB\b\n" +
+	"\x06_error\"\xb1\x01\n" +
+	"\aAttempt\x12\x18\n" +
+	"\aattempt\x18\x01 \x01(\x05R\aattempt\x12$\n" +
+	"\vstatus_code\x18\x02 \x01(\x05H\x00R\n" +
+	"statusCode\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"latency_ms\x18\x03 \x01(\x05H\x01R\tlatencyMs\x88\x01\x01\x12\x19\n" +
+	"\x05error\x18\x04 \x01(\tH\x02R\x05error\x88\x01\x01B\x0e\n" +
+	"\f_status_codeB\r\n" +
+	"\v_latency_msB\b\n" +
+	"\x06_error\"d\n" +
+	"\x12ListTargetsRequest\x12\x12\n" +
+	"\x04host\x18\x01 \x01(\tR\x04host\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\"m\n" +
+	"\x13ListTargetsResponse\x12.\n" +
+	"\atargets\x18\x01 \x03(\v2\x14.linkwatch.v1.TargetR\atargets\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"P\n" +
+	"\x13CreateTargetRequest\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12'\n" +
+	"\x0fidempotency_key\x18\x02 \x01(\tR\x0eidempotencyKey\"^\n" +
+	"\x14CreateTargetResponse\x12,\n" +
+	"\x06target\x18\x01 \x01(\v2\x14.linkwatch.v1.TargetR\x06target\x12\x18\n" +
+	"\acreated\x18\x02 \x01(\bR\acreated\"\x80\x01\n" +
+	"\x12ListResultsRequest\x12\x1b\n" +
+	"\ttarget_id\x18\x01 \x01(\tR\btargetId\x120\n" +
+	"\x05since\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\"J\n" +
+	"\x13ListResultsResponse\x123\n" +
+	"\aresults\x18\x01 \x03(\v2\x19.linkwatch.v1.CheckResultR\aresults\"\xec\x01\n" +
+	"\x13WatchResultsRequest\x12\x1b\n" +
+	"\ttarget_id\x18\x01 \x01(\tR\btargetId\x12\x12\n" +
+	"\x04host\x18\x02 \x01(\tR\x04host\x12E\n" +
+	"\x06labels\x18\x03 \x03(\v2-.linkwatch.v1.WatchResultsRequest.LabelsEntryR\x06labels\x12\"\n" +
+	"\rlast_event_id\x18\x04 \x01(\tR\vlastEventId\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"d\n" +
+	"\x14WatchResultsResponse\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x121\n" +
+	"\x06result\x18\x02 \x01(\v2\x19.linkwatch.v1.CheckResultR\x06result2\xea\x02\n" +
+	"\x10LinkwatchService\x12R\n" +
+	"\vListTargets\x12 .linkwatch.v1.ListTargetsRequest\x1a!.linkwatch.v1.ListTargetsResponse\x12U\n" +
+	"\fCreateTarget\x12!.linkwatch.v1.CreateTargetRequest\x1a\".linkwatch.v1.CreateTargetResponse\x12R\n" +
+	"\vListResults\x12 .linkwatch.v1.ListResultsRequest\x1a!.linkwatch.v1.ListResultsResponse\x12W\n" +
+	"\fWatchResults\x12!.linkwatch.v1.WatchResultsRequest\x1a\".linkwatch.v1.WatchResultsResponse0\x01B8Z6github.com/nurzh/linkwatch/pkg/linkwatchpb;linkwatchpbb\x06proto3"
+
+var (
+	file_linkwatch_v1_linkwatch_proto_rawDescOnce sync.Once
+	file_linkwatch_v1_linkwatch_proto_rawDescData []byte
+)
+
+func file_linkwatch_v1_linkwatch_proto_rawDescGZIP() []byte {
+	file_linkwatch_v1_linkwatch_proto_rawDescOnce.Do(func() {
+		file_linkwatch_v1_linkwatch_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_linkwatch_v1_linkwatch_proto_rawDesc), len(file_linkwatch_v1_linkwatch_proto_rawDesc)))
+	})
+	return file_linkwatch_v1_linkwatch_proto_rawDescData
+}
+
+var file_linkwatch_v1_linkwatch_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_linkwatch_v1_linkwatch_proto_goTypes = []any{
+	(*Target)(nil),                // 0: linkwatch.v1.Target
+	(*BasicAuth)(nil),             // 1: linkwatch.v1.BasicAuth
+	(*RetrySettings)(nil),         // 2: linkwatch.v1.RetrySettings
+	(*TransportSettings)(nil),     // 3: linkwatch.v1.TransportSettings
+	(*CheckResult)(nil),           // 4: linkwatch.v1.CheckResult
+	(*Attempt)(nil),               // 5: linkwatch.v1.Attempt
+	(*ListTargetsRequest)(nil),    // 6: linkwatch.v1.ListTargetsRequest
+	(*ListTargetsResponse)(nil),   // 7: linkwatch.v1.ListTargetsResponse
+	(*CreateTargetRequest)(nil),   // 8: linkwatch.v1.CreateTargetRequest
+	(*CreateTargetResponse)(nil),  // 9: linkwatch.v1.CreateTargetResponse
+	(*ListResultsRequest)(nil),    // 10: linkwatch.v1.ListResultsRequest
+	(*ListResultsResponse)(nil),   // 11: linkwatch.v1.ListResultsResponse
+	(*WatchResultsRequest)(nil),   // 12: linkwatch.v1.WatchResultsRequest
+	(*WatchResultsResponse)(nil),  // 13: linkwatch.v1.WatchResultsResponse
+	nil,                           // 14: linkwatch.v1.Target.LabelsEntry
+	nil,                           // 15: linkwatch.v1.Target.HeadersEntry
+	nil,                           // 16: linkwatch.v1.WatchResultsRequest.LabelsEntry
+	(*timestamppb.Timestamp)(nil), // 17: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),       // 18: google.protobuf.Struct
+}
+var file_linkwatch_v1_linkwatch_proto_depIdxs = []int32{
+	17, // 0: linkwatch.v1.Target.created_at:type_name -> google.protobuf.Timestamp
+	14, // 1: linkwatch.v1.Target.labels:type_name -> linkwatch.v1.Target.LabelsEntry
+	17, // 2: linkwatch.v1.Target.archived_at:type_name -> google.protobuf.Timestamp
+	2,  // 3: linkwatch.v1.Target.retry:type_name -> linkwatch.v1.RetrySettings
+	3,  // 4: linkwatch.v1.Target.transport:type_name -> linkwatch.v1.TransportSettings
+	15, // 5: linkwatch.v1.Target.headers:type_name -> linkwatch.v1.Target.HeadersEntry
+	1,  // 6: linkwatch.v1.Target.basic_auth:type_name -> linkwatch.v1.BasicAuth
+	17, // 7: linkwatch.v1.CheckResult.checked_at:type_name -> google.protobuf.Timestamp
+	18, // 8: linkwatch.v1.CheckResult.details:type_name -> google.protobuf.Struct
+	5,  // 9: linkwatch.v1.CheckResult.attempts:type_name -> linkwatch.v1.Attempt
+	0,  // 10: linkwatch.v1.ListTargetsResponse.targets:type_name -> linkwatch.v1.Target
+	0,  // 11: linkwatch.v1.CreateTargetResponse.target:type_name -> linkwatch.v1.Target
+	17, // 12: linkwatch.v1.ListResultsRequest.since:type_name -> google.protobuf.Timestamp
+	4,  // 13: linkwatch.v1.ListResultsResponse.results:type_name -> linkwatch.v1.CheckResult
+	16, // 14: linkwatch.v1.WatchResultsRequest.labels:type_name -> linkwatch.v1.WatchResultsRequest.LabelsEntry
+	4,  // 15: linkwatch.v1.WatchResultsResponse.result:type_name -> linkwatch.v1.CheckResult
+	6,  // 16: linkwatch.v1.LinkwatchService.ListTargets:input_type -> linkwatch.v1.ListTargetsRequest
+	8,  // 17: linkwatch.v1.LinkwatchService.CreateTarget:input_type -> linkwatch.v1.CreateTargetRequest
+	10, // 18: linkwatch.v1.LinkwatchService.ListResults:input_type -> linkwatch.v1.ListResultsRequest
+	12, // 19: linkwatch.v1.LinkwatchService.WatchResults:input_type -> linkwatch.v1.WatchResultsRequest
+	7,  // 20: linkwatch.v1.LinkwatchService.ListTargets:output_type -> linkwatch.v1.ListTargetsResponse
+	9,  // 21: linkwatch.v1.LinkwatchService.CreateTarget:output_type -> linkwatch.v1.CreateTargetResponse
+	11, // 22: linkwatch.v1.LinkwatchService.ListResults:output_type -> linkwatch.v1.ListResultsResponse
+	13, // 23: linkwatch.v1.LinkwatchService.WatchResults:output_type -> linkwatch.v1.WatchResultsResponse
+	20, // [20:24] is the sub-list for method output_type
+	16, // [16:20] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_linkwatch_v1_linkwatch_proto_init() }
+func file_linkwatch_v1_linkwatch_proto_init() {
+	if File_linkwatch_v1_linkwatch_proto != nil {
+		return
+	}
+	file_linkwatch_v1_linkwatch_proto_msgTypes[2].OneofWrappers = []any{}
+	file_linkwatch_v1_linkwatch_proto_msgTypes[3].OneofWrappers = []any{}
+	file_linkwatch_v1_linkwatch_proto_msgTypes[4].OneofWrappers = []any{}
+	file_linkwatch_v1_linkwatch_proto_msgTypes[5].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_linkwatch_v1_linkwatch_proto_rawDesc), len(file_linkwatch_v1_linkwatch_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   17,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_linkwatch_v1_linkwatch_proto_goTypes,
+		DependencyIndexes: file_linkwatch_v1_linkwatch_proto_depIdxs,
+		MessageInfos:      file_linkwatch_v1_linkwatch_proto_msgTypes,
+	}.Build()
+	File_linkwatch_v1_linkwatch_proto = out.File
+	file_linkwatch_v1_linkwatch_proto_goTypes = nil
+	file_linkwatch_v1_linkwatch_proto_depIdxs = nil
+}
@@ -0,0 +1,252 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: linkwatch/v1/linkwatch.proto
+
+// gRPC API of linkwatch. It is served next to the REST API (grpc_listen_addr)
+// and behaves the same: same store, URL canonicalization, quotas and
+// idempotency keys. Authenticate with "authorization: Bearer <key>" or
+// "x-api-key: <key>" metadata.
+
+package linkwatchpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	LinkwatchService_ListTargets_FullMethodName  = "/linkwatch.v1.LinkwatchService/ListTargets"
+	LinkwatchService_CreateTarget_FullMethodName = "/linkwatch.v1.LinkwatchService/CreateTarget"
+	LinkwatchService_ListResults_FullMethodName  = "/linkwatch.v1.LinkwatchService/ListResults"
+	LinkwatchService_WatchResults_FullMethodName = "/linkwatch.v1.LinkwatchService/WatchResults"
+)
+
+// LinkwatchServiceClient is the client API for LinkwatchService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LinkwatchServiceClient interface {
+	// Targets of the caller's project, ordered by (created_at, id). Needs targets:read.
+	ListTargets(ctx context.Context, in *ListTargetsRequest, opts ...grpc.CallOption) (*ListTargetsResponse, error)
+	// Registers a URL, like POST /v1/targets. Needs targets:write.
+	CreateTarget(ctx context.Context, in *CreateTargetRequest, opts ...grpc.CallOption) (*CreateTargetResponse, error)
+	// Recent results of a target, newest first. Needs results:read.
+	ListResults(ctx context.Context, in *ListResultsRequest, opts ...grpc.CallOption) (*ListResultsResponse, error)
+	// Check results as they are recorded, until the client cancels. Needs results:read.
+	WatchResults(ctx context.Context, in *WatchResultsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchResultsResponse], error)
+}
+
+type linkwatchServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLinkwatchServiceClient(cc grpc.ClientConnInterface) LinkwatchServiceClient {
+	return &linkwatchServiceClient{cc}
+}
+
+func (c *linkwatchServiceClient) ListTargets(ctx context.Context, in *ListTargetsRequest, opts ...grpc.CallOption) (*ListTargetsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTargetsResponse)
+	err := c.cc.Invoke(ctx, LinkwatchService_ListTargets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *linkwatchServiceClient) CreateTarget(ctx context.Context, in *CreateTargetRequest, opts ...grpc.CallOption) (*CreateTargetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateTargetResponse)
+	err := c.cc.Invoke(ctx, LinkwatchService_CreateTarget_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *linkwatchServiceClient) ListResults(ctx context.Context, in *ListResultsRequest, opts ...grpc.CallOption) (*ListResultsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResultsResponse)
+	err := c.cc.Invoke(ctx, LinkwatchService_ListResults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *linkwatchServiceClient) WatchResults(ctx context.Context, in *WatchResultsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchResultsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LinkwatchService_ServiceDesc.Streams[0], LinkwatchService_WatchResults_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchResultsRequest, WatchResultsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LinkwatchService_WatchResultsClient = grpc.ServerStreamingClient[WatchResultsResponse]
+
+// LinkwatchServiceServer is the server API for LinkwatchService service.
+// All implementations must embed UnimplementedLinkwatchServiceServer
+// for forward compatibility.
+type LinkwatchServiceServer interface {
+	// Targets of the caller's project, ordered by (created_at, id). Needs targets:read.
+	ListTargets(context.Context, *ListTargetsRequest) (*ListTargetsResponse, error)
+	// Registers a URL, like POST /v1/targets. Needs targets:write.
+	CreateTarget(context.Context, *CreateTargetRequest) (*CreateTargetResponse, error)
+	// Recent results of a target, newest first. Needs results:read.
+	ListResults(context.Context, *ListResultsRequest) (*ListResultsResponse, error)
+	// Check results as they are recorded, until the client cancels. Needs results:read.
+	WatchResults(*WatchResultsRequest, grpc.ServerStreamingServer[WatchResultsResponse]) error
+	mustEmbedUnimplementedLinkwatchServiceServer()
+}
+
+// UnimplementedLinkwatchServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLinkwatchServiceServer struct{}
+
+func (UnimplementedLinkwatchServiceServer) ListTargets(context.Context, *ListTargetsRequest) (*ListTargetsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListTargets not implemented")
+}
+func (UnimplementedLinkwatchServiceServer) CreateTarget(context.Context, *CreateTargetRequest) (*CreateTargetResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateTarget not implemented")
+}
+func (UnimplementedLinkwatchServiceServer) ListResults(context.Context, *ListResultsRequest) (*ListResultsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListResults not implemented")
+}
+func (UnimplementedLinkwatchServiceServer) WatchResults(*WatchResultsRequest, grpc.ServerStreamingServer[WatchResultsResponse]) error {
+	return status.Error(codes.Unimplemented, "method WatchResults not implemented")
+}
+func (UnimplementedLinkwatchServiceServer) mustEmbedUnimplementedLinkwatchServiceServer() {}
+func (UnimplementedLinkwatchServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafeLinkwatchServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LinkwatchServiceServer will
+// result in compilation errors.
+type UnsafeLinkwatchServiceServer interface {
+	mustEmbedUnimplementedLinkwatchServiceServer()
+}
+
+func RegisterLinkwatchServiceServer(s grpc.ServiceRegistrar, srv LinkwatchServiceServer) {
+	// If the following call panics, it indicates UnimplementedLinkwatchServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&LinkwatchService_ServiceDesc, srv)
+}
+
+func _LinkwatchService_ListTargets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTargetsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LinkwatchServiceServer).ListTargets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LinkwatchService_ListTargets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LinkwatchServiceServer).ListTargets(ctx, req.(*ListTargetsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LinkwatchService_CreateTarget_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTargetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LinkwatchServiceServer).CreateTarget(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LinkwatchService_CreateTarget_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LinkwatchServiceServer).CreateTarget(ctx, req.(*CreateTargetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LinkwatchService_ListResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListResultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LinkwatchServiceServer).ListResults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LinkwatchService_ListResults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LinkwatchServiceServer).ListResults(ctx, req.(*ListResultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LinkwatchService_WatchResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchResultsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LinkwatchServiceServer).WatchResults(m, &grpc.GenericServerStream[WatchResultsRequest, WatchResultsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LinkwatchService_WatchResultsServer = grpc.ServerStreamingServer[WatchResultsResponse]
+
+// LinkwatchService_ServiceDesc is the grpc.ServiceDesc for LinkwatchService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LinkwatchService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "linkwatch.v1.LinkwatchService",
+	HandlerType: (*LinkwatchServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListTargets",
+			Handler:    _LinkwatchService_ListTargets_Handler,
+		},
+		{
+			MethodName: "CreateTarget",
+			Handler:    _LinkwatchService_CreateTarget_Handler,
+		},
+		{
+			MethodName: "ListResults",
+			Handler:    _LinkwatchService_ListResults_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchResults",
+			Handler:       _LinkwatchService_WatchResults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "linkwatch/v1/linkwatch.proto",
+}